@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/migrate"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/seed"
+	"go.uber.org/zap"
+)
+
+const usage = `usage: warehouse-service [flags] [command]
+
+Without a command the HTTP server is started.
+
+commands:
+  migrate up          apply all pending migrations
+  migrate down [N]    roll back the last N migrations (default 1)
+  migrate goto V      migrate up or down to version V (0 rolls back everything)
+  migrate force V     set version V without running migrations and clear the dirty flag
+  migrate status      show current version and pending migrations
+  seed [-profile demo|loadtest] [-scale X] [-seed N]
+                      insert generated test data; safe to repeat
+  create-admin [-username NAME] [-key-name NAME]
+                      create (or promote) an admin user and print a new API key
+                      for it; the key is shown only once`
+
+var errUsage = errors.New(usage)
+
+// runCommand выполняет подкоманду вместо запуска сервера.
+func runCommand(ctx context.Context, logger *zap.Logger, dbpool *pgxpool.Pool, migrator *migrate.Migrator, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, migrator, args[1:], os.Stdout)
+	case "seed":
+		return runSeed(ctx, migrator, seed.New(dbpool, logger), args[1:], os.Stdout)
+	case "create-admin":
+		return runCreateAdmin(ctx, logger, dbpool, migrator, args[1:], os.Stdout)
+	default:
+		return errUsage
+	}
+}
+
+// runMigrate выполняет подкоманду migrate.
+func runMigrate(ctx context.Context, migrator *migrate.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	// Числовой аргумент команды: обязательный для goto/force, необязательный для down
+	number := func(required bool, fallback int64) (int64, error) {
+		if len(args) < 2 {
+			if required {
+				return 0, errUsage
+			}
+			return fallback, nil
+		}
+		if len(args) > 2 {
+			return 0, errUsage
+		}
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number %q", args[1])
+		}
+		return n, nil
+	}
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return migrator.Up(ctx)
+	case "down":
+		steps, err := number(false, 1)
+		if err != nil {
+			return err
+		}
+		return migrator.Down(ctx, int(steps))
+	case "goto":
+		version, err := number(true, 0)
+		if err != nil {
+			return err
+		}
+		return migrator.Goto(ctx, version)
+	case "force":
+		version, err := number(true, 0)
+		if err != nil {
+			return err
+		}
+		return migrator.Force(ctx, version)
+	case "status":
+		return printMigrationStatus(ctx, migrator, out)
+	default:
+		return errUsage
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator, out io.Writer) error {
+	current, dirty, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	state := "clean"
+	if dirty {
+		state = "dirty"
+	}
+	fmt.Fprintf(out, "version: %d (%s), latest: %d\n", current, state, migrator.Latest())
+	for _, m := range migrator.Migrations() {
+		mark := " "
+		if m.Version <= current {
+			mark = "x"
+		}
+		fmt.Fprintf(out, "[%s] %06d %s\n", mark, m.Version, m.Name)
+	}
+	return nil
+}
+
+func runSeed(ctx context.Context, migrator *migrate.Migrator, seeder *seed.Seeder, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts := seed.Options{}
+	fs.StringVar(&opts.Profile, "profile", "demo", "")
+	fs.Float64Var(&opts.Scale, "scale", 1, "")
+	fs.Uint64Var(&opts.Seed, "seed", 1, "")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	// Данные пишутся только в схему актуальной версии
+	if err := migrator.Check(ctx); err != nil {
+		return err
+	}
+	stats, err := seeder.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "inserted: %d warehouses, %d products, %d inventory rows, %d sales rows\n",
+		stats.Warehouses, stats.Products, stats.Inventory, stats.Sales)
+	return nil
+}
+
+// runCreateAdmin выдает первый доступ к API при включенной аутентификации:
+// создает пользователя с ролью admin (или добавляет роль существующему) и
+// новый API-ключ для него. Повторный запуск выдает еще один ключ.
+func runCreateAdmin(ctx context.Context, logger *zap.Logger, dbpool *pgxpool.Pool, migrator *migrate.Migrator, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	username := fs.String("username", "admin", "")
+	keyName := fs.String("key-name", "bootstrap", "")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *username == "" || *keyName == "" {
+		return errUsage
+	}
+	if err := migrator.Check(ctx); err != nil {
+		return err
+	}
+
+	users := repository.NewUserRepository(dbpool)
+	apiKeys := repository.NewAPIKeyRepository(dbpool)
+	// В журнале аудита изменения CLI записываются от имени "cli"
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "cli", Name: "create-admin"})
+	recorder := audit.NewRecorder(repository.NewAuditRepository(dbpool), logger)
+
+	user, err := users.GetUserBySubject(ctx, *username)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		if user, err = users.CreateUser(ctx, *username); err == nil {
+			recorder.Record(ctx, audit.ActionCreate, "user", user.ID.String(), nil, user)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// Выдача роли admin — самое привилегированное изменение, поэтому
+	// журналируется, как смена ролей через API
+	if !slices.Contains(user.Roles, auth.RoleAdmin) {
+		before := user
+		if err := users.SetRoles(ctx, user.ID, append(slices.Clip(user.Roles), auth.RoleAdmin)); err != nil {
+			return err
+		}
+		if user, err = users.GetUserByID(ctx, before.ID); err != nil {
+			return err
+		}
+		recorder.Record(ctx, audit.ActionUpdate, "user", user.ID.String(), before, user)
+	}
+
+	plain, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+	key, err := apiKeys.Create(ctx, *keyName, user.ID.String(), auth.HashAPIKey(plain))
+	if err != nil {
+		return err
+	}
+	recorder.Record(ctx, audit.ActionCreate, "api_key", key.ID.String(), nil, key)
+
+	fmt.Fprintf(out, "user: %s (%s)\napi key: %s\n", user.Username, user.ID, plain)
+	return nil
+}
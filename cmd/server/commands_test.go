@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestCreateAdmin(t *testing.T) {
+	ctx := context.Background()
+	pool, migrator := pgtest.NewTestDatabaseWithMigrator(t)
+	authenticator := auth.NewAuthenticator(repository.NewAPIKeyRepository(pool), repository.NewUserRepository(pool), nil)
+
+	// Повторный запуск не создает второго пользователя, но выдает новый ключ
+	var keys []string
+	for range 2 {
+		var out bytes.Buffer
+		if err := runCreateAdmin(ctx, zap.NewNop(), pool, migrator, []string{"-username", "root"}, &out); err != nil {
+			t.Fatal(err)
+		}
+		_, key, ok := strings.Cut(out.String(), "api key: ")
+		if !ok {
+			t.Fatalf("no key in output %q", out.String())
+		}
+		keys = append(keys, strings.TrimSpace(key))
+	}
+	if keys[0] == keys[1] {
+		t.Fatal("second run returned the same key")
+	}
+
+	users, err := repository.NewUserRepository(pool).GetUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Username != "root" {
+		t.Fatalf("users = %+v, want one user root", users)
+	}
+
+	// Создание пользователя, выдача роли admin и оба ключа — в журнале аудита
+	entries, err := repository.NewAuditRepository(pool).Find(ctx, repository.AuditFilter{Actor: "cli"}, pagination.Params{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries.Items {
+		actions = append(actions, e.EntityType+":"+e.Action)
+	}
+	slices.Sort(actions)
+	if want := "[api_key:create api_key:create user:create user:update]"; fmt.Sprint(actions) != want {
+		t.Fatalf("audit entries = %v, want %s", actions, want)
+	}
+	for _, e := range entries.Items {
+		if e.EntityType == "user" && e.Action == "update" && !strings.Contains(string(e.After), auth.RoleAdmin) {
+			t.Fatalf("role change entry after = %s, want admin role", e.After)
+		}
+	}
+
+	for _, key := range keys {
+		req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		principal, err := authenticator.Authenticate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !principal.HasPermission(auth.PermAdmin) {
+			t.Fatalf("principal %+v has no admin permission", principal)
+		}
+	}
+}
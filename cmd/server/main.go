@@ -2,72 +2,153 @@ package main
 
 import (
 	"context"
-	"net/http"
-	"os"
-	"os/signal"
-	"time"
-
 	"errors"
+	"flag"
+	"fmt"
+	"os"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	"github.com/yourusername/warehouse-service/internal/app"
 	"github.com/yourusername/warehouse-service/internal/config"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/tracing"
 	"go.uber.org/zap"
+
+	// Драйверы хранилищ регистрируются при импорте; postgres входит в
+	// пакет repository
+	_ "github.com/yourusername/warehouse-service/internal/repository/memory"
+	_ "github.com/yourusername/warehouse-service/internal/repository/sqlite"
 )
 
 func main() {
-	logger, err := zap.NewDevelopment()
+	// os.Exit не выполняет defer, поэтому вся работа вынесена в run
+	os.Exit(run())
+}
+
+func run() int {
+	// Загрузить .env файл (до разбора конфигурации, чтобы его значения учитывались)
+	envErr := godotenv.Load()
+
+	cfg, command, err := config.Load(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
 	if err != nil {
-		panic("failed to initialize logger: " + err.Error())
+		fmt.Fprintln(os.Stderr, "invalid configuration:\n"+err.Error())
+		return 2
 	}
-	defer func() {
-		if err := logger.Sync(); err != nil {
-			logger.Error("Failed to sync logger", zap.Error(err))
-		}
-	}()
 
-	// Загрузить .env файл
-	if err := godotenv.Load(); err != nil {
+	logger, err := cfg.NewLogger()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger: "+err.Error())
+		return 1
+	}
+	// Сбрасывается последним, после остановки всего остального
+	defer func() { _ = logger.Sync() }()
+	if envErr != nil {
 		logger.Warn(".env file not found, falling back to environment variables")
 	}
+	for _, warning := range cfg.Warnings() {
+		logger.Warn(warning)
+	}
+
+	// Команды (migrate, seed) работают только с PostgreSQL
+	if len(command) > 0 {
+		if cfg.Storage.Driver != repository.StoragePostgres {
+			fmt.Fprintln(os.Stderr, "commands require storage postgres\n\n"+usage)
+			return 2
+		}
+		dbpool, migrator, err := repository.OpenPostgres(context.Background(), cfg.Database, logger)
+		if err != nil {
+			logger.Error("Failed to open database", zap.Error(err))
+			return 1
+		}
+		defer dbpool.Close()
 
-	//database connection
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		logger.Fatal("DB_URL is not set in environment")
+		err = runCommand(context.Background(), logger, dbpool, migrator, command)
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		if err != nil {
+			logger.Error("Command failed", zap.String("command", command[0]), zap.Error(err))
+			return 1
+		}
+		return 0
 	}
-	dbpool, err := pgxpool.New(context.Background(), dbURL)
 
-	// Инициализация зависимостей
-	router := config.SetupDependencies(logger, dbpool)
+	// Хранилище выбирается драйвером из storage.driver
+	storage, err := repository.OpenStorage(context.Background(), cfg.Storage.Driver, cfg.StorageSettings(), logger)
+	if err != nil {
+		logger.Error("Failed to open storage", zap.String("driver", cfg.Storage.Driver), zap.Error(err))
+		return 1
+	}
+	// Обычно хранилище закрывает хук остановки приложения; повторный Close безопасен
+	if storage.Close != nil {
+		defer func() { _ = storage.Close() }()
+	}
 
-	port := os.Getenv("APP_PORT")
-	if port == "" {
-		port = "8080"
+	// Трассировка
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingConfig())
+	if err != nil {
+		logger.Error("Failed to set up tracing", zap.Error(err))
+		return 1
 	}
-	server := config.NewServer(port, router)
 
-	//server start
-	go func() {
-		logger.Info("Starting server", zap.String("port", port))
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Fatal("ListenAndServe failed", zap.Error(err))
-		}
-	}()
+	// Настройки API
+	opts, err := cfg.APIOptions()
+	if err != nil {
+		logger.Error("Invalid API configuration", zap.Error(err))
+		return 1
+	}
 
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	// Кэш чтений (списки, цены)
+	readCache, closeCache, err := cfg.OpenCache(context.Background(), logger)
+	if err != nil {
+		logger.Error("Failed to open cache", zap.String("driver", cfg.Cache.Driver), zap.Error(err))
+		return 1
+	}
+	defer func() { _ = closeCache() }()
+	opts.Cache = readCache
+
+	// Корзины ограничителя в Redis (rate_limit.store redis)
+	rateLimitRedis, closeRateLimit, err := cfg.OpenRateLimitRedis()
+	if err != nil {
+		logger.Error("Failed to open rate limit store", zap.Error(err))
+		return 1
+	}
+	defer func() { _ = closeRateLimit() }()
+	opts.RateLimitRedis = rateLimitRedis
 
-	// Shutdown
-	logger.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// Инициализация зависимостей
+	deps := config.SetupDependencies(logger, storage, opts)
+	server := config.NewServer(cfg.Server, deps.Router)
 
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Fatal("Server Shutdown Failed", zap.Error(err))
+	// Порядок остановки: /readyz → 503, текущие запросы, фоновые задачи,
+	// затем хранилище и трассы
+	application := app.New(logger, server, deps.Health, app.Options{
+		DrainDelay:      cfg.Server.DrainDelay,
+		ShutdownTimeout: cfg.Server.ShutdownTimeout,
+	})
+	for _, job := range deps.Jobs {
+		application.AddJob(job)
 	}
+	if storage.Close != nil {
+		application.OnShutdown("storage", func(context.Context) error {
+			return storage.Close()
+		})
+	}
+	application.OnShutdown("cache", func(context.Context) error {
+		return closeCache()
+	})
+	application.OnShutdown("rate-limit", func(context.Context) error {
+		return closeRateLimit()
+	})
+	application.OnShutdown("tracing", shutdownTracing)
 
-	logger.Info("Server exited properly")
+	if err := application.Run(context.Background()); err != nil {
+		logger.Error("Server stopped with errors", zap.Error(err))
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,240 @@
+// Package apitest — табличные HTTP-сценарии для всех маршрутов API. Как и
+// repository/contract, набор не зависит от хранилища: он получает
+// http.Handler, собранный config.SetupDependencies, и возвращает ошибку со
+// списком всех нарушений.
+//
+// Сценарий — последовательность шагов. Шаг отправляет запрос, сверяет
+// статус и поля JSON-ответа и сохраняет значения (ID, ETag) в переменные,
+// которые следующие шаги подставляют в путь, тело и заголовки как {name}.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+// Features — возможности собранного приложения; сценарии, требующие
+// отсутствующей возможности, пропускаются.
+type Features struct {
+	// Admin — маршруты /api/admin (пользователи и API-ключи)
+	Admin bool
+	// Audit — журнал /api/audit
+	Audit bool
+	// Metrics — /metrics
+	Metrics bool
+}
+
+// Target — проверяемое приложение.
+type Target struct {
+	Handler  http.Handler
+	Features Features
+}
+
+// Factory собирает приложение на пустом хранилище для одного сценария.
+// cleanup (может быть nil) вызывается после сценария.
+type Factory func(ctx context.Context) (target Target, cleanup func(), err error)
+
+// Step — один запрос сценария.
+type Step struct {
+	Name   string
+	Method string
+	// Path и Body могут содержать переменные {name}
+	Path    string
+	Body    string
+	Headers map[string]string
+	Status  int
+	// Expect сверяет поля ответа: ключ — путь в JSON ("items.0.quantity",
+	// "items.#" — длина массива, "header:ETag" — заголовок), значение
+	// сравнивается в текстовом виде и может содержать переменные
+	Expect map[string]any
+	// Save сохраняет значения ответа в переменные: имя → путь, как в Expect
+	Save map[string]string
+}
+
+// Scenario — сценарий работы с API.
+type Scenario struct {
+	Name     string
+	Requires func(Features) bool
+	Steps    []Step
+	// Check выполняется после шагов, если проверку нельзя выразить
+	// последовательностью запросов (параллельные покупки)
+	Check func(ctx context.Context, c *Client) error
+}
+
+// Run выполняет все сценарии, каждый на новом приложении, и объединяет ошибки.
+func Run(ctx context.Context, newTarget Factory) error {
+	var errs []error
+	for _, s := range Scenarios() {
+		if err := RunScenario(ctx, newTarget, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunScenario выполняет один сценарий на новом приложении.
+func RunScenario(ctx context.Context, newTarget Factory, s Scenario) error {
+	target, cleanup, err := newTarget(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: create target: %w", s.Name, err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if s.Requires != nil && !s.Requires(target.Features) {
+		return nil
+	}
+
+	server := httptest.NewServer(target.Handler)
+	defer server.Close()
+	c := &Client{base: server.URL, http: server.Client(), Vars: map[string]string{}}
+
+	for i, step := range s.Steps {
+		if err := c.Do(ctx, step); err != nil {
+			return fmt.Errorf("%s: step %d (%s): %w", s.Name, i+1, step.Name, err)
+		}
+	}
+	if s.Check != nil {
+		if err := s.Check(ctx, c); err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// Client выполняет шаги против запущенного приложения. Переменные
+// сценария доступны проверкам через Vars.
+type Client struct {
+	base string
+	http *http.Client
+	Vars map[string]string
+}
+
+// Response — ответ на шаг.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Send отправляет запрос шага без сверки ответа; безопасен для
+// параллельного вызова, если Vars не меняются.
+func (c *Client) Send(ctx context.Context, step Step) (*Response, error) {
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(c.expand(step.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, step.Method, c.base+c.expand(step.Path), body)
+	if err != nil {
+		return nil, err
+	}
+	if step.Body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range step.Headers {
+		req.Header.Set(name, c.expand(value))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: data}, nil
+}
+
+// Do отправляет запрос, сверяет ответ и сохраняет переменные.
+func (c *Client) Do(ctx context.Context, step Step) error {
+	resp, err := c.Send(ctx, step)
+	if err != nil {
+		return err
+	}
+	if resp.Status != step.Status {
+		return fmt.Errorf("%s %s: status %d, want %d: %s", step.Method, c.expand(step.Path), resp.Status, step.Status, bytes.TrimSpace(resp.Body))
+	}
+	for path, want := range step.Expect {
+		got, err := resp.Lookup(path)
+		if err != nil {
+			return err
+		}
+		if expected := c.expand(fmt.Sprint(want)); got != expected {
+			return fmt.Errorf("%s = %q, want %q", path, got, expected)
+		}
+	}
+	for name, path := range step.Save {
+		value, err := resp.Lookup(path)
+		if err != nil {
+			return err
+		}
+		c.Vars[name] = value
+	}
+	return nil
+}
+
+// expand подставляет переменные {name}; неизвестные остаются как есть
+func (c *Client) expand(s string) string {
+	for name, value := range c.Vars {
+		s = strings.ReplaceAll(s, "{"+name+"}", value)
+	}
+	return s
+}
+
+// Lookup возвращает значение по пути из Step.Expect в текстовом виде.
+func (r *Response) Lookup(path string) (string, error) {
+	if name, ok := strings.CutPrefix(path, "header:"); ok {
+		return r.Header.Get(name), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(r.Body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("decode response for %s: %w", path, err)
+	}
+	if path == "" {
+		return fmt.Sprint(value), nil
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[part]; !ok {
+				return "", fmt.Errorf("%s: no field %q in response", path, part)
+			}
+		case []any:
+			if part == "#" {
+				value = len(v)
+				continue
+			}
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("%s: no element %q in array of %d", path, part, len(v))
+			}
+			value = v[i]
+		case nil:
+			// пустой список кодируется как null
+			if part != "#" {
+				return "", fmt.Errorf("%s: %q is null", path, part)
+			}
+			value = 0
+		default:
+			return "", fmt.Errorf("%s: %q is not an object or array", path, part)
+		}
+	}
+	if value == nil {
+		return "null", nil
+	}
+	return fmt.Sprint(value), nil
+}
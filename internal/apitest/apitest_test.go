@@ -0,0 +1,122 @@
+package apitest_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/yourusername/warehouse-service/internal/apitest"
+	"github.com/yourusername/warehouse-service/internal/cache"
+	"github.com/yourusername/warehouse-service/internal/config"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/repository/memory"
+	"github.com/yourusername/warehouse-service/internal/repository/sqlite"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+// options — настройки API, на которых выполняются сценарии
+var options = config.Options{
+	Paging:         pagination.DefaultLimits,
+	IdempotencyTTL: time.Hour,
+	MetricsEnabled: true,
+	CacheTTL:       time.Minute,
+}
+
+// backend собирает хранилище для одного сценария; ресурсы освобождаются
+// через t.Cleanup
+type backend struct {
+	name string
+	// short — хранилище проверяется и с -short
+	short bool
+	open  func(t *testing.T) (repository.Storage, apitest.Features)
+}
+
+var backends = []backend{
+	{
+		name:  memory.Name,
+		short: true,
+		open: func(*testing.T) (repository.Storage, apitest.Features) {
+			return memory.NewStorage(memory.New()), apitest.Features{Metrics: true}
+		},
+	},
+	{
+		name: sqlite.Name,
+		open: func(t *testing.T) (repository.Storage, apitest.Features) {
+			store, err := sqlite.Open(context.Background(), filepath.Join(t.TempDir(), "warehouse.db"), zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = store.Close() })
+			return sqlite.NewStorage(store), apitest.Features{Metrics: true}
+		},
+	},
+	{
+		name: repository.StoragePostgres,
+		open: func(t *testing.T) (repository.Storage, apitest.Features) {
+			pool, migrator := pgtest.NewTestDatabaseWithMigrator(t)
+			return repository.NewPostgresStorage(zap.NewNop(), pool, migrator), apitest.Features{Admin: true, Audit: true, Metrics: true}
+		},
+	},
+}
+
+// newCaches возвращает фабрику пустых кэшей драйвера: memory — новый LRU на
+// сценарий, redis — общий miniredis с отдельным префиксом ключей на сценарий
+func newCaches(t *testing.T, driver string) func(t *testing.T) cache.Cache {
+	switch driver {
+	case config.CacheMemory:
+		return func(*testing.T) cache.Cache { return cache.NewLRU(1000) }
+	case config.CacheRedis:
+		server := miniredis.RunT(t)
+		var scenarios int
+		return func(t *testing.T) cache.Cache {
+			scenarios++
+			c := cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), fmt.Sprintf("scenario%d:", scenarios))
+			t.Cleanup(func() { _ = c.Close() })
+			return c
+		}
+	default:
+		return func(*testing.T) cache.Cache { return nil }
+	}
+}
+
+// Сценарии API на каждом хранилище и с каждым кэшем чтений. С -short
+// проверяется только хранилище в памяти, PostgreSQL без сервера
+// пропускается (с PGTEST_REQUIRE — ошибка).
+func TestScenarios(t *testing.T) {
+	for _, b := range backends {
+		for _, driver := range []string{config.CacheNone, config.CacheMemory, config.CacheRedis} {
+			t.Run(b.name+"/cache="+driver, func(t *testing.T) {
+				if testing.Short() && !b.short {
+					t.Skip("skipped in -short mode")
+				}
+				if b.name == repository.StoragePostgres {
+					pgtest.Shared(t)
+				}
+				caches := newCaches(t, driver)
+				for _, s := range apitest.Scenarios() {
+					t.Run(s.Name, func(t *testing.T) {
+						factory := func(context.Context) (apitest.Target, func(), error) {
+							storage, features := b.open(t)
+							opts := options
+							opts.Cache = caches(t)
+							handler := config.SetupDependencies(zap.NewNop(), storage, opts).Router
+							return apitest.Target{Handler: handler, Features: features}, nil, nil
+						}
+						if err := apitest.RunScenario(context.Background(), factory, s); err != nil {
+							t.Fatal(err)
+						}
+					})
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// parallel — число одновременных запросов в проверках конкурентности
+const parallel = 50
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
+
+// fire отправляет step parallel раз одновременно и возвращает число ответов
+// по статусам
+func fire(ctx context.Context, c *Client, step Step) (map[int]int, error) {
+	var (
+		mu       sync.Mutex
+		statuses = map[int]int{}
+		firstErr error
+		wg       sync.WaitGroup
+		start    = make(chan struct{})
+	)
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			resp, err := c.Send(ctx, step)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			statuses[resp.Status]++
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return statuses, firstErr
+}
+
+// concurrentPurchases: покупок по одной единице больше, чем остаток;
+// успешных ровно столько, сколько было на складе, остаток не уходит
+// в минус, а аналитика совпадает с проданным
+func concurrentPurchases(ctx context.Context, c *Client) error {
+	const stock = 20
+	statuses, err := fire(ctx, c, Step{
+		Method: http.MethodPost, Path: "/api/inventory/purchase/{w}",
+		Body: `{"items": {"{p}": 1}}`,
+	})
+	if err != nil {
+		return err
+	}
+	if statuses[http.StatusOK] != stock || statuses[http.StatusBadRequest] != parallel-stock {
+		return fmt.Errorf("purchase statuses %v, want %d × 200 and %d × 400", statuses, stock, parallel-stock)
+	}
+
+	return doAll(ctx, c,
+		Step{
+			Name: "stock", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK,
+			Expect: map[string]any{"quantity": 0},
+		},
+		Step{
+			Name: "analytics", Method: http.MethodGet, Path: "/api/analytics/{w}", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.sold_quantity": stock, "items.0.total_sum": "50"},
+		},
+	)
+}
+
+// concurrentRestocks: одновременные поступления без If-Match не теряются
+func concurrentRestocks(ctx context.Context, c *Client) error {
+	statuses, err := fire(ctx, c, Step{
+		Method: http.MethodPut, Path: "/api/inventory/update/{w}/{p}",
+		Body: `{"quantity": 2}`,
+	})
+	if err != nil {
+		return err
+	}
+	if statuses[http.StatusOK] != parallel {
+		return fmt.Errorf("restock statuses %v, want %d × 200", statuses, parallel)
+	}
+	return doAll(ctx, c, Step{
+		Name: "stock", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK,
+		Expect: map[string]any{"quantity": 5 + 2*parallel, "version": 1 + parallel},
+	})
+}
+
+// concurrentConditionalUpdates: из одновременных изменений с одной и той
+// же версией в If-Match применяется ровно одно
+func concurrentConditionalUpdates(ctx context.Context, c *Client) error {
+	statuses, err := fire(ctx, c, Step{
+		Method: http.MethodPut, Path: "/api/warehouse/update/{w}", Headers: ifMatch("1"),
+		Body: `{"name": "Renamed", "address": "New street"}`,
+	})
+	if err != nil {
+		return err
+	}
+	if statuses[http.StatusOK] != 1 || statuses[http.StatusPreconditionFailed] != parallel-1 {
+		return fmt.Errorf("update statuses %v, want 1 × 200 and %d × 412", statuses, parallel-1)
+	}
+	return doAll(ctx, c, Step{
+		Name: "warehouse", Method: http.MethodGet, Path: "/api/warehouse/{w}", Status: http.StatusOK,
+		Expect: map[string]any{"version": 2},
+	})
+}
+
+func doAll(ctx context.Context, c *Client, steps ...Step) error {
+	for _, step := range steps {
+		if err := c.Do(ctx, step); err != nil {
+			return fmt.Errorf("%s: %w", step.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+package apitest
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// missing — ID, которого нет ни в одном хранилище
+var missing = uuid.NewSHA1(uuid.NameSpaceOID, []byte("warehouse-service/apitest")).String()
+
+// Scenarios возвращает все сценарии набора.
+func Scenarios() []Scenario {
+	return []Scenario{
+		{Name: "probes", Steps: probes()},
+		{Name: "metrics", Requires: func(f Features) bool { return f.Metrics }, Steps: metricsSteps()},
+		{Name: "warehouse lifecycle", Steps: warehouseLifecycle()},
+		{Name: "warehouse pagination", Steps: warehousePagination()},
+		{Name: "product lifecycle", Steps: productLifecycle()},
+		{Name: "inventory lifecycle", Steps: inventoryLifecycle()},
+		{Name: "inventory listing", Steps: inventoryListing()},
+		{Name: "purchase and analytics", Steps: purchaseAndAnalytics()},
+		{Name: "idempotent purchase", Steps: idempotentPurchase()},
+		{Name: "warehouse delete cascades", Steps: warehouseCascade()},
+		{Name: "read caching", Steps: readCaching()},
+		{Name: "admin", Requires: func(f Features) bool { return f.Admin }, Steps: adminSteps()},
+		{Name: "audit", Requires: func(f Features) bool { return f.Audit }, Steps: auditSteps()},
+		{Name: "concurrent purchases", Steps: stock("w", "p", 20, "2.50"), Check: concurrentPurchases},
+		{Name: "concurrent restocks", Steps: stock("w", "p", 5, "1"), Check: concurrentRestocks},
+		{Name: "concurrent conditional updates", Steps: createWarehouse("w", "Main"), Check: concurrentConditionalUpdates},
+	}
+}
+
+// Общие шаги подготовки данных
+
+func createWarehouse(id, name string) []Step {
+	return []Step{{
+		Name:   "create warehouse " + name,
+		Method: http.MethodPost, Path: "/api/warehouse",
+		Body:   `{"name": "` + name + `", "address": "` + name + ` street 1"}`,
+		Status: http.StatusCreated,
+		Save:   map[string]string{id: "id"},
+	}}
+}
+
+func createProduct(id, name, barcode string) []Step {
+	return []Step{{
+		Name:   "create product " + name,
+		Method: http.MethodPost, Path: "/api/product",
+		Body:   `{"name": "` + name + `", "description": "test", "attributes": {"color": "red"}, "weight": 1.5, "barcode": "` + barcode + `"}`,
+		Status: http.StatusCreated,
+		Save:   map[string]string{id: "id"},
+	}}
+}
+
+func addInventory(warehouse, product string, quantity, price string) Step {
+	return Step{
+		Name:   "add " + product + " to " + warehouse,
+		Method: http.MethodPost, Path: "/api/inventory",
+		Body:   `{"product_id": "{` + product + `}", "warehouse_id": "{` + warehouse + `}", "quantity": ` + quantity + `, "price": "` + price + `"}`,
+		Status: http.StatusCreated,
+	}
+}
+
+// stock создает склад, товар и позицию с количеством quantity
+func stock(warehouse, product string, quantity int, price string) []Step {
+	steps := append(createWarehouse(warehouse, "Main"), createProduct(product, "Widget", "4600000000001")...)
+	return append(steps, addInventory(warehouse, product, itoa(quantity), price))
+}
+
+func ifNoneMatch(etag string) map[string]string {
+	return map[string]string{"If-None-Match": etag}
+}
+
+func ifMatch(version string) map[string]string {
+	return map[string]string{"If-Match": `"` + version + `"`}
+}
+
+func probes() []Step {
+	return []Step{
+		{Name: "liveness", Method: http.MethodGet, Path: "/livez", Status: http.StatusOK},
+		{Name: "readiness", Method: http.MethodGet, Path: "/readyz", Status: http.StatusOK},
+		{Name: "legacy health", Method: http.MethodGet, Path: "/api/health", Status: http.StatusOK},
+		{Name: "unknown route", Method: http.MethodGet, Path: "/api/unknown", Status: http.StatusNotFound},
+	}
+}
+
+func metricsSteps() []Step {
+	return []Step{
+		{Name: "request", Method: http.MethodGet, Path: "/livez", Status: http.StatusOK},
+		{Name: "scrape", Method: http.MethodGet, Path: "/metrics", Status: http.StatusOK},
+	}
+}
+
+func warehouseLifecycle() []Step {
+	steps := []Step{
+		{Name: "invalid body", Method: http.MethodPost, Path: "/api/warehouse", Body: `{`, Status: http.StatusBadRequest},
+	}
+	steps = append(steps, createWarehouse("w", "Main")...)
+	return append(steps,
+		Step{
+			Name: "get", Method: http.MethodGet, Path: "/api/warehouse/{w}", Status: http.StatusOK,
+			Expect: map[string]any{"name": "Main", "address": "Main street 1", "version": 1, "header:ETag": `"1"`},
+		},
+		Step{Name: "invalid id", Method: http.MethodGet, Path: "/api/warehouse/not-a-uuid", Status: http.StatusBadRequest},
+		Step{Name: "unknown id", Method: http.MethodGet, Path: "/api/warehouse/" + missing, Status: http.StatusNotFound},
+		Step{Name: "list", Method: http.MethodGet, Path: "/api/warehouses", Status: http.StatusOK, Expect: map[string]any{"items.#": 1, "items.0.id": "{w}"}},
+		Step{
+			Name: "replace", Method: http.MethodPut, Path: "/api/warehouse/update/{w}", Headers: ifMatch("1"),
+			Body:   `{"name": "Renamed", "address": "New street 2"}`,
+			Status: http.StatusOK,
+			Expect: map[string]any{"name": "Renamed", "version": 2, "header:ETag": `"2"`},
+		},
+		Step{
+			Name: "replace with stale version", Method: http.MethodPut, Path: "/api/warehouse/update/{w}", Headers: ifMatch("1"),
+			Body: `{"name": "Lost", "address": "Lost"}`, Status: http.StatusPreconditionFailed,
+		},
+		Step{
+			Name: "merge patch", Method: http.MethodPatch, Path: "/api/warehouse/update/{w}",
+			Headers: map[string]string{"If-Match": `"2"`, "Content-Type": "application/merge-patch+json"},
+			Body:    `{"description": "cold storage"}`,
+			Status:  http.StatusOK,
+			Expect:  map[string]any{"name": "Renamed", "description": "cold storage", "version": 3},
+		},
+		Step{
+			Name: "json patch", Method: http.MethodPatch, Path: "/api/warehouse/update/{w}",
+			Headers: map[string]string{"Content-Type": "application/json-patch+json"},
+			Body:    `[{"op": "replace", "path": "/address", "value": "Patched street 3"}]`,
+			Status:  http.StatusOK,
+			Expect:  map[string]any{"address": "Patched street 3", "version": 4},
+		},
+		Step{
+			Name: "legacy location update", Method: http.MethodPut, Path: "/api/warehouse/update/{w}",
+			Body:   `{"location": "Legacy street 4"}`,
+			Status: http.StatusOK,
+			Expect: map[string]any{"name": "Renamed", "address": "Legacy street 4", "description": "cold storage", "version": 5, "header:Deprecation": "true"},
+		},
+		Step{
+			Name: "unsupported patch", Method: http.MethodPatch, Path: "/api/warehouse/update/{w}",
+			Headers: map[string]string{"Content-Type": "text/plain"},
+			Body:    `{}`, Status: http.StatusUnsupportedMediaType,
+		},
+		Step{Name: "delete with stale version", Method: http.MethodDelete, Path: "/api/warehouse/delete/{w}", Headers: ifMatch("1"), Status: http.StatusPreconditionFailed},
+		Step{Name: "delete", Method: http.MethodDelete, Path: "/api/warehouse/delete/{w}", Headers: ifMatch("5"), Status: http.StatusNoContent},
+		Step{Name: "get deleted", Method: http.MethodGet, Path: "/api/warehouse/{w}", Status: http.StatusNotFound},
+		Step{Name: "delete again", Method: http.MethodDelete, Path: "/api/warehouse/delete/{w}", Status: http.StatusNotFound},
+	)
+}
+
+func warehousePagination() []Step {
+	var steps []Step
+	for _, name := range []string{"A", "B", "C"} {
+		steps = append(steps, createWarehouse("w"+name, name)...)
+	}
+	return append(steps,
+		Step{
+			Name: "first page", Method: http.MethodGet, Path: "/api/warehouses?limit=2&include_total=true", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 2, "total": 3},
+			Save:   map[string]string{"cursor": "next_cursor"},
+		},
+		Step{
+			Name: "last page", Method: http.MethodGet, Path: "/api/warehouses?limit=2&cursor={cursor}", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1},
+		},
+		Step{Name: "invalid cursor", Method: http.MethodGet, Path: "/api/warehouses?cursor=garbage", Status: http.StatusBadRequest},
+		// Курсор корректно закодирован, но ID в нем не UUID
+		Step{Name: "forged cursor", Method: http.MethodGet, Path: "/api/warehouses?cursor=eyJpZCI6Im5vdC1hLXV1aWQifQ", Status: http.StatusBadRequest},
+		Step{Name: "forged product cursor", Method: http.MethodGet, Path: "/api/products?cursor=eyJpZCI6Im5vdC1hLXV1aWQifQ", Status: http.StatusBadRequest},
+		Step{Name: "forged analytics cursor", Method: http.MethodGet, Path: "/api/analytics/{wA}?cursor=eyJpZCI6Im5vdC1hLXV1aWQifQ", Status: http.StatusBadRequest},
+		Step{Name: "invalid limit", Method: http.MethodGet, Path: "/api/warehouses?limit=abc", Status: http.StatusBadRequest},
+	)
+}
+
+func productLifecycle() []Step {
+	steps := []Step{
+		{Name: "invalid body", Method: http.MethodPost, Path: "/api/product", Body: `[]`, Status: http.StatusBadRequest},
+	}
+	steps = append(steps, createProduct("p", "Widget", "4600000000001")...)
+	return append(steps,
+		Step{
+			Name: "duplicate barcode", Method: http.MethodPost, Path: "/api/product",
+			Body: `{"name": "Copy", "barcode": "4600000000001"}`, Status: http.StatusConflict,
+		},
+		Step{
+			Name: "get", Method: http.MethodGet, Path: "/api/product/{p}", Status: http.StatusOK,
+			Expect: map[string]any{"id": "{p}", "name": "Widget", "attributes.color": "red", "weight": "1.5", "header:ETag": `"1"`},
+		},
+		Step{Name: "unknown id", Method: http.MethodGet, Path: "/api/product/" + missing, Status: http.StatusNotFound},
+		Step{Name: "list", Method: http.MethodGet, Path: "/api/products", Status: http.StatusOK, Expect: map[string]any{"items.#": 1}},
+		Step{
+			Name: "replace", Method: http.MethodPut, Path: "/api/product/update/{p}", Headers: ifMatch("1"),
+			Body:   `{"name": "Gadget", "description": "updated", "attributes": {"size": "L"}, "weight": 2, "barcode": "4600000000002"}`,
+			Status: http.StatusOK,
+			Expect: map[string]any{"name": "Gadget", "barcode": "4600000000002", "version": 2},
+		},
+		Step{
+			Name: "merge patch removes attribute", Method: http.MethodPatch, Path: "/api/product/update/{p}",
+			Headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+			Body:    `{"attributes": {"size": null, "material": "steel"}}`,
+			Status:  http.StatusOK,
+			Expect:  map[string]any{"attributes.material": "steel", "version": 3},
+		},
+		Step{Name: "delete with stale version", Method: http.MethodDelete, Path: "/api/product/delete/{p}", Headers: ifMatch("2"), Status: http.StatusPreconditionFailed},
+		Step{Name: "delete", Method: http.MethodDelete, Path: "/api/product/delete/{p}", Headers: ifMatch("3"), Status: http.StatusNoContent},
+		Step{Name: "get deleted", Method: http.MethodGet, Path: "/api/product/{p}", Status: http.StatusNotFound},
+	)
+}
+
+func inventoryLifecycle() []Step {
+	steps := stock("w", "p", 10, "100")
+	return append(steps,
+		Step{
+			Name: "unknown product", Method: http.MethodPost, Path: "/api/inventory",
+			Body: `{"product_id": "` + missing + `", "warehouse_id": "{w}", "quantity": 1, "price": "1"}`, Status: http.StatusNotFound,
+		},
+		Step{
+			Name: "unknown warehouse", Method: http.MethodPost, Path: "/api/inventory",
+			Body: `{"product_id": "{p}", "warehouse_id": "` + missing + `", "quantity": 1, "price": "1"}`, Status: http.StatusNotFound,
+		},
+		Step{
+			Name: "get position", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK,
+			Expect: map[string]any{"quantity": 10, "price": "100", "header:ETag": `"1"`},
+			Save:   map[string]string{"inv": "id"},
+		},
+		Step{Name: "unknown position", Method: http.MethodGet, Path: "/api/inventory/{w}/" + missing, Status: http.StatusNotFound},
+		Step{
+			Name: "restock", Method: http.MethodPut, Path: "/api/inventory/update/{w}/{p}", Headers: ifMatch("1"),
+			Body: `{"quantity": 5}`, Status: http.StatusOK, Expect: map[string]any{"status": "updated"},
+		},
+		Step{
+			Name: "restock with stale version", Method: http.MethodPut, Path: "/api/inventory/update/{w}/{p}", Headers: ifMatch("1"),
+			Body: `{"quantity": 5}`, Status: http.StatusPreconditionFailed,
+		},
+		Step{
+			Name: "write off more than stock", Method: http.MethodPut, Path: "/api/inventory/update/{w}/{p}",
+			Body: `{"quantity": -16}`, Status: http.StatusBadRequest,
+		},
+		Step{
+			Name: "discount", Method: http.MethodPut, Path: "/api/inventory/discount/{w}",
+			Body: `{"product_ids": ["{p}"], "discount": 10}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "position after changes", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK,
+			Expect: map[string]any{"quantity": 15, "discount": "10", "version": 3},
+		},
+		Step{
+			Name: "calculate total", Method: http.MethodPost, Path: "/api/inventory/calculate/{w}",
+			Body: `{"items": {"{p}": 2}}`, Status: http.StatusOK, Expect: map[string]any{"total": 180},
+		},
+		Step{
+			Name: "calculate total for missing product", Method: http.MethodPost, Path: "/api/inventory/calculate/{w}",
+			Body: `{"items": {"` + missing + `": 1}}`, Status: http.StatusNotFound,
+		},
+		Step{Name: "delete position with stale version", Method: http.MethodDelete, Path: "/api/inventory/{w}/{p}", Headers: ifMatch("1"), Status: http.StatusPreconditionFailed},
+		Step{Name: "delete position", Method: http.MethodDelete, Path: "/api/inventory/{w}/{p}", Headers: ifMatch("3"), Status: http.StatusOK},
+		Step{Name: "position deleted", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusNotFound},
+		addInventory("w", "p", "1", "1"),
+		Step{Name: "get new position", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK, Save: map[string]string{"inv": "id"}},
+		Step{Name: "delete by id", Method: http.MethodDelete, Path: "/api/inventory/{inv}", Status: http.StatusOK},
+		Step{Name: "delete by id again", Method: http.MethodDelete, Path: "/api/inventory/{inv}", Status: http.StatusNotFound},
+	)
+}
+
+func inventoryListing() []Step {
+	steps := createWarehouse("w", "Main")
+	steps = append(steps, createProduct("apple", "Apple", "4600000000011")...)
+	steps = append(steps, createProduct("banana", "Banana", "4600000000012")...)
+	steps = append(steps, createProduct("cherry", "Cherry", "4600000000013")...)
+	return append(steps,
+		addInventory("w", "apple", "5", "30"),
+		addInventory("w", "banana", "0", "10"),
+		addInventory("w", "cherry", "7", "20"),
+		Step{
+			Name: "discount cherry", Method: http.MethodPut, Path: "/api/inventory/discount/{w}",
+			Body: `{"product_ids": ["{cherry}"], "discount": 5}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "all", Method: http.MethodGet, Path: "/api/inventory/{w}?sort=product_name&include_total=true", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 3, "total": 3, "items.0.product_name": "Apple", "items.0.warehouse_name": "Main"},
+		},
+		Step{
+			Name: "sort by price desc", Method: http.MethodGet, Path: "/api/inventory/{w}?sort=price&order=desc", Status: http.StatusOK,
+			Expect: map[string]any{"items.0.product_name": "Apple", "items.2.product_name": "Banana"},
+		},
+		Step{
+			Name: "in stock", Method: http.MethodGet, Path: "/api/inventory/{w}?in_stock=true", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 2},
+		},
+		Step{
+			Name: "discounted", Method: http.MethodGet, Path: "/api/inventory/{w}?discounted=true", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.product_name": "Cherry"},
+		},
+		Step{
+			Name: "price range", Method: http.MethodGet, Path: "/api/inventory/{w}?min_price=15&max_price=25", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.product_name": "Cherry"},
+		},
+		Step{
+			Name: "search", Method: http.MethodGet, Path: "/api/inventory/{w}?search=ANA", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.product_name": "Banana"},
+		},
+		Step{
+			Name: "first page by quantity", Method: http.MethodGet, Path: "/api/inventory/{w}?sort=quantity&limit=2", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 2, "items.0.quantity": 0, "items.1.quantity": 5},
+			Save:   map[string]string{"cursor": "next_cursor"},
+		},
+		Step{
+			Name: "second page by quantity", Method: http.MethodGet, Path: "/api/inventory/{w}?sort=quantity&limit=2&cursor={cursor}", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.quantity": 7},
+		},
+		Step{Name: "cursor from another sort", Method: http.MethodGet, Path: "/api/inventory/{w}?sort=price&cursor={cursor}", Status: http.StatusBadRequest},
+		Step{Name: "invalid sort", Method: http.MethodGet, Path: "/api/inventory/{w}?sort=id", Status: http.StatusBadRequest},
+		Step{Name: "invalid order", Method: http.MethodGet, Path: "/api/inventory/{w}?order=up", Status: http.StatusBadRequest},
+		Step{Name: "invalid price range", Method: http.MethodGet, Path: "/api/inventory/{w}?min_price=5&max_price=1", Status: http.StatusBadRequest},
+		Step{Name: "invalid warehouse", Method: http.MethodGet, Path: "/api/inventory/not-a-uuid", Status: http.StatusBadRequest},
+	)
+}
+
+// readCaching проверяет, что записи сразу видны в закэшированных списках и
+// ценах, а ETag списков меняется вместе с содержимым
+func readCaching() []Step {
+	steps := stock("w", "p", 10, "4")
+	return append(steps,
+		Step{
+			Name: "list warehouses", Method: http.MethodGet, Path: "/api/warehouses", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "header:Cache-Control": "private, no-cache"},
+			Save:   map[string]string{"warehouses": "header:ETag"},
+		},
+		Step{Name: "warehouses not modified", Method: http.MethodGet, Path: "/api/warehouses", Headers: ifNoneMatch("{warehouses}"), Status: http.StatusNotModified},
+		Step{Name: "warehouses match any", Method: http.MethodGet, Path: "/api/warehouses", Headers: ifNoneMatch("*"), Status: http.StatusNotModified},
+		createWarehouse("second", "Second")[0],
+		Step{
+			Name: "warehouses modified", Method: http.MethodGet, Path: "/api/warehouses", Headers: ifNoneMatch("{warehouses}"), Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 2},
+		},
+		Step{
+			Name: "list products", Method: http.MethodGet, Path: "/api/products", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1},
+			Save:   map[string]string{"products": "header:ETag"},
+		},
+		Step{Name: "products not modified", Method: http.MethodGet, Path: "/api/products", Headers: ifNoneMatch("{products}"), Status: http.StatusNotModified},
+		Step{
+			Name: "rename product", Method: http.MethodPatch, Path: "/api/product/update/{p}",
+			Headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+			Body:    `{"name": "Gadget"}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "products modified", Method: http.MethodGet, Path: "/api/products", Headers: ifNoneMatch("{products}"), Status: http.StatusOK,
+			Expect: map[string]any{"items.0.name": "Gadget"},
+		},
+		Step{
+			Name: "calculate total", Method: http.MethodPost, Path: "/api/inventory/calculate/{w}",
+			Body: `{"items": {"{p}": 2}}`, Status: http.StatusOK, Expect: map[string]any{"total": 8},
+		},
+		addInventory("w", "p", "0", "5"),
+		Step{
+			Name: "total after price change", Method: http.MethodPost, Path: "/api/inventory/calculate/{w}",
+			Body: `{"items": {"{p}": 2}}`, Status: http.StatusOK, Expect: map[string]any{"total": 10},
+		},
+		Step{
+			Name: "discount", Method: http.MethodPut, Path: "/api/inventory/discount/{w}",
+			Body: `{"product_ids": ["{p}"], "discount": 20}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "total after discount", Method: http.MethodPost, Path: "/api/inventory/calculate/{w}",
+			Body: `{"items": {"{p}": 2}}`, Status: http.StatusOK, Expect: map[string]any{"total": 8},
+		},
+		Step{
+			Name: "purchase", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}",
+			Body: `{"items": {"{p}": 1}}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "purchase at current price", Method: http.MethodGet, Path: "/api/analytics/{w}", Status: http.StatusOK,
+			Expect: map[string]any{"items.0.total_sum": "4"},
+		},
+		Step{Name: "delete position", Method: http.MethodDelete, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK},
+		Step{
+			Name: "total for deleted position", Method: http.MethodPost, Path: "/api/inventory/calculate/{w}",
+			Body: `{"items": {"{p}": 1}}`, Status: http.StatusNotFound,
+		},
+	)
+}
+
+func purchaseAndAnalytics() []Step {
+	steps := stock("w", "p", 10, "4.50")
+	steps = append(steps, createWarehouse("empty", "Empty")...)
+	return append(steps,
+		Step{
+			Name: "purchase", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}",
+			Body: `{"items": {"{p}": 3}}`, Status: http.StatusOK, Expect: map[string]any{"status": "purchase successful"},
+		},
+		Step{
+			Name: "purchase more than stock", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}",
+			Body: `{"items": {"{p}": 8}}`, Status: http.StatusBadRequest,
+		},
+		Step{
+			Name: "purchase product not in stock", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}",
+			Body: `{"items": {"` + missing + `": 1}}`, Status: http.StatusBadRequest,
+		},
+		Step{
+			Name: "stock after purchase", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK,
+			Expect: map[string]any{"quantity": 7},
+		},
+		Step{
+			Name: "second purchase", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}",
+			Body: `{"items": {"{p}": 2}}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "warehouse analytics", Method: http.MethodGet, Path: "/api/analytics/{w}?include_total=true", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "total": 1, "items.0.product_id": "{p}", "items.0.sold_quantity": 5, "items.0.total_sum": "22.5"},
+		},
+		// Отчеты строятся фоновой задачей; в тестах она отключена
+		Step{
+			Name: "top before refresh", Method: http.MethodGet, Path: "/api/analytics/top", Status: http.StatusOK,
+			Expect: map[string]any{"as_of": "null", "items.#": 0},
+		},
+		Step{Name: "refresh reports", Method: http.MethodPost, Path: "/api/analytics/refresh", Status: http.StatusNoContent},
+		Step{
+			Name: "top warehouses", Method: http.MethodGet, Path: "/api/analytics/top?limit=1", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.warehouse_id": "{w}", "items.0.total_sum": "22.5"},
+		},
+		Step{Name: "top with invalid limit", Method: http.MethodGet, Path: "/api/analytics/top?limit=x", Status: http.StatusBadRequest},
+		Step{
+			Name: "daily revenue", Method: http.MethodGet, Path: "/api/analytics/{w}/daily", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.sold_quantity": 5, "items.0.revenue": "22.5"},
+		},
+		Step{
+			Name: "daily revenue for past period", Method: http.MethodGet, Path: "/api/analytics/{w}/daily?from=2020-01-01&to=2020-01-31", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 0},
+		},
+		Step{Name: "daily revenue with invalid date", Method: http.MethodGet, Path: "/api/analytics/{w}/daily?from=01.01.2020", Status: http.StatusBadRequest},
+		Step{Name: "daily revenue with reversed period", Method: http.MethodGet, Path: "/api/analytics/{w}/daily?from=2020-02-01&to=2020-01-01", Status: http.StatusBadRequest},
+		Step{Name: "daily revenue for too long period", Method: http.MethodGet, Path: "/api/analytics/{w}/daily?from=2020-01-01&to=2021-12-31", Status: http.StatusBadRequest},
+		// 5 × 4.50 = 22.5 по складу {w}; пустой склад не имеет остатков
+		Step{
+			Name: "stock value", Method: http.MethodGet, Path: "/api/analytics/stock-value", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 1, "items.0.warehouse_id": "{w}", "items.0.units": 5, "items.0.value": "22.5"},
+		},
+		Step{Name: "analytics for invalid warehouse", Method: http.MethodGet, Path: "/api/analytics/not-a-uuid", Status: http.StatusBadRequest},
+		Step{Name: "delete analytics", Method: http.MethodDelete, Path: "/api/analytics/delete/{w}/{p}", Status: http.StatusNoContent},
+		Step{
+			Name: "analytics after delete", Method: http.MethodGet, Path: "/api/analytics/{w}", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 0},
+		},
+		Step{
+			Name: "top after delete", Method: http.MethodGet, Path: "/api/analytics/top", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 0},
+		},
+	)
+}
+
+func idempotentPurchase() []Step {
+	key := map[string]string{"Idempotency-Key": "purchase-1"}
+	return append(stock("w", "p", 10, "1"),
+		Step{
+			Name: "purchase", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}", Headers: key,
+			Body: `{"items": {"{p}": 4}}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "retry", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}", Headers: key,
+			Body: `{"items": {"{p}": 4}}`, Status: http.StatusOK,
+			Expect: map[string]any{"header:Idempotency-Replayed": "true", "status": "purchase successful"},
+		},
+		Step{
+			Name: "same key with another payload", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}", Headers: key,
+			Body: `{"items": {"{p}": 5}}`, Status: http.StatusUnprocessableEntity,
+		},
+		Step{
+			Name: "stock charged once", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusOK,
+			Expect: map[string]any{"quantity": 6},
+		},
+	)
+}
+
+func warehouseCascade() []Step {
+	return append(stock("w", "p", 10, "1"),
+		Step{Name: "purchase", Method: http.MethodPost, Path: "/api/inventory/purchase/{w}", Body: `{"items": {"{p}": 1}}`, Status: http.StatusOK},
+		Step{Name: "delete warehouse", Method: http.MethodDelete, Path: "/api/warehouse/delete/{w}", Status: http.StatusNoContent},
+		Step{Name: "position removed", Method: http.MethodGet, Path: "/api/inventory/{w}/{p}", Status: http.StatusNotFound},
+		Step{Name: "analytics removed", Method: http.MethodGet, Path: "/api/analytics/{w}", Status: http.StatusOK, Expect: map[string]any{"items.#": 0}},
+		Step{Name: "product kept", Method: http.MethodGet, Path: "/api/product/{p}", Status: http.StatusOK},
+	)
+}
+
+func adminSteps() []Step {
+	steps := createWarehouse("w", "Main")
+	return append(steps,
+		Step{Name: "roles", Method: http.MethodGet, Path: "/api/admin/roles", Status: http.StatusOK},
+		Step{
+			Name: "create user", Method: http.MethodPost, Path: "/api/admin/users",
+			Body: `{"username": "alice", "roles": ["cashier"], "warehouse_ids": ["{w}"]}`, Status: http.StatusCreated,
+			Expect: map[string]any{"username": "alice", "roles.0": "cashier", "warehouse_ids.0": "{w}"},
+			Save:   map[string]string{"user": "id"},
+		},
+		Step{Name: "duplicate username", Method: http.MethodPost, Path: "/api/admin/users", Body: `{"username": "alice"}`, Status: http.StatusConflict},
+		Step{Name: "unknown role", Method: http.MethodPost, Path: "/api/admin/users", Body: `{"username": "bob", "roles": ["root"]}`, Status: http.StatusBadRequest},
+		Step{Name: "get user", Method: http.MethodGet, Path: "/api/admin/users/{user}", Status: http.StatusOK, Expect: map[string]any{"username": "alice"}},
+		Step{Name: "list users", Method: http.MethodGet, Path: "/api/admin/users", Status: http.StatusOK},
+		Step{
+			Name: "set roles", Method: http.MethodPut, Path: "/api/admin/users/{user}/roles",
+			Body: `{"roles": ["analyst", "warehouse_manager"]}`, Status: http.StatusOK,
+			Expect: map[string]any{"roles.#": 2},
+		},
+		Step{
+			Name: "unknown warehouse", Method: http.MethodPut, Path: "/api/admin/users/{user}/warehouses",
+			Body: `{"warehouse_ids": ["` + missing + `"]}`, Status: http.StatusBadRequest,
+		},
+		Step{
+			Name: "clear warehouses", Method: http.MethodPut, Path: "/api/admin/users/{user}/warehouses",
+			Body: `{"warehouse_ids": []}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "issue api key", Method: http.MethodPost, Path: "/api/admin/users/{user}/api-keys",
+			Body: `{"name": "ci"}`, Status: http.StatusCreated,
+			Save: map[string]string{"key": "id"},
+		},
+		Step{Name: "revoke api key", Method: http.MethodDelete, Path: "/api/admin/api-keys/{key}", Status: http.StatusNoContent},
+		Step{Name: "revoke unknown api key", Method: http.MethodDelete, Path: "/api/admin/api-keys/" + missing, Status: http.StatusNotFound},
+		Step{Name: "delete user", Method: http.MethodDelete, Path: "/api/admin/users/{user}", Status: http.StatusNoContent},
+		Step{Name: "get deleted user", Method: http.MethodGet, Path: "/api/admin/users/{user}", Status: http.StatusNotFound},
+	)
+}
+
+func auditSteps() []Step {
+	return append(createWarehouse("w", "Main"),
+		Step{
+			Name: "update", Method: http.MethodPut, Path: "/api/warehouse/update/{w}",
+			Body: `{"name": "Renamed", "address": "Main street 1"}`, Status: http.StatusOK,
+		},
+		Step{
+			Name: "history", Method: http.MethodGet, Path: "/api/audit?entity=warehouse&entity_id={w}", Status: http.StatusOK,
+			Expect: map[string]any{"items.#": 2},
+		},
+		Step{Name: "invalid time", Method: http.MethodGet, Path: "/api/audit?from=yesterday", Status: http.StatusBadRequest},
+	)
+}
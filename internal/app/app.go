@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job — фоновая задача. Run работает до отмены ctx и должен вернуться
+// вскоре после нее; ошибка останавливает приложение.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Every создает задачу, которая вызывает fn раз в interval. Ошибки fn
+// логируются и не останавливают приложение.
+func Every(name string, interval time.Duration, logger *zap.Logger, fn func(ctx context.Context) error) Job {
+	return Job{Name: name, Run: func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					logger.Error("Background job failed", zap.String("job", name), zap.Error(err))
+				}
+			}
+		}
+	}}
+}
+
+// Drainer переводит пробу готовности в состояние остановки (health.Checker).
+type Drainer interface {
+	SetDraining()
+}
+
+// Options — параметры остановки.
+type Options struct {
+	// DrainDelay — пауза между переводом готовности в 503 и закрытием
+	// сервера, чтобы балансировщик успел перестать слать запросы
+	DrainDelay time.Duration
+	// ShutdownTimeout — общий лимит на завершение запросов, задач и хуков
+	ShutdownTimeout time.Duration
+	// Signals — сигналы остановки; по умолчанию SIGINT и SIGTERM
+	Signals []os.Signal
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// App запускает HTTP-сервер и фоновые задачи и останавливает их по сигналу
+// в порядке: готовность → 503, ожидание DrainDelay, завершение текущих
+// запросов, остановка задач в обратном порядке, хуки остановки (закрытие
+// пула, сброс трасс) в порядке регистрации.
+type App struct {
+	logger  *zap.Logger
+	server  *http.Server
+	drainer Drainer
+	opts    Options
+	jobs    []Job
+	hooks   []hook
+}
+
+func New(logger *zap.Logger, server *http.Server, drainer Drainer, opts Options) *App {
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	return &App{logger: logger, server: server, drainer: drainer, opts: opts}
+}
+
+// AddJob регистрирует фоновую задачу.
+func (a *App) AddJob(job Job) {
+	a.jobs = append(a.jobs, job)
+}
+
+// OnShutdown регистрирует действие, выполняемое после остановки сервера и задач.
+func (a *App) OnShutdown(name string, fn func(ctx context.Context) error) {
+	a.hooks = append(a.hooks, hook{name: name, fn: fn})
+}
+
+// Run слушает адрес сервера и работает до сигнала остановки, отмены ctx
+// или ошибки сервера/задачи, после чего корректно останавливается.
+func (a *App) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", a.server.Addr, err)
+	}
+	return a.Serve(ctx, listener)
+}
+
+// Serve — то же, что Run, но на готовом listener.
+func (a *App) Serve(ctx context.Context, listener net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, a.opts.Signals...)
+	defer stop()
+
+	failed := make(chan error, 1+len(a.jobs))
+
+	a.logger.Info("Starting server", zap.String("addr", listener.Addr().String()))
+	go func() {
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+
+	// У каждой задачи свой контекст, чтобы останавливать их по очереди
+	type running struct {
+		name   string
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+	jobs := make([]running, 0, len(a.jobs))
+	for _, job := range a.jobs {
+		jobCtx, cancel := context.WithCancel(context.Background())
+		r := running{name: job.Name, cancel: cancel, done: make(chan struct{})}
+		jobs = append(jobs, r)
+		go func(job Job) {
+			defer close(r.done)
+			if err := job.Run(jobCtx); err != nil && jobCtx.Err() == nil {
+				failed <- fmt.Errorf("job %s: %w", job.Name, err)
+			}
+		}(job)
+	}
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+		a.logger.Info("Shutdown signal received")
+	case err := <-failed:
+		a.logger.Error("Stopping after failure", zap.Error(err))
+		errs = append(errs, err)
+	}
+	// Повторный сигнал во время остановки завершает процесс немедленно
+	stop()
+
+	if a.drainer != nil {
+		a.drainer.SetDraining()
+	}
+	if a.opts.DrainDelay > 0 && len(errs) == 0 {
+		a.logger.Info("Draining before shutdown", zap.Duration("delay", a.opts.DrainDelay))
+		time.Sleep(a.opts.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.opts.ShutdownTimeout)
+	defer cancel()
+
+	// Shutdown перестает принимать соединения и ждет завершения текущих запросов
+	a.logger.Info("Shutting down server...")
+	if err := a.server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("http server shutdown: %w", err))
+	}
+
+	for i := len(jobs) - 1; i >= 0; i-- {
+		jobs[i].cancel()
+		select {
+		case <-jobs[i].done:
+		case <-shutdownCtx.Done():
+			errs = append(errs, fmt.Errorf("job %s did not stop: %w", jobs[i].name, shutdownCtx.Err()))
+		}
+	}
+
+	for _, h := range a.hooks {
+		if err := h.fn(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		a.logger.Info("Server exited properly")
+	}
+	return errors.Join(errs...)
+}
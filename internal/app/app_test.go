@@ -0,0 +1,126 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/app"
+	"github.com/yourusername/warehouse-service/internal/config"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+// slowTx задерживает фиксацию транзакций, пока slow включен, и сообщает
+// в entered о начале такой транзакции
+type slowTx struct {
+	repository.Transactor
+	slow    *atomic.Bool
+	entered chan struct{}
+}
+
+func (t slowTx) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return t.Transactor.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := fn(repos); err != nil || !t.slow.Load() {
+			return err
+		}
+		t.entered <- struct{}{}
+		time.Sleep(500 * time.Millisecond)
+		return nil
+	})
+}
+
+// Покупка, начатая до SIGTERM, завершается ответом 200 и фиксируется, а
+// /readyz на время остановки отвечает 503.
+func TestShutdownCompletesInFlightPurchase(t *testing.T) {
+	store := memory.New()
+	storage := memory.NewStorage(store)
+	slow := &atomic.Bool{}
+	entered := make(chan struct{}, 1)
+	storage.Tx = slowTx{Transactor: storage.Tx, slow: slow, entered: entered}
+
+	deps := config.SetupDependencies(zap.NewNop(), storage, config.Options{Paging: pagination.DefaultLimits})
+	application := app.New(zap.NewNop(), &http.Server{Handler: deps.Router}, deps.Health, app.Options{
+		DrainDelay:      200 * time.Millisecond,
+		ShutdownTimeout: 5 * time.Second,
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + listener.Addr().String()
+	stopped := make(chan error, 1)
+	go func() { stopped <- application.Serve(context.Background(), listener) }()
+
+	post := func(path, body string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Post(base+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp.StatusCode, decoded
+	}
+	_, warehouse := post("/api/warehouse", `{"name": "Main", "address": "Main street 1"}`)
+	_, product := post("/api/product", `{"name": "Widget", "barcode": "4600000000001"}`)
+	w, p := warehouse["id"].(string), product["id"].(string)
+	if status, _ := post("/api/inventory", `{"product_id": "`+p+`", "warehouse_id": "`+w+`", "quantity": 10, "price": "2"}`); status != http.StatusCreated {
+		t.Fatalf("create inventory: status %d", status)
+	}
+
+	slow.Store(true)
+	purchased := make(chan int, 1)
+	go func() {
+		status, _ := post("/api/inventory/purchase/"+w, `{"items": {"`+p+`": 3}}`)
+		purchased <- status
+	}()
+	<-entered
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	// Во время задержки перед закрытием сервер еще принимает запросы
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := http.Get(base + "/readyz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusServiceUnavailable {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("/readyz did not switch to 503 after SIGTERM")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status := <-purchased; status != http.StatusOK {
+		t.Fatalf("in-flight purchase: status %d, want 200", status)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	inv, err := store.Repositories().Inventory.GetProductInWarehouse(context.Background(), uuid.MustParse(p), uuid.MustParse(w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Quantity != 7 {
+		t.Fatalf("quantity after shutdown = %d, want 7: purchase was not committed", inv.Quantity)
+	}
+	if _, err := http.Get(base + "/livez"); err == nil {
+		t.Fatal("server still accepts connections after shutdown")
+	}
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"go.uber.org/zap"
+)
+
+// Действия, попадающие в журнал
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionPurchase = "purchase"
+)
+
+// anonymousActor записывается, когда аутентификация выключена.
+const anonymousActor = "anonymous"
+
+// Store сохраняет записи журнала.
+type Store interface {
+	Record(ctx context.Context, entry models.AuditEntry) error
+}
+
+// Recorder пишет в журнал аудита изменения сущностей. Нулевой *Recorder
+// ничего не делает, что упрощает использование в тестах и CLI.
+type Recorder struct {
+	store  Store
+	logger *zap.Logger
+}
+
+func NewRecorder(store Store, logger *zap.Logger) *Recorder {
+	return &Recorder{store: store, logger: logger}
+}
+
+// Record сохраняет событие: кто (из контекста запроса), что сделал и с какой
+// сущностью, состояние до и после и разницу между ними. before или after
+// равны nil для создания и удаления. Ошибка записи журнала логируется,
+// но не прерывает уже выполненную операцию.
+func (r *Recorder) Record(ctx context.Context, action, entityType, entityID string, before, after any) {
+	if r == nil {
+		return
+	}
+
+	entry := models.AuditEntry{
+		Actor:      Actor(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  logging.RequestID(ctx),
+	}
+
+	var err error
+	if entry.Before, err = marshalState(before); err == nil {
+		if entry.After, err = marshalState(after); err == nil {
+			entry.Diff, err = Diff(entry.Before, entry.After)
+		}
+	}
+	if err == nil {
+		err = r.store.Record(ctx, entry)
+	}
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to write audit entry",
+			zap.Error(err),
+			zap.String("action", action),
+			zap.String("entity_type", entityType),
+			zap.String("entity_id", entityID))
+	}
+}
+
+// Actor возвращает субъекта запроса для журнала.
+func Actor(ctx context.Context) string {
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		return p.Subject
+	}
+	return anonymousActor
+}
+
+func marshalState(v any) (json.RawMessage, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// Change — изменение одного поля.
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Diff сравнивает два JSON-объекта по полям верхнего уровня и возвращает
+// {"поле": {"from": ..., "to": ...}} только для изменившихся полей.
+func Diff(before, after json.RawMessage) (json.RawMessage, error) {
+	var from, to map[string]any
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &from); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &to); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := map[string]Change{}
+	for field, old := range from {
+		if updated, ok := to[field]; !ok || !reflect.DeepEqual(old, updated) {
+			changes[field] = Change{From: old, To: to[field]}
+		}
+	}
+	for field, updated := range to {
+		if _, ok := from[field]; !ok {
+			changes[field] = Change{To: updated}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changes)
+}
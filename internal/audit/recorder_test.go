@@ -0,0 +1,40 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"go.uber.org/zap"
+)
+
+type store []models.AuditEntry
+
+func (s *store) Record(_ context.Context, entry models.AuditEntry) error {
+	*s = append(*s, entry)
+	return nil
+}
+
+func TestRecorderTakesActorAndRequestIDFromContext(t *testing.T) {
+	var entries store
+	recorder := audit.NewRecorder(&entries, zap.NewNop())
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "user:1"})
+	recorder.Record(ctx, audit.ActionUpdate, "warehouse", "w1",
+		map[string]string{"name": "Old"}, map[string]string{"name": "New"})
+
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	got := entries[0]
+	if got.Actor != "user:1" || got.RequestID != "req-1" || got.EntityID != "w1" {
+		t.Fatalf("entry = %+v", got)
+	}
+	if len(got.Diff) == 0 {
+		t.Fatal("diff is empty")
+	}
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// apiKeyPrefix помогает распознать ключ сервиса в логах и сканерах секретов.
+const apiKeyPrefix = "wh_"
+
+// GenerateAPIKey создает новый случайный API-ключ. Клиенту он показывается
+// один раз, в базе хранится только HashAPIKey от него.
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashAPIKey возвращает SHA-256 ключа в hex. Ключи высокоэнтропийные,
+// поэтому медленное хэширование не требуется.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// APIKeyHeader — заголовок для передачи API-ключа интеграций.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	// ErrNoCredentials — запрос не содержит ни API-ключа, ни токена.
+	ErrNoCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials — ключ не найден или токен не прошел проверку.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// APIKeyStore ищет действующий API-ключ по его хэшу.
+type APIKeyStore interface {
+	GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+}
+
+// UserStore возвращает учетную запись пользователя с ролями и складами.
+type UserStore interface {
+	GetUserBySubject(ctx context.Context, subject string) (*models.User, error)
+}
+
+// JWTConfig задает параметры проверки bearer-токенов.
+type JWTConfig struct {
+	Keys     *KeySet
+	Issuer   string // пусто — не проверяется
+	Audience string // пусто — не проверяется
+}
+
+// Authenticator определяет субъекта запроса по API-ключу или JWT.
+type Authenticator struct {
+	apiKeys APIKeyStore
+	users   UserStore
+	jwt     *JWTConfig
+	parser  *jwt.Parser
+}
+
+// NewAuthenticator создает аутентификатор. jwtConfig = nil отключает JWT.
+func NewAuthenticator(apiKeys APIKeyStore, users UserStore, jwtConfig *JWTConfig) *Authenticator {
+	a := &Authenticator{apiKeys: apiKeys, users: users, jwt: jwtConfig}
+	if jwtConfig != nil {
+		opts := []jwt.ParserOption{
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodHS256.Alg()}),
+			jwt.WithExpirationRequired(),
+		}
+		if jwtConfig.Issuer != "" {
+			opts = append(opts, jwt.WithIssuer(jwtConfig.Issuer))
+		}
+		if jwtConfig.Audience != "" {
+			opts = append(opts, jwt.WithAudience(jwtConfig.Audience))
+		}
+		a.parser = jwt.NewParser(opts...)
+	}
+	return a
+}
+
+// Authenticate проверяет X-API-Key или Authorization: Bearer
+// и дополняет субъекта ролями и складами его учетной записи.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	p, err := a.credentials(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.loadUser(r.Context(), p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (a *Authenticator) credentials(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(r.Context(), key)
+	}
+
+	scheme, credentials, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found {
+		return nil, ErrNoCredentials
+	}
+	switch strings.ToLower(scheme) {
+	case "apikey":
+		return a.authenticateAPIKey(r.Context(), strings.TrimSpace(credentials))
+	case "bearer":
+		return a.authenticateJWT(strings.TrimSpace(credentials))
+	default:
+		return nil, ErrNoCredentials
+	}
+}
+
+// loadUser заполняет роли субъекта. Субъект без учетной записи
+// аутентифицирован, но не получает никаких прав.
+func (a *Authenticator) loadUser(ctx context.Context, p *Principal) error {
+	p.Roles = []string{}
+	p.WarehouseIDs = []uuid.UUID{}
+	if a.users == nil {
+		return nil
+	}
+	user, err := a.users.GetUserBySubject(ctx, p.Subject)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.UserID = &user.ID
+	p.Roles = user.Roles
+	p.WarehouseIDs = user.WarehouseIDs
+	return nil
+}
+
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	if a.apiKeys == nil {
+		return nil, ErrInvalidCredentials
+	}
+	apiKey, err := a.apiKeys.GetActiveByHash(ctx, HashAPIKey(key))
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: apiKey.Subject, Name: apiKey.Name, Method: MethodAPIKey}, nil
+}
+
+func (a *Authenticator) authenticateJWT(raw string) (*Principal, error) {
+	if a.parser == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	p := &Principal{Subject: subject, Method: MethodJWT}
+	if name, ok := claims["name"].(string); ok {
+		p.Name = name
+	}
+	return p, nil
+}
+
+// keyFunc выбирает ключ по kid и не допускает подмены алгоритма:
+// RSA-ключ принимается только для RS256, секрет — только для HS256.
+func (a *Authenticator) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	keys := a.jwt.Keys
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if key, ok := keys.rsa[kid]; ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodHMAC:
+		if key, ok := keys.hmac[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, errors.New("unknown signing key")
+}
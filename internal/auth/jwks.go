@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwk — ключ из JWKS (RFC 7517). Поддерживаются RSA (RS256) и oct (HS256).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// KeySet — ключи проверки подписи JWT, индексированные по kid.
+type KeySet struct {
+	rsa  map[string]*rsa.PublicKey
+	hmac map[string][]byte
+}
+
+// LoadKeySet читает JWKS из локального файла.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	return ParseKeySet(data)
+}
+
+// ParseKeySet разбирает JWKS-документ.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	set := &KeySet{rsa: map[string]*rsa.PublicKey{}, hmac: map[string][]byte{}}
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			if k.Alg != "" && k.Alg != "RS256" {
+				return nil, fmt.Errorf("key %q: unsupported alg %s", k.Kid, k.Alg)
+			}
+			pub, err := parseRSAKey(k)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+			}
+			set.rsa[k.Kid] = pub
+		case "oct":
+			if k.Alg != "" && k.Alg != "HS256" {
+				return nil, fmt.Errorf("key %q: unsupported alg %s", k.Kid, k.Alg)
+			}
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("key %q: invalid secret", k.Kid)
+			}
+			set.hmac[k.Kid] = secret
+		default:
+			return nil, fmt.Errorf("key %q: unsupported kty %s", k.Kid, k.Kty)
+		}
+	}
+	if len(set.rsa) == 0 && len(set.hmac) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+	return set, nil
+}
+
+func parseRSAKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+	exponent := 0
+	for _, b := range e {
+		exponent = exponent<<8 | int(b)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
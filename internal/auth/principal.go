@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Способы аутентификации
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal — аутентифицированный субъект запроса.
+// Roles и WarehouseIDs заполняются из учетной записи пользователя с id или
+// username, равным Subject; без учетной записи у субъекта нет прав.
+type Principal struct {
+	Subject      string      `json:"subject"`
+	Name         string      `json:"name,omitempty"`
+	Method       string      `json:"method"`
+	UserID       *uuid.UUID  `json:"user_id,omitempty"`
+	Roles        []string    `json:"roles"`
+	WarehouseIDs []uuid.UUID `json:"warehouse_ids"`
+}
+
+type ctxKey struct{}
+
+// WithPrincipal кладет субъекта в контекст запроса.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// PrincipalFromContext возвращает субъекта запроса или nil, если запрос не аутентифицирован.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(ctxKey{}).(*Principal)
+	return p
+}
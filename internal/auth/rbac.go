@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Роли пользователей
+const (
+	RoleAdmin            = "admin"
+	RoleWarehouseManager = "warehouse_manager"
+	RoleCashier          = "cashier"
+	RoleAnalyst          = "analyst"
+)
+
+// Permission — право на группу операций API.
+type Permission string
+
+const (
+	PermWarehouseRead     Permission = "warehouse:read"
+	PermWarehouseWrite    Permission = "warehouse:write"
+	PermProductRead       Permission = "product:read"
+	PermProductWrite      Permission = "product:write"
+	PermInventoryRead     Permission = "inventory:read"
+	PermInventoryWrite    Permission = "inventory:write"
+	PermInventoryPurchase Permission = "inventory:purchase"
+	PermAnalyticsRead     Permission = "analytics:read"
+	PermAnalyticsWrite    Permission = "analytics:write"
+	PermAuditRead         Permission = "audit:read"
+	PermAdmin             Permission = "admin"
+)
+
+// Grant выдает право на все склады (Scoped = false)
+// или только на склады, закрепленные за пользователем.
+type Grant struct {
+	Permission Permission `json:"permission"`
+	Scoped     bool       `json:"scoped"`
+}
+
+// RolePermissions описывает права каждой роли.
+var RolePermissions = map[string][]Grant{
+	RoleAdmin: {
+		{Permission: PermWarehouseRead}, {Permission: PermWarehouseWrite},
+		{Permission: PermProductRead}, {Permission: PermProductWrite},
+		{Permission: PermInventoryRead}, {Permission: PermInventoryWrite}, {Permission: PermInventoryPurchase},
+		{Permission: PermAnalyticsRead}, {Permission: PermAnalyticsWrite},
+		{Permission: PermAuditRead}, {Permission: PermAdmin},
+	},
+	RoleWarehouseManager: {
+		{Permission: PermWarehouseRead}, {Permission: PermProductRead},
+		{Permission: PermInventoryRead, Scoped: true},
+		{Permission: PermInventoryWrite, Scoped: true},
+		{Permission: PermInventoryPurchase, Scoped: true},
+		{Permission: PermAnalyticsRead, Scoped: true},
+	},
+	RoleCashier: {
+		{Permission: PermWarehouseRead}, {Permission: PermProductRead},
+		{Permission: PermInventoryRead, Scoped: true},
+		{Permission: PermInventoryPurchase, Scoped: true},
+	},
+	RoleAnalyst: {
+		{Permission: PermWarehouseRead}, {Permission: PermProductRead},
+		{Permission: PermInventoryRead},
+		{Permission: PermAnalyticsRead},
+	},
+}
+
+// ValidRole сообщает, существует ли роль.
+func ValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// Can проверяет право субъекта. warehouseID = nil означает операцию без
+// конкретного склада: тогда подходит только право на все склады.
+func (p *Principal) Can(perm Permission, warehouseID *uuid.UUID) bool {
+	for _, role := range p.Roles {
+		for _, grant := range RolePermissions[role] {
+			if grant.Permission != perm {
+				continue
+			}
+			if !grant.Scoped || (warehouseID != nil && p.assigned(*warehouseID)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission проверяет наличие права хотя бы на один склад.
+func (p *Principal) HasPermission(perm Permission) bool {
+	for _, role := range p.Roles {
+		for _, grant := range RolePermissions[role] {
+			if grant.Permission == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Principal) assigned(warehouseID uuid.UUID) bool {
+	for _, id := range p.WarehouseIDs {
+		if id == warehouseID {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed проверяет право субъекта из контекста. Если аутентификация
+// выключена и субъекта нет, операция разрешена.
+func Allowed(ctx context.Context, perm Permission, warehouseID *uuid.UUID) bool {
+	p := PrincipalFromContext(ctx)
+	if p == nil {
+		return true
+	}
+	return p.Can(perm, warehouseID)
+}
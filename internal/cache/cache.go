@@ -0,0 +1,19 @@
+// Package cache — хранилища для кэша чтений: LRU в памяти процесса и
+// Redis (или совместимый сервер: Valkey, KeyDB, miniredis в тестах).
+// Значения — байты, поэтому реализации взаимозаменяемы; что и как
+// кэшировать, решает вызывающий код (см. repository/cached).
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache — хранилище значений со сроком жизни.
+type Cache interface {
+	// Get возвращает значение и true, если ключ есть и не истек.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set сохраняет значение; ttl 0 — без срока (до вытеснения).
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
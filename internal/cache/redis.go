@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis — кэш на сервере Redis, общий для всех реплик сервиса: сброс
+// кэша одной репликой виден остальным. Ключи получают префикс, чтобы
+// несколько сервисов могли делить одну базу Redis.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Cache = (*Redis)(nil)
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// OpenRedis создает клиент по URL вида redis://[:password@]host:port/db.
+// Соединения устанавливаются при первых запросах (см. Ping).
+func OpenRedis(url, prefix string) (*Redis, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedis(redis.NewClient(options), prefix), nil
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.client.Del(ctx, prefixed...).Err()
+}
+
+// Ping проверяет доступность сервера.
+func (c *Redis) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// Close закрывает соединения с сервером.
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/cache"
+	"go.uber.org/zap"
+)
+
+// cachePingTimeout ограничивает проверку Redis при запуске
+const cachePingTimeout = 2 * time.Second
+
+// OpenCache создает кэш чтений по cache.driver. Для none возвращает nil.
+// Недоступный при запуске Redis не мешает старту: ошибки кэша только
+// логируются, а чтения идут в хранилище.
+func (c *Config) OpenCache(ctx context.Context, logger *zap.Logger) (cache.Cache, func() error, error) {
+	noop := func() error { return nil }
+	switch c.Cache.Driver {
+	case CacheMemory:
+		return cache.NewLRU(c.Cache.Size), noop, nil
+	case CacheRedis:
+		client, err := cache.OpenRedis(c.Cache.Redis.URL, c.Cache.Redis.Prefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cache.redis.url: %w", err)
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, cachePingTimeout)
+		defer cancel()
+		if err := client.Ping(pingCtx); err != nil {
+			logger.Warn("Cache Redis is unavailable, reads fall back to storage", zap.Error(err))
+		}
+		// Close вызывается и хуком остановки, и defer в main
+		return client, sync.OnceValue(client.Close), nil
+	default:
+		return nil, noop, nil
+	}
+}
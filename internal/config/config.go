@@ -0,0 +1,544 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/tracing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Хранилища корзин ограничителя запросов
+const (
+	RateLimitMemory   = "memory"
+	RateLimitRedis    = "redis"
+	RateLimitPostgres = "postgres"
+)
+
+// Драйверы кэша чтений
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+// DefaultConfigFile читается, если путь не задан через -config или CONFIG_FILE.
+const DefaultConfigFile = "config/config.yaml"
+
+// Config — настройки сервиса. Источники применяются по порядку, каждый
+// следующий переопределяет предыдущий: значения по умолчанию, YAML-файл,
+// переменные окружения, флаги командной строки.
+type Config struct {
+	Server      ServerConfig              `yaml:"server"`
+	Storage     StorageConfig             `yaml:"storage"`
+	Database    repository.DatabaseConfig `yaml:"database"`
+	Log         LogConfig                 `yaml:"log"`
+	Pagination  PaginationConfig          `yaml:"pagination"`
+	Features    FeaturesConfig            `yaml:"features"`
+	Auth        AuthConfig                `yaml:"auth"`
+	Idempotency IdempotencyConfig         `yaml:"idempotency"`
+	Reports     ReportsConfig             `yaml:"reports"`
+	Cache       CacheConfig               `yaml:"cache"`
+	RateLimit   RateLimitConfig           `yaml:"rate_limit"`
+	Tracing     TracingConfig             `yaml:"tracing"`
+
+	// warnings — исправленные при загрузке настройки, о которых нужно
+	// сообщить в лог
+	warnings []string
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// DrainDelay — сколько /readyz отвечает 503 перед остановкой сервера
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout — лимит на завершение запросов, фоновых задач и хуков остановки
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type StorageConfig struct {
+	// Driver — имя зарегистрированного драйвера (repository.RegisterStorage):
+	// postgres, sqlite или memory (без базы; данные теряются при остановке)
+	Driver string                  `yaml:"driver"`
+	SQLite repository.SQLiteConfig `yaml:"sqlite"`
+}
+
+type LogConfig struct {
+	// Level — debug, info, warn или error
+	Level string `yaml:"level"`
+	// Format — console (читаемый) или json
+	Format string `yaml:"format"`
+}
+
+type PaginationConfig struct {
+	DefaultLimit int `yaml:"default_limit"`
+	MaxLimit     int `yaml:"max_limit"`
+}
+
+type FeaturesConfig struct {
+	// Auth требует API-ключ или JWT на всех маршрутах, кроме проб и метрик
+	Auth bool `yaml:"auth"`
+	// RequireIfMatch отвечает 428 на изменения версионируемых записей без If-Match
+	RequireIfMatch bool `yaml:"require_if_match"`
+	// Metrics публикует /metrics
+	Metrics bool `yaml:"metrics"`
+}
+
+type AuthConfig struct {
+	JWKSFile    string `yaml:"jwks_file"`
+	JWTIssuer   string `yaml:"jwt_issuer"`
+	JWTAudience string `yaml:"jwt_audience"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// ReportsConfig — обновление предрассчитанных отчетов аналитики.
+// RefreshInterval 0 отключает фоновое обновление (только POST /api/analytics/refresh).
+type ReportsConfig struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// CacheConfig — кэш чтений перед хранилищем: списки складов и товаров,
+// цены товаров на складах.
+type CacheConfig struct {
+	// Driver — none, memory (LRU в процессе; реплики не видят сбросы друг
+	// друга до истечения TTL) или redis (общий для реплик)
+	Driver string        `yaml:"driver"`
+	TTL    time.Duration `yaml:"ttl"`
+	// Size — число записей LRU (driver memory)
+	Size  int              `yaml:"size"`
+	Redis RedisCacheConfig `yaml:"redis"`
+}
+
+type RedisCacheConfig struct {
+	URL string `yaml:"url"`
+	// Prefix отделяет ключи сервиса в общей базе Redis
+	Prefix string `yaml:"prefix"`
+}
+
+type RateLimitConfig struct {
+	// Квоты в формате "<запросов>/<период>", например 600/1m; пусто — без ограничения
+	Reads     string `yaml:"reads"`
+	Writes    string `yaml:"writes"`
+	Purchases string `yaml:"purchases"`
+	// IP — общая квота адреса, проверяемая до аутентификации
+	IP string `yaml:"ip"`
+	// Store — memory (у каждой реплики свои корзины), redis (общие корзины)
+	// или postgres (общие корзины ценой записи в базу на каждый запрос)
+	Store      string `yaml:"store"`
+	RedisURL   string `yaml:"redis_url"`
+	TrustProxy bool   `yaml:"trust_proxy"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Default возвращает настройки по умолчанию.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       5 * time.Second,
+			ReadHeaderTimeout: 2 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Storage: StorageConfig{Driver: repository.StoragePostgres, SQLite: repository.SQLiteConfig{Path: "warehouse.db"}},
+		Database: repository.DatabaseConfig{
+			MaxConns:        10,
+			MinConns:        0,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+			Replica: repository.ReplicaConfig{
+				MaxConns:      10,
+				MaxLag:        2 * time.Second,
+				CheckInterval: time.Second,
+			},
+		},
+		Log:         LogConfig{Level: "info", Format: "console"},
+		Pagination:  PaginationConfig{DefaultLimit: pagination.DefaultLimits.Default, MaxLimit: pagination.DefaultLimits.Max},
+		Features:    FeaturesConfig{Auth: true, Metrics: true},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour},
+		Reports:     ReportsConfig{RefreshInterval: time.Minute},
+		RateLimit:   RateLimitConfig{IP: "1200/1m", Store: RateLimitMemory},
+		Tracing:     TracingConfig{Exporter: tracing.ExporterNone, SampleRatio: 1},
+		Cache: CacheConfig{
+			Driver: CacheMemory,
+			TTL:    30 * time.Second,
+			Size:   10000,
+			Redis:  RedisCacheConfig{Prefix: "warehouse:"},
+		},
+	}
+}
+
+// binding связывает поле конфигурации с переменной окружения и флагом.
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	target any // *string, *int, *bool, *float64 или *time.Duration
+}
+
+func (c *Config) bindings() []binding {
+	return []binding{
+		{"APP_PORT", "port", "HTTP port", &c.Server.Port},
+		{"SERVER_READ_TIMEOUT", "read-timeout", "HTTP read timeout", &c.Server.ReadTimeout},
+		{"SERVER_READ_HEADER_TIMEOUT", "read-header-timeout", "HTTP read header timeout", &c.Server.ReadHeaderTimeout},
+		{"SERVER_WRITE_TIMEOUT", "write-timeout", "HTTP write timeout", &c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", "idle-timeout", "HTTP keep-alive idle timeout", &c.Server.IdleTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", "drain-delay", "time /readyz reports draining before shutdown", &c.Server.DrainDelay},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time to wait for in-flight requests on shutdown", &c.Server.ShutdownTimeout},
+
+		{"STORAGE", "storage", "storage backend: postgres, sqlite or memory", &c.Storage.Driver},
+		{"SQLITE_PATH", "sqlite-path", "SQLite database file for storage sqlite", &c.Storage.SQLite.Path},
+
+		{"DB_URL", "db-url", "PostgreSQL connection URL", &c.Database.URL},
+		{"DB_MAX_CONNS", "db-max-conns", "maximum pool size", &c.Database.MaxConns},
+		{"DB_MIN_CONNS", "db-min-conns", "minimum pool size", &c.Database.MinConns},
+		{"DB_MAX_CONN_LIFETIME", "db-max-conn-lifetime", "maximum connection lifetime", &c.Database.MaxConnLifetime},
+		{"DB_MAX_CONN_IDLE_TIME", "db-max-conn-idle-time", "maximum connection idle time", &c.Database.MaxConnIdleTime},
+		{"DB_REPLICA_URL", "db-replica-url", "PostgreSQL read replica URL for lists and analytics", &c.Database.Replica.URL},
+		{"DB_REPLICA_MAX_CONNS", "db-replica-max-conns", "maximum replica pool size", &c.Database.Replica.MaxConns},
+		{"DB_REPLICA_MAX_LAG", "db-replica-max-lag", "replica lag above which reads go to the primary", &c.Database.Replica.MaxLag},
+		{"DB_REPLICA_CHECK_INTERVAL", "db-replica-check-interval", "how often replica lag is measured", &c.Database.Replica.CheckInterval},
+
+		{"LOG_LEVEL", "log-level", "log level: debug, info, warn, error", &c.Log.Level},
+		{"LOG_FORMAT", "log-format", "log format: console or json", &c.Log.Format},
+
+		{"PAGINATION_DEFAULT_LIMIT", "pagination-default-limit", "default page size", &c.Pagination.DefaultLimit},
+		{"PAGINATION_MAX_LIMIT", "pagination-max-limit", "maximum page size", &c.Pagination.MaxLimit},
+
+		{"AUTH_ENABLED", "auth", "require API key or JWT", &c.Features.Auth},
+		{"REQUIRE_IF_MATCH", "require-if-match", "require If-Match on versioned updates", &c.Features.RequireIfMatch},
+		{"METRICS_ENABLED", "metrics", "expose /metrics", &c.Features.Metrics},
+
+		{"AUTH_JWKS_FILE", "jwks-file", "JWKS file for JWT verification", &c.Auth.JWKSFile},
+		{"AUTH_JWT_ISSUER", "jwt-issuer", "expected JWT issuer", &c.Auth.JWTIssuer},
+		{"AUTH_JWT_AUDIENCE", "jwt-audience", "expected JWT audience", &c.Auth.JWTAudience},
+
+		{"IDEMPOTENCY_TTL", "idempotency-ttl", "how long Idempotency-Key responses are kept", &c.Idempotency.TTL},
+
+		{"REPORTS_REFRESH_INTERVAL", "reports-refresh-interval", "how often analytics reports are refreshed (0 disables)", &c.Reports.RefreshInterval},
+
+		{"CACHE_DRIVER", "cache", "read cache: none, memory or redis", &c.Cache.Driver},
+		{"CACHE_TTL", "cache-ttl", "how long cached reads are kept", &c.Cache.TTL},
+		{"CACHE_SIZE", "cache-size", "maximum entries of the memory cache", &c.Cache.Size},
+		{"CACHE_REDIS_URL", "cache-redis-url", "Redis URL for cache redis", &c.Cache.Redis.URL},
+		{"CACHE_REDIS_PREFIX", "cache-redis-prefix", "key prefix in Redis", &c.Cache.Redis.Prefix},
+
+		{"RATE_LIMIT_READS", "rate-limit-reads", "read quota, e.g. 600/1m", &c.RateLimit.Reads},
+		{"RATE_LIMIT_WRITES", "rate-limit-writes", "write quota, e.g. 120/1m", &c.RateLimit.Writes},
+		{"RATE_LIMIT_PURCHASES", "rate-limit-purchases", "purchase quota, e.g. 60/1m", &c.RateLimit.Purchases},
+		{"RATE_LIMIT_IP", "rate-limit-ip", "per-IP quota checked before authentication, e.g. 1200/1m", &c.RateLimit.IP},
+		{"RATE_LIMIT_STORE", "rate-limit-store", "rate limit store: memory, redis or postgres", &c.RateLimit.Store},
+		{"RATE_LIMIT_REDIS_URL", "rate-limit-redis-url", "Redis URL for rate limit store redis", &c.RateLimit.RedisURL},
+		{"TRUST_PROXY", "trust-proxy", "take client IP from X-Forwarded-For", &c.RateLimit.TrustProxy},
+
+		{"TRACING_EXPORTER", "tracing-exporter", "tracing exporter: none, otlp or stdout", &c.Tracing.Exporter},
+		{"TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP collector host:port", &c.Tracing.Endpoint},
+		{"TRACING_INSECURE", "tracing-insecure", "send traces without TLS", &c.Tracing.Insecure},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "share of traced requests (0..1]", &c.Tracing.SampleRatio},
+	}
+}
+
+// Load собирает конфигурацию из файла, окружения и аргументов командной
+// строки (без имени программы) и проверяет ее. Аргументы после флагов
+// (подкоманда) возвращаются вторым значением. -h выводит список флагов
+// и возвращает flag.ErrHelp.
+func Load(args []string, output io.Writer) (*Config, []string, error) {
+	cfg := Default()
+	bindings := cfg.bindings()
+
+	// Флаги применяются последними, поэтому при разборе только запоминаются
+	fs := flag.NewFlagSet("warehouse-service", flag.ContinueOnError)
+	fs.SetOutput(output)
+	configFile := fs.String("config", "", "path to YAML config (default "+DefaultConfigFile+", env CONFIG_FILE)")
+	var flagValues []func() error
+	for _, b := range bindings {
+		b := b
+		record := func(value string) error {
+			flagValues = append(flagValues, func() error { return setValue(b.target, value) })
+			return nil
+		}
+		if _, ok := b.target.(*bool); ok {
+			fs.BoolFunc(b.flag, b.usage+" (env "+b.env+")", record)
+		} else {
+			fs.Func(b.flag, b.usage+" (env "+b.env+")", record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	path, explicit := *configFile, true
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		path, explicit = DefaultConfigFile, false
+	}
+	if err := cfg.loadFile(path, explicit); err != nil {
+		return nil, nil, err
+	}
+
+	// Заданная, но пустая переменная сбрасывает настройку (например,
+	// RATE_LIMIT_IP= отключает квоту адреса из файла)
+	var errs []error
+	for _, b := range bindings {
+		if value, ok := os.LookupEnv(b.env); ok {
+			if err := setValue(b.target, value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", b.env, err))
+			}
+		}
+	}
+	for _, apply := range flagValues {
+		if err := apply(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	// В демо-хранилище нет пользователей: вместо отказа запускаться
+	// отключаем аутентификацию, чтобы -storage=memory работал с любым конфигом
+	if driver, ok := repository.LookupStorage(cfg.Storage.Driver); ok && driver.Demo && cfg.Features.Auth {
+		cfg.Features.Auth = false
+		cfg.warnings = append(cfg.warnings, fmt.Sprintf(
+			"features.auth is disabled: storage driver %s has no users, the API is open to everyone", cfg.Storage.Driver))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, fs.Args(), nil
+}
+
+// Warnings возвращает настройки, которые Load изменил сам (например,
+// отключенную в демо-режиме аутентификацию).
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+func (c *Config) loadFile(path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setValue записывает значение из окружения или флага; пустая строка
+// сбрасывает настройку в нулевое значение
+func setValue(target any, value string) error {
+	if value == "" {
+		reflect.ValueOf(target).Elem().SetZero()
+		return nil
+	}
+	var err error
+	switch t := target.(type) {
+	case *string:
+		*t = value
+	case *int:
+		*t, err = strconv.Atoi(value)
+	case *bool:
+		*t, err = strconv.ParseBool(value)
+	case *float64:
+		*t, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*t, err = time.ParseDuration(value)
+	default:
+		err = fmt.Errorf("unsupported config type %T", target)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	return nil
+}
+
+// Validate проверяет настройки и возвращает все найденные ошибки сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535 (APP_PORT, -port)")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+
+	if driver, ok := repository.LookupStorage(c.Storage.Driver); ok {
+		if driver.Validate != nil {
+			if err := driver.Validate(c.StorageSettings()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		// Пользователи и API-ключи есть не во всех хранилищах, общие квоты — только в PostgreSQL
+		check(driver.Users || !c.Features.Auth,
+			"features.auth requires a storage driver with users (postgres), disable it (AUTH_ENABLED=false, -auth=false)")
+		check(c.RateLimit.Store != RateLimitPostgres || c.Storage.Driver == repository.StoragePostgres,
+			"rate_limit.store postgres requires storage.driver postgres")
+	} else {
+		check(false, "storage.driver %q is invalid, use %s", c.Storage.Driver, strings.Join(repository.StorageDrivers(), ", "))
+	}
+
+	var level zapcore.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q is invalid, use debug, info, warn or error", c.Log.Level)
+	check(c.Log.Format == "console" || c.Log.Format == "json", "log.format %q is invalid, use console or json", c.Log.Format)
+
+	check(c.Pagination.DefaultLimit > 0, "pagination.default_limit must be positive")
+	check(c.Pagination.MaxLimit >= c.Pagination.DefaultLimit,
+		"pagination.max_limit must be at least pagination.default_limit (%d)", c.Pagination.DefaultLimit)
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+	check(c.Reports.RefreshInterval >= 0, "reports.refresh_interval must not be negative")
+
+	switch c.Cache.Driver {
+	case CacheNone:
+	case CacheMemory, CacheRedis:
+		check(c.Cache.TTL > 0, "cache.ttl must be positive")
+		if c.Cache.Driver == CacheMemory {
+			check(c.Cache.Size > 0, "cache.size must be positive")
+		} else if c.Cache.Redis.URL == "" {
+			check(false, "cache.redis.url is required for cache.driver redis (CACHE_REDIS_URL)")
+		} else {
+			_, err := redis.ParseURL(c.Cache.Redis.URL)
+			check(err == nil, "cache.redis.url is invalid: %v", err)
+		}
+	default:
+		check(false, "cache.driver %q is invalid, use none, memory or redis", c.Cache.Driver)
+	}
+
+	for name, value := range map[string]string{
+		"reads": c.RateLimit.Reads, "writes": c.RateLimit.Writes, "purchases": c.RateLimit.Purchases, "ip": c.RateLimit.IP,
+	} {
+		if value != "" {
+			_, err := ratelimit.ParseLimit(value)
+			check(err == nil, "rate_limit.%s %q is invalid: %v", name, value, err)
+		}
+	}
+	switch c.RateLimit.Store {
+	case RateLimitMemory, RateLimitPostgres:
+	case RateLimitRedis:
+		_, err := redis.ParseURL(c.RateLimit.RedisURL)
+		check(err == nil, "rate_limit.redis_url is required and must be valid for rate_limit.store redis (RATE_LIMIT_REDIS_URL)")
+	default:
+		check(false, "rate_limit.store %q is invalid, use memory, redis or postgres", c.RateLimit.Store)
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		check(false, "tracing.exporter %q is invalid, use none, otlp or stdout", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio > 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be in (0, 1]")
+
+	return errors.Join(errs...)
+}
+
+// NewLogger создает логгер по настройкам log.
+func (c *Config) NewLogger() (*zap.Logger, error) {
+	zc := zap.NewProductionConfig()
+	if c.Log.Format == "console" {
+		zc = zap.NewDevelopmentConfig()
+	}
+	level, err := zap.ParseAtomicLevel(c.Log.Level)
+	if err != nil {
+		return nil, err
+	}
+	zc.Level = level
+	return zc.Build()
+}
+
+// StorageSettings возвращает разделы конфигурации для драйвера хранилища.
+func (c *Config) StorageSettings() repository.StorageSettings {
+	return repository.StorageSettings{SQLite: c.Storage.SQLite, Database: c.Database}
+}
+
+// TracingConfig возвращает настройки трассировки.
+func (c *Config) TracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.Tracing.Exporter,
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		SampleRatio: c.Tracing.SampleRatio,
+		ServiceName: "warehouse-service",
+	}
+}
+
+// APIOptions возвращает настройки поведения API; читает JWKS-файл, если он задан.
+func (c *Config) APIOptions() (Options, error) {
+	opts := Options{
+		Paging:         pagination.Limits{Default: c.Pagination.DefaultLimit, Max: c.Pagination.MaxLimit},
+		RequireIfMatch: c.Features.RequireIfMatch,
+		IdempotencyTTL: c.Idempotency.TTL,
+		AuthEnabled:    c.Features.Auth,
+		MetricsEnabled: c.Features.Metrics,
+		RateLimitStore: c.RateLimit.Store,
+		TrustProxy:     c.RateLimit.TrustProxy,
+
+		ReportsRefreshInterval: c.Reports.RefreshInterval,
+		CacheTTL:               c.Cache.TTL,
+	}
+
+	// Формат квот уже проверен в Validate
+	for _, q := range []struct {
+		value string
+		limit *ratelimit.Limit
+	}{
+		{c.RateLimit.Reads, &opts.RateLimit.Reads},
+		{c.RateLimit.Writes, &opts.RateLimit.Writes},
+		{c.RateLimit.Purchases, &opts.RateLimit.Purchases},
+		{c.RateLimit.IP, &opts.RateLimit.IP},
+	} {
+		if strings.TrimSpace(q.value) != "" {
+			*q.limit, _ = ratelimit.ParseLimit(q.value)
+		}
+	}
+
+	if c.Auth.JWKSFile != "" {
+		keys, err := auth.LoadKeySet(c.Auth.JWKSFile)
+		if err != nil {
+			return Options{}, fmt.Errorf("load JWKS: %w", err)
+		}
+		opts.JWT = &auth.JWTConfig{Keys: keys, Issuer: c.Auth.JWTIssuer, Audience: c.Auth.JWTAudience}
+	}
+	return opts, nil
+}
@@ -0,0 +1,79 @@
+package config_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/config"
+	"github.com/yourusername/warehouse-service/internal/repository/memory"
+	"github.com/yourusername/warehouse-service/internal/repository/sqlite"
+)
+
+// Поставляемый config.yaml включает аутентификацию
+const shippedConfig = "../../config/config.yaml"
+
+// unsetenv убирает переменную окружения на время теста
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	if err := os.Unsetenv(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMemoryDisablesAuth(t *testing.T) {
+	unsetenv(t, "AUTH_ENABLED")
+	cfg, _, err := config.Load([]string{"-config", shippedConfig, "-storage", memory.Name}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Features.Auth {
+		t.Fatal("auth is still enabled on memory storage")
+	}
+	if len(cfg.Warnings()) != 1 || !strings.Contains(cfg.Warnings()[0], "features.auth") {
+		t.Fatalf("warnings = %q, want one about features.auth", cfg.Warnings())
+	}
+}
+
+func TestLoadSQLiteRequiresAuthDisabled(t *testing.T) {
+	unsetenv(t, "AUTH_ENABLED")
+	_, _, err := config.Load([]string{"-config", shippedConfig, "-storage", sqlite.Name}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "features.auth") {
+		t.Fatalf("err = %v, want features.auth error", err)
+	}
+
+	cfg, _, err := config.Load([]string{"-config", shippedConfig, "-storage", sqlite.Name, "-auth=false"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Warnings()) != 0 {
+		t.Fatalf("unexpected warnings %q", cfg.Warnings())
+	}
+}
+
+// Заданная, но пустая переменная окружения сбрасывает значение из файла,
+// а не игнорируется
+func TestLoadEmptyEnvClearsValue(t *testing.T) {
+	unsetenv(t, "AUTH_ENABLED")
+	unsetenv(t, "RATE_LIMIT_IP")
+	args := []string{"-config", shippedConfig, "-storage", memory.Name}
+
+	cfg, _, err := config.Load(args, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RateLimit.IP == "" {
+		t.Fatal("shipped config has no rate_limit.ip to clear")
+	}
+
+	t.Setenv("RATE_LIMIT_IP", "")
+	t.Setenv("TRACING_ENDPOINT", "")
+	if cfg, _, err = config.Load(args, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RateLimit.IP != "" || cfg.Tracing.Endpoint != "" {
+		t.Fatalf("rate_limit.ip = %q, tracing.endpoint = %q, want both cleared", cfg.RateLimit.IP, cfg.Tracing.Endpoint)
+	}
+}
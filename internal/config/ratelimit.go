@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+)
+
+// OpenRateLimitRedis создает хранилище корзин в Redis для
+// rate_limit.store redis; для остальных хранилищ возвращает nil. Клиент
+// подключается при первых запросах, а недоступный Redis не блокирует
+// запросы (см. middleware.RateLimit).
+func (c *Config) OpenRateLimitRedis() (ratelimit.Store, func() error, error) {
+	if c.RateLimit.Store != RateLimitRedis {
+		return nil, func() error { return nil }, nil
+	}
+	options, err := redis.ParseURL(c.RateLimit.RedisURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid rate_limit.redis_url: %w", err)
+	}
+	client := redis.NewClient(options)
+	return ratelimit.NewRedisStore(client, "warehouse:ratelimit:"), sync.OnceValue(client.Close), nil
+}
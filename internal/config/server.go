@@ -1,94 +1,308 @@
 package config
 
 import (
+	"context"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/app"
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/cache"
 	"github.com/yourusername/warehouse-service/internal/handlers"
+	"github.com/yourusername/warehouse-service/internal/health"
+	"github.com/yourusername/warehouse-service/internal/metrics"
 	"github.com/yourusername/warehouse-service/internal/middleware"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
 	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/repository/cached"
+	"github.com/yourusername/warehouse-service/internal/services"
 	"go.uber.org/zap"
 )
 
+const (
+	idempotencyCleanupInterval = 10 * time.Minute
+	rateLimitCleanupInterval   = time.Minute
+)
+
 // NewServer создает и возвращает настроенный HTTP сервер.
-func NewServer(port string, handler http.Handler) *http.Server {
+func NewServer(cfg ServerConfig, handler http.Handler) *http.Server {
 	return &http.Server{
-		Addr:         ":" + port,
-		Handler:      handler,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 }
 
-// SetupDependencies инициализирует репозитории, обработчики и маршруты.
-func SetupDependencies(logger *zap.Logger, dbpool *pgxpool.Pool) *mux.Router {
-	// Репозитории
-	warehouseRepo := repository.NewWarehouseRepository(dbpool)
-	productRepo := repository.NewProductRepository(dbpool)
-	inventoryRepo := repository.NewInventoryRepository(dbpool)
-	analyticsRepo := repository.NewAnalyticsRepository(dbpool, logger)
+// Options содержит настройки поведения API.
+type Options struct {
+	Paging pagination.Limits
+	// RequireIfMatch включает ответ 428 на PUT/PATCH/DELETE версионируемых записей без If-Match
+	RequireIfMatch bool
+	// IdempotencyTTL — время хранения ответов по Idempotency-Key
+	IdempotencyTTL time.Duration
+	// MetricsEnabled публикует /metrics
+	MetricsEnabled bool
+	// AuthEnabled требует API-ключ или JWT на всех маршрутах, кроме health-check
+	AuthEnabled bool
+	// JWT — параметры проверки bearer-токенов; nil отключает JWT
+	JWT *auth.JWTConfig
+	// RateLimit — квоты запросов по группам маршрутов; пустая политика отключает ограничение
+	RateLimit ratelimit.Policy
+	// RateLimitStore — "memory" (по умолчанию), "redis" или "postgres" для
+	// общих квот нескольких реплик
+	RateLimitStore string
+	// RateLimitRedis — корзины в Redis для RateLimitStore "redis"
+	RateLimitRedis ratelimit.Store
+	// TrustProxy разрешает брать IP клиента из X-Forwarded-For
+	TrustProxy bool
+	// ReportsRefreshInterval — период обновления отчетов; 0 отключает фоновое обновление
+	ReportsRefreshInterval time.Duration
+	// Cache — кэш чтений списков и цен; nil отключает кэширование
+	Cache cache.Cache
+	// CacheTTL — время жизни записей кэша
+	CacheTTL time.Duration
+}
+
+// Dependencies — собранное приложение: маршруты, пробы и фоновые задачи.
+type Dependencies struct {
+	Router *mux.Router
+	// Health нужен при остановке, чтобы перевести /readyz в 503
+	Health *health.Checker
+	// Jobs запускаются и останавливаются вместе с сервером
+	Jobs []app.Job
+}
+
+// SetupDependencies инициализирует сервисы, обработчики, маршруты и фоновые задачи.
+func SetupDependencies(logger *zap.Logger, storage repository.Storage, opts Options) Dependencies {
+	// Пробы готовности
+	checker := health.New(2 * time.Second)
+	for _, name := range slices.Sorted(maps.Keys(storage.Ready)) {
+		checker.Add(name, storage.Ready[name])
+	}
+
+	// Журнал аудита изменений и метрики; без хранилища журнала *Recorder
+	// остается nil и ничего не пишет
+	var recorder *audit.Recorder
+	if storage.Audit != nil {
+		recorder = audit.NewRecorder(storage.Audit, logger)
+	}
+	appMetrics := metrics.New(storage.Collectors...)
+
+	// Кэш чтений оборачивает репозитории и транзакции, чтобы записи
+	// сбрасывали закэшированные списки и цены. С репликой промахи сразу
+	// после сброса читаются с основной базы, чтобы не закэшировать отставшие
+	// данные
+	repos, tx := storage.Repos, storage.Tx
+	if opts.Cache != nil {
+		var replicaLag time.Duration
+		if storage.Writes != nil {
+			replicaLag = storage.Writes.Window()
+		}
+		layer := cached.New(opts.Cache, opts.CacheTTL, replicaLag, logger, appMetrics)
+		repos, tx = layer.Repositories(repos), layer.Transactor(tx)
+	}
+
+	// Сервисы: валидация, транзакции, аудит и бизнес-метрики
+	warehouseService := services.NewWarehouseService(repos.Warehouses, tx, recorder)
+	productService := services.NewProductService(repos.Products, tx, recorder)
+	inventoryService := services.NewInventoryService(repos.Inventory, tx, recorder, appMetrics)
+	analyticsService := services.NewAnalyticsService(repos.Analytics, repos.Reports, recorder)
 
 	// Обработчики
-	warehouseHandler := handlers.NewWarehouseHandler(warehouseRepo)
-	productHandler := handlers.NewProductHandler(productRepo, logger)
-	inventoryHandler := handlers.NewInventoryHandler(inventoryRepo, analyticsRepo, logger)
-	analyticsHandler := handlers.NewAnalyticsHandler(analyticsRepo, logger)
+	warehouseHandler := handlers.NewWarehouseHandler(warehouseService, logger, opts.Paging)
+	productHandler := handlers.NewProductHandler(productService, logger, opts.Paging)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService, logger, opts.Paging)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, logger, opts.Paging)
+	var adminHandler *handlers.AdminHandler
+	if storage.Users != nil && storage.APIKeys != nil {
+		adminHandler = handlers.NewAdminHandler(storage.Users, storage.APIKeys, logger, recorder)
+	}
+	var auditHandler *handlers.AuditHandler
+	if storage.Audit != nil {
+		auditHandler = handlers.NewAuditHandler(storage.Audit, logger, opts.Paging)
+	}
 
 	// Настройка маршрутов
-	router := SetupRoutes(logger, warehouseHandler, productHandler, inventoryHandler, analyticsHandler)
-	router.Use(middleware.LoggingMiddleware(logger))
+	idempotency := middleware.Idempotency(storage.Idempotency, opts.IdempotencyTTL, logger)
+	// LoggingMiddleware подключается в SetupRoutes и выполняется первым
+	router := SetupRoutes(logger, opts, checker, appMetrics, idempotency, warehouseHandler, productHandler, inventoryHandler, analyticsHandler, adminHandler, auditHandler)
+	public := []string{"/livez", "/readyz", "/api/health", "/metrics"}
 
-	return router
+	// Фоновые задачи: задачи хранилища, обновление отчетов, очистка
+	// истекших ключей идемпотентности и корзин квот
+	jobs := append(slices.Clip(storage.Jobs),
+		app.Every("idempotency-cleanup", idempotencyCleanupInterval, logger, func(ctx context.Context) error {
+			_, err := storage.Idempotency.DeleteExpired(ctx)
+			return err
+		}),
+	)
+	if opts.ReportsRefreshInterval > 0 {
+		jobs = append(jobs, reportsRefreshJob(analyticsService, opts.ReportsRefreshInterval, logger))
+	}
+
+	// Порядок middleware: квота IP-адреса, аутентификация, квоты групп.
+	// Квота адреса отсекает поток запросов до поиска ключа в базе, квоты
+	// групп считаются по субъекту, а не по IP
+	var store ratelimit.Store
+	if opts.RateLimit.Enabled() {
+		store = ratelimit.NewMemoryStore()
+		switch opts.RateLimitStore {
+		case "redis":
+			if opts.RateLimitRedis != nil {
+				store = opts.RateLimitRedis
+			} else {
+				logger.Warn("Rate limit store redis is not configured, using memory")
+			}
+		case "postgres":
+			if storage.RateLimit != nil {
+				store = storage.RateLimit
+			} else {
+				logger.Warn("Storage has no rate limit buckets, using memory")
+			}
+			if expirer, ok := store.(ratelimit.Expirer); ok {
+				jobs = append(jobs, app.Every("rate-limit-cleanup", rateLimitCleanupInterval, logger, func(ctx context.Context) error {
+					_, err := expirer.DeleteExpired(ctx)
+					return err
+				}))
+			}
+		}
+	}
+	if opts.RateLimit.IP.Enabled() {
+		router.Use(middleware.RateLimitByIP(store, opts.RateLimit.IP, opts.TrustProxy, logger, public...))
+	}
+	if opts.AuthEnabled {
+		authenticator := auth.NewAuthenticator(storage.APIKeys, storage.Users, opts.JWT)
+		router.Use(middleware.Authentication(authenticator, logger, public...))
+	}
+	if opts.RateLimit.Enabled() {
+		router.Use(middleware.RateLimit(store, opts.RateLimit, opts.TrustProxy, logger))
+	}
+	// С репликой клиент читает свои изменения с основной базы
+	if storage.Writes != nil {
+		router.Use(middleware.ReadYourWrites(storage.Writes, opts.TrustProxy))
+	}
+
+	return Dependencies{Router: router, Health: checker, Jobs: jobs}
+}
+
+// reportsRefreshJob обновляет отчеты сразу при запуске, чтобы после старта
+// они не ждали первого интервала, и затем раз в interval
+func reportsRefreshJob(service *services.AnalyticsService, interval time.Duration, logger *zap.Logger) app.Job {
+	const name = "reports-refresh"
+	every := app.Every(name, interval, logger, service.RefreshReports)
+	return app.Job{Name: name, Run: func(ctx context.Context) error {
+		if err := service.RefreshReports(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("Background job failed", zap.String("job", name), zap.Error(err))
+		}
+		return every.Run(ctx)
+	}}
 }
 
 // SetupRoutes настраивает маршруты для приложения.
 func SetupRoutes(
 	logger *zap.Logger,
+	opts Options,
+	checker *health.Checker,
+	appMetrics *metrics.Metrics,
+	idempotency mux.MiddlewareFunc,
 	warehouseHandler *handlers.WarehouseHandler,
 	productHandler *handlers.ProductHandler,
 	inventoryHandler *handlers.InventoryHandler,
 	analyticsHandler *handlers.AnalyticsHandler,
+	adminHandler *handlers.AdminHandler,
+	auditHandler *handlers.AuditHandler,
 ) *mux.Router {
 	router := mux.NewRouter()
 	router.Use(middleware.LoggingMiddleware(logger))
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Metrics(appMetrics))
+	router.Use(middleware.LimitBody(middleware.MaxBodySize))
 
-	// Health-check
-	router.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write([]byte("200")); err != nil {
-			logger.Error("Failed to write health check response", zap.Error(err))
-		}
-	}).Methods("GET")
+	// Метрики Prometheus
+	if opts.MetricsEnabled {
+		router.Handle("/metrics", appMetrics.Handler()).Methods("GET")
+	}
+
+	// Пробы живости и готовности; /api/health оставлен для существующих проверок
+	router.HandleFunc("/livez", checker.LiveHandler).Methods("GET")
+	router.HandleFunc("/readyz", checker.ReadyHandler).Methods("GET")
+	router.HandleFunc("/api/health", checker.ReadyHandler).Methods("GET")
+
+	// Проверка прав выполняется первой, до идемпотентности и If-Match
+	allow := func(perm auth.Permission, h http.Handler) http.Handler {
+		return middleware.Authorize(perm)(h)
+	}
+	// Версионируемые записи: PUT/PATCH/DELETE принимают If-Match
+	versioned := func(h http.HandlerFunc) http.Handler {
+		return middleware.RequireIfMatch(opts.RequireIfMatch)(h)
+	}
+	type fn = http.HandlerFunc
 
 	// Warehouse routes
-	router.HandleFunc("/api/warehouses", warehouseHandler.GetAllHandler).Methods("GET")
-	router.HandleFunc("/api/warehouse", warehouseHandler.CreateHandler).Methods("POST")
-	router.HandleFunc("/api/warehouse/update/{id}", warehouseHandler.UpdateHandler).Methods("PUT")
-	router.HandleFunc("/api/warehouse/delete/{id}", warehouseHandler.DeleteHandler).Methods("DELETE")
+	router.Handle("/api/warehouses", allow(auth.PermWarehouseRead, fn(warehouseHandler.GetAllHandler))).Methods("GET")
+	router.Handle("/api/warehouse", allow(auth.PermWarehouseWrite, fn(warehouseHandler.CreateHandler))).Methods("POST")
+	router.Handle("/api/warehouse/{id}", allow(auth.PermWarehouseRead, fn(warehouseHandler.GetByIDHandler))).Methods("GET")
+	router.Handle("/api/warehouse/update/{id}", allow(auth.PermWarehouseWrite, versioned(warehouseHandler.UpdateHandler))).Methods("PUT")
+	router.Handle("/api/warehouse/update/{id}", allow(auth.PermWarehouseWrite, versioned(warehouseHandler.PatchHandler))).Methods("PATCH")
+	router.Handle("/api/warehouse/delete/{id}", allow(auth.PermWarehouseWrite, versioned(warehouseHandler.DeleteHandler))).Methods("DELETE")
 
 	// Product routes
-	router.HandleFunc("/api/products", productHandler.GetAllHandler).Methods("GET")
-	router.HandleFunc("/api/product", productHandler.CreateHandler).Methods("POST")
-	router.HandleFunc("/api/product/update/{id}", productHandler.UpdateHandler).Methods("PUT")
-	router.HandleFunc("/api/product/delete/{id}", productHandler.DeleteHandler).Methods("DELETE")
-
-	// Inventory routes
-	router.HandleFunc("/api/inventory", inventoryHandler.CreateHandler).Methods("POST")
-	router.HandleFunc("/api/inventory/update/{warehouseId}/{productId}", inventoryHandler.UpdateQuantityHandler).Methods("PUT")
-	router.HandleFunc("/api/inventory/discount/{warehouseId}", inventoryHandler.SetDiscountHandler).Methods("PUT")
-	router.HandleFunc("/api/inventory/{warehouseId}", inventoryHandler.GetByWarehouseHandler).Methods("GET")
-	router.HandleFunc("/api/inventory/{warehouseId}/{productId}", inventoryHandler.GetProductHandler).Methods("GET")
-	router.HandleFunc("/api/inventory/calculate/{warehouseId}", inventoryHandler.CalculateTotalHandler).Methods("POST")
-	router.HandleFunc("/api/inventory/purchase/{warehouseId}", inventoryHandler.PurchaseHandler).Methods("POST")
-	router.HandleFunc("/api/inventory/{warehouseId}/{productId}", inventoryHandler.DeleteProductFromWarehouseHandler).Methods("GET")
-	router.HandleFunc("/api/inventory/{inventoryID}", inventoryHandler.DeleteInventoryHandler).Methods("DELETE")
+	router.Handle("/api/products", allow(auth.PermProductRead, fn(productHandler.GetAllHandler))).Methods("GET")
+	router.Handle("/api/product", allow(auth.PermProductWrite, fn(productHandler.CreateHandler))).Methods("POST")
+	router.Handle("/api/product/{id}", allow(auth.PermProductRead, fn(productHandler.GetByIDHandler))).Methods("GET")
+	router.Handle("/api/product/update/{id}", allow(auth.PermProductWrite, versioned(productHandler.UpdateHandler))).Methods("PUT")
+	router.Handle("/api/product/update/{id}", allow(auth.PermProductWrite, versioned(productHandler.PatchHandler))).Methods("PATCH")
+	router.Handle("/api/product/delete/{id}", allow(auth.PermProductWrite, versioned(productHandler.DeleteHandler))).Methods("DELETE")
+
+	// Inventory routes (изменения остатков принимают Idempotency-Key: покупка,
+	// создание позиции и изменение количества; перемещений между складами в
+	// API пока нет, их маршрут тоже нужно будет обернуть в idempotency).
+	// Склад для создания записи приходит в теле и проверяется обработчиком.
+	router.Handle("/api/inventory", middleware.AuthorizeUnscoped(auth.PermInventoryWrite)(idempotency(fn(inventoryHandler.CreateHandler)))).Methods("POST")
+	router.Handle("/api/inventory/update/{warehouseId}/{productId}", allow(auth.PermInventoryWrite, idempotency(versioned(inventoryHandler.UpdateQuantityHandler)))).Methods("PUT")
+	router.Handle("/api/inventory/discount/{warehouseId}", allow(auth.PermInventoryWrite, fn(inventoryHandler.SetDiscountHandler))).Methods("PUT")
+	router.Handle("/api/inventory/{warehouseId}", allow(auth.PermInventoryRead, fn(inventoryHandler.GetByWarehouseHandler))).Methods("GET")
+	router.Handle("/api/inventory/{warehouseId}/{productId}", allow(auth.PermInventoryRead, fn(inventoryHandler.GetProductHandler))).Methods("GET")
+	router.Handle("/api/inventory/calculate/{warehouseId}", allow(auth.PermInventoryRead, fn(inventoryHandler.CalculateTotalHandler))).Methods("POST")
+	router.Handle("/api/inventory/purchase/{warehouseId}", allow(auth.PermInventoryPurchase, idempotency(fn(inventoryHandler.PurchaseHandler)))).Methods("POST")
+	router.Handle("/api/inventory/{warehouseId}/{productId}", allow(auth.PermInventoryWrite, versioned(inventoryHandler.DeleteProductFromWarehouseHandler))).Methods("DELETE")
+	router.Handle("/api/inventory/{inventoryID}", allow(auth.PermInventoryWrite, versioned(inventoryHandler.DeleteInventoryHandler))).Methods("DELETE")
 
 	// Analytics routes
-	router.HandleFunc("/api/analytics/top", analyticsHandler.GetTopWarehousesHandler).Methods("GET")
-	router.HandleFunc("/api/analytics/{warehouseId}", analyticsHandler.GetWarehouseAnalyticsHandler).Methods("GET")
-	router.HandleFunc("/api/analytics/delete/{warehouseId}/{productId}", analyticsHandler.DeleteAnalyticsHandler).Methods("DELETE")
+	router.Handle("/api/analytics/top", allow(auth.PermAnalyticsRead, fn(analyticsHandler.GetTopWarehousesHandler))).Methods("GET")
+	router.Handle("/api/analytics/stock-value", allow(auth.PermAnalyticsRead, fn(analyticsHandler.GetStockValueHandler))).Methods("GET")
+	router.Handle("/api/analytics/refresh", allow(auth.PermAnalyticsWrite, fn(analyticsHandler.RefreshReportsHandler))).Methods("POST")
+	router.Handle("/api/analytics/{warehouseId}", allow(auth.PermAnalyticsRead, fn(analyticsHandler.GetWarehouseAnalyticsHandler))).Methods("GET")
+	router.Handle("/api/analytics/{warehouseId}/daily", allow(auth.PermAnalyticsRead, fn(analyticsHandler.GetDailyRevenueHandler))).Methods("GET")
+	router.Handle("/api/analytics/delete/{warehouseId}/{productId}", allow(auth.PermAnalyticsWrite, fn(analyticsHandler.DeleteAnalyticsHandler))).Methods("DELETE")
+
+	// Admin routes (nil, если хранилище не поддерживает пользователей)
+	if adminHandler != nil {
+		admin := func(h http.HandlerFunc) http.Handler { return allow(auth.PermAdmin, h) }
+		router.Handle("/api/admin/roles", admin(adminHandler.GetRolesHandler)).Methods("GET")
+		router.Handle("/api/admin/users", admin(adminHandler.GetUsersHandler)).Methods("GET")
+		router.Handle("/api/admin/users", admin(adminHandler.CreateUserHandler)).Methods("POST")
+		router.Handle("/api/admin/users/{id}", admin(adminHandler.GetUserHandler)).Methods("GET")
+		router.Handle("/api/admin/users/{id}", admin(adminHandler.DeleteUserHandler)).Methods("DELETE")
+		router.Handle("/api/admin/users/{id}/roles", admin(adminHandler.SetRolesHandler)).Methods("PUT")
+		router.Handle("/api/admin/users/{id}/warehouses", admin(adminHandler.SetWarehousesHandler)).Methods("PUT")
+		router.Handle("/api/admin/users/{id}/api-keys", admin(adminHandler.CreateAPIKeyHandler)).Methods("POST")
+		router.Handle("/api/admin/api-keys/{id}", admin(adminHandler.RevokeAPIKeyHandler)).Methods("DELETE")
+	}
+
+	// Audit routes (nil, если хранилище не ведет журнал)
+	if auditHandler != nil {
+		router.Handle("/api/audit", allow(auth.PermAuditRead, fn(auditHandler.FindHandler))).Methods("GET")
+	}
 
 	return router
 }
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/services"
+	"go.uber.org/zap"
+)
+
+// writeServiceError переводит ошибку сервиса в HTTP-ответ. Неизвестные ошибки
+// логируются и скрываются за сообщением fallback.
+func writeServiceError(w http.ResponseWriter, logger *zap.Logger, err error, fallback string) {
+	switch {
+	case services.IsValidation(err),
+		errors.Is(err, errInvalidPatch),
+		errors.Is(err, repository.ErrInsufficientStock),
+		errors.Is(err, repository.ErrInvalidSort),
+		errors.Is(err, pagination.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errUnsupportedPatch):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, repository.ErrWarehouseNotFound),
+		errors.Is(err, repository.ErrProductNotFound),
+		errors.Is(err, repository.ErrInventoryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrDuplicateBarcode):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case isVersionMismatch(err):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	default:
+		logger.Error(fallback, zap.Error(err))
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// errInvalidIfMatch возвращается, если If-Match не содержит версию записи.
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// setETag выставляет ETag по версии записи.
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// ifMatchVersion возвращает версию из заголовка If-Match.
+// Отсутствующий заголовок и "*" означают repository.AnyVersion.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return repository.AnyVersion, nil
+	}
+	value = strings.TrimPrefix(value, "W/")
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(value[1:len(value)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
+
+// writeIfMatchError отвечает на некорректный If-Match: такой тег не совпадет
+// ни с одной версией, поэтому это 412, а не 400.
+func writeIfMatchError(w http.ResponseWriter) {
+	http.Error(w, errInvalidIfMatch.Error(), http.StatusPreconditionFailed)
+}
+
+// writeList отвечает списком со слабым ETag по содержимому. У списка нет
+// версии, поэтому тег — хеш тела ответа. Cache-Control разрешает клиенту
+// хранить ответ, но требует перепроверки: если If-None-Match содержит тот
+// же тег, ответ — 304 без тела.
+func writeList(w http.ResponseWriter, r *http.Request, logger *zap.Logger, list any) {
+	body, err := json.Marshal(list)
+	if err != nil {
+		logger.Error("Failed to encode list response", zap.Error(err))
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n') // как у json.Encoder в остальных ответах
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if ifNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		logger.Debug("Failed to write list response", zap.Error(err))
+	}
+}
+
+// ifNoneMatch сообщает, что If-None-Match содержит тег etag или "*".
+// Теги сравниваются слабо (RFC 9110, 13.1.2): префикс W/ не учитывается.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// isVersionMismatch сообщает, что запись изменилась с момента чтения клиентом.
+func isVersionMismatch(err error) bool {
+	return errors.Is(err, repository.ErrVersionMismatch)
+}
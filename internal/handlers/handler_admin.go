@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	Users   repository.UserRepository
+	APIKeys repository.APIKeyRepository
+	Audit   *audit.Recorder
+	Logger  *zap.Logger
+}
+
+func NewAdminHandler(users repository.UserRepository, apiKeys repository.APIKeyRepository, logger *zap.Logger, recorder *audit.Recorder) *AdminHandler {
+	return &AdminHandler{Users: users, APIKeys: apiKeys, Audit: recorder, Logger: logger}
+}
+
+// log возвращает логгер запроса (с request_id и субъектом)
+func (h *AdminHandler) log(r *http.Request) *zap.Logger {
+	return logging.FromContext(r.Context(), h.Logger)
+}
+
+// 1. Список ролей и их прав
+func (h *AdminHandler) GetRolesHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, auth.RolePermissions)
+}
+
+// 2. Список пользователей
+func (h *AdminHandler) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := h.Users.GetUsers(r.Context())
+	if err != nil {
+		h.log(r).Error("Failed to fetch users", zap.Error(err))
+		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, users)
+}
+
+// 3. Создание пользователя с ролями и складами
+func (h *AdminHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Username     string      `json:"username"`
+		Roles        []string    `json:"roles"`
+		WarehouseIDs []uuid.UUID `json:"warehouse_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+	if err := validateRoles(request.Roles); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Users.CreateUser(r.Context(), request.Username)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			http.Error(w, "Username already exists", http.StatusConflict)
+			return
+		}
+		h.log(r).Error("Failed to create user", zap.Error(err))
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+	if len(request.Roles) > 0 {
+		if err := h.Users.SetRoles(r.Context(), user.ID, request.Roles); err != nil {
+			h.log(r).Error("Failed to set user roles", zap.Error(err))
+			http.Error(w, "Failed to set user roles", http.StatusInternalServerError)
+			return
+		}
+	}
+	if len(request.WarehouseIDs) > 0 {
+		if err := h.Users.SetWarehouses(r.Context(), user.ID, request.WarehouseIDs); err != nil {
+			h.log(r).Error("Failed to assign warehouses", zap.Error(err))
+			http.Error(w, "Failed to assign warehouses", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if created := h.respondUser(w, r, user.ID, http.StatusCreated); created != nil {
+		h.Audit.Record(r.Context(), audit.ActionCreate, "user", user.ID.String(), nil, created)
+	}
+}
+
+// 4. Пользователь по id
+func (h *AdminHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	h.respondUser(w, r, id, http.StatusOK)
+}
+
+// 5. Удаление пользователя
+func (h *AdminHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	before, _ := h.Users.GetUserByID(r.Context(), id)
+	err := h.Users.DeleteUser(r.Context(), id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to delete user", zap.Error(err))
+		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r.Context(), audit.ActionDelete, "user", id.String(), before, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 6. Замена ролей пользователя
+func (h *AdminHandler) SetRolesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateRoles(request.Roles); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	before, _ := h.Users.GetUserByID(r.Context(), id)
+	err := h.Users.SetRoles(r.Context(), id, request.Roles)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to set user roles", zap.Error(err))
+		http.Error(w, "Failed to set user roles", http.StatusInternalServerError)
+		return
+	}
+	if after := h.respondUser(w, r, id, http.StatusOK); after != nil {
+		h.Audit.Record(r.Context(), audit.ActionUpdate, "user", id.String(), before, after)
+	}
+}
+
+// 7. Замена складов, закрепленных за пользователем
+func (h *AdminHandler) SetWarehousesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		WarehouseIDs []uuid.UUID `json:"warehouse_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, _ := h.Users.GetUserByID(r.Context(), id)
+	err := h.Users.SetWarehouses(r.Context(), id, request.WarehouseIDs)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		http.Error(w, "Unknown warehouse ID", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to assign warehouses", zap.Error(err))
+		http.Error(w, "Failed to assign warehouses", http.StatusInternalServerError)
+		return
+	}
+	if after := h.respondUser(w, r, id, http.StatusOK); after != nil {
+		h.Audit.Record(r.Context(), audit.ActionUpdate, "user", id.String(), before, after)
+	}
+}
+
+// 8. Выпуск API-ключа для пользователя (ключ возвращается один раз)
+func (h *AdminHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Name == "" {
+		http.Error(w, "Key name is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.Users.GetUserByID(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.log(r).Error("Failed to fetch user", zap.Error(err))
+		http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+
+	plain, err := auth.GenerateAPIKey()
+	if err != nil {
+		h.log(r).Error("Failed to generate API key", zap.Error(err))
+		http.Error(w, "Failed to generate API key", http.StatusInternalServerError)
+		return
+	}
+	key, err := h.APIKeys.Create(r.Context(), request.Name, id.String(), auth.HashAPIKey(plain))
+	if err != nil {
+		h.log(r).Error("Failed to store API key", zap.Error(err))
+		http.Error(w, "Failed to store API key", http.StatusInternalServerError)
+		return
+	}
+	// В журнал попадают только метаданные ключа, сам ключ не сохраняется
+	h.Audit.Record(r.Context(), audit.ActionCreate, "api_key", key.ID.String(), nil, key)
+
+	h.writeJSON(w, http.StatusCreated, struct {
+		ID  uuid.UUID `json:"id"`
+		Key string    `json:"key"`
+	}{ID: key.ID, Key: plain})
+}
+
+// 9. Отзыв API-ключа
+func (h *AdminHandler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+	err = h.APIKeys.Revoke(r.Context(), id)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to revoke API key", zap.Error(err))
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r.Context(), audit.ActionDelete, "api_key", id.String(), nil, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// respondUser отправляет актуальное состояние пользователя и возвращает его
+// (nil, если ответом стала ошибка)
+func (h *AdminHandler) respondUser(w http.ResponseWriter, r *http.Request, id uuid.UUID, status int) *models.User {
+	user, err := h.Users.GetUserByID(r.Context(), id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		h.log(r).Error("Failed to fetch user", zap.Error(err))
+		http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		return nil
+	}
+	h.writeJSON(w, status, user)
+	return user
+}
+
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.Logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if !auth.ValidRole(role) {
+			return errors.New("unknown role: " + role)
+		}
+	}
+	return nil
+}
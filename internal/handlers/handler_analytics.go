@@ -4,93 +4,173 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/services"
 	"go.uber.org/zap"
 )
 
 type AnalyticsHandler struct {
-	Repo   repository.AnalyticsRepository
-	Logger *zap.Logger
+	Service *services.AnalyticsService
+	Paging  pagination.Limits
+	Logger  *zap.Logger
 }
 
-func NewAnalyticsHandler(repo repository.AnalyticsRepository, logger *zap.Logger) *AnalyticsHandler {
-	return &AnalyticsHandler{Repo: repo, Logger: logger}
+func NewAnalyticsHandler(service *services.AnalyticsService, logger *zap.Logger, paging pagination.Limits) *AnalyticsHandler {
+	return &AnalyticsHandler{Service: service, Paging: paging, Logger: logger}
+}
+
+// log возвращает логгер запроса (с request_id и субъектом)
+func (h *AnalyticsHandler) log(r *http.Request) *zap.Logger {
+	return logging.FromContext(r.Context(), h.Logger)
 }
 
 // 1. Получение аналитики по складу
 func (h *AnalyticsHandler) GetWarehouseAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	h.Logger.Info("Fetching warehouse analytics", zap.String("warehouseId", vars["warehouseId"]))
+	h.log(r).Info("Fetching warehouse analytics", zap.String("warehouseId", vars["warehouseId"]))
 	warehouseID, err := uuid.Parse(vars["warehouseId"])
 	if err != nil {
-		h.Logger.Error("Invalid UUID format", zap.String("warehouseId", vars["warehouseId"]))
+		h.log(r).Error("Invalid UUID format", zap.String("warehouseId", vars["warehouseId"]))
 		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
 		return
 	}
 
-	analytics, err := h.Repo.GetWarehouseAnalytics(r.Context(), warehouseID)
+	page, err := pagination.FromRequest(r, h.Paging)
 	if err != nil {
-		h.Logger.Error("Failed to fetch analytics", zap.Error(err), zap.String("warehouseId", warehouseID.String()))
-		http.Error(w, "Failed to fetch analytics", http.StatusInternalServerError)
+		h.log(r).Error("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(analytics); err != nil {
-		h.Logger.Error("Failed to encode analytics response", zap.Error(err))
-		http.Error(w, "Failed to encode analytics response", http.StatusInternalServerError)
+	analytics, err := h.Service.WarehouseSales(r.Context(), warehouseID, page)
+	if err != nil {
+		writeServiceError(w, h.log(r).With(zap.String("warehouseId", warehouseID.String())), err, "Failed to fetch analytics")
 		return
 	}
+
+	writeList(w, r, h.log(r), analytics)
 }
 
 // 2. Получение топ-10 складов по выручке
 func (h *AnalyticsHandler) GetTopWarehousesHandler(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit == 0 {
-		limit = 10 // По умолчанию возвращаем топ-10
+	limit := 0 // По умолчанию сервис возвращает топ-10
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
 	}
 
-	h.Logger.Info("Fetching top warehouses by revenue", zap.Int("limit", limit))
+	h.log(r).Info("Fetching top warehouses by revenue", zap.Int("limit", limit))
 
-	warehouses, err := h.Repo.GetTopWarehouses(r.Context(), limit)
+	warehouses, err := h.Service.TopWarehouses(r.Context(), limit)
 	if err != nil {
-		h.Logger.Error("Failed to fetch top warehouses", zap.Error(err))
-		http.Error(w, "Failed to fetch top warehouses", http.StatusInternalServerError)
+		writeServiceError(w, h.log(r), err, "Failed to fetch top warehouses")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(warehouses); err != nil {
-		h.Logger.Error("Failed to encode top warehouses response", zap.Error(err))
+		h.log(r).Error("Failed to encode top warehouses response", zap.Error(err))
 		http.Error(w, "Failed to encode top warehouses response", http.StatusInternalServerError)
 		return
 	}
 }
 
+// 3. Выручка склада по дням (?from=YYYY-MM-DD&to=YYYY-MM-DD)
+func (h *AnalyticsHandler) GetDailyRevenueHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	warehouseID, err := uuid.Parse(vars["warehouseId"])
+	if err != nil {
+		h.log(r).Error("Invalid UUID format", zap.String("warehouseId", vars["warehouseId"]))
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	var period [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		if period[i], err = time.Parse(time.DateOnly, raw); err != nil {
+			http.Error(w, "Invalid "+name+" date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.log(r).Info("Fetching daily revenue", zap.String("warehouseId", warehouseID.String()))
+
+	revenue, err := h.Service.DailyRevenue(r.Context(), warehouseID, period[0], period[1])
+	if err != nil {
+		writeServiceError(w, h.log(r).With(zap.String("warehouseId", warehouseID.String())), err, "Failed to fetch daily revenue")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(revenue); err != nil {
+		h.log(r).Error("Failed to encode daily revenue response", zap.Error(err))
+		http.Error(w, "Failed to encode daily revenue response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// 4. Стоимость остатков по складам
+func (h *AnalyticsHandler) GetStockValueHandler(w http.ResponseWriter, r *http.Request) {
+	h.log(r).Info("Fetching stock value")
+
+	value, err := h.Service.StockValue(r.Context())
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to fetch stock value")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		h.log(r).Error("Failed to encode stock value response", zap.Error(err))
+		http.Error(w, "Failed to encode stock value response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// 5. Обновление отчетов вне расписания
+func (h *AnalyticsHandler) RefreshReportsHandler(w http.ResponseWriter, r *http.Request) {
+	h.log(r).Info("Refreshing reports")
+
+	if err := h.Service.RefreshReports(r.Context()); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to refresh reports")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *AnalyticsHandler) DeleteAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	h.Logger.Info("Deleting analytics data", zap.String("warehouseId", vars["warehouseId"]), zap.String("productId", vars["productId"]))
+	h.log(r).Info("Deleting analytics data", zap.String("warehouseId", vars["warehouseId"]), zap.String("productId", vars["productId"]))
 
 	warehouseID, err := uuid.Parse(vars["warehouseId"])
 	if err != nil {
-		h.Logger.Error("Invalid UUID format for warehouseId", zap.String("warehouseId", vars["warehouseId"]))
+		h.log(r).Error("Invalid UUID format for warehouseId", zap.String("warehouseId", vars["warehouseId"]))
 		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
 		return
 	}
 
 	productID, err := uuid.Parse(vars["productId"])
 	if err != nil {
-		h.Logger.Error("Invalid UUID format for productId", zap.String("productId", vars["productId"]))
+		h.log(r).Error("Invalid UUID format for productId", zap.String("productId", vars["productId"]))
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.Repo.DeleteAnalytics(r.Context(), warehouseID, productID); err != nil {
-		h.Logger.Error("Failed to delete analytics data", zap.Error(err))
-		http.Error(w, "Failed to delete analytics data", http.StatusInternalServerError)
+	if err := h.Service.DeleteSales(r.Context(), warehouseID, productID); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to delete analytics data")
 		return
 	}
 
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+type AuditHandler struct {
+	Repo   repository.AuditRepository
+	Paging pagination.Limits
+	Logger *zap.Logger
+}
+
+func NewAuditHandler(repo repository.AuditRepository, logger *zap.Logger, paging pagination.Limits) *AuditHandler {
+	return &AuditHandler{Repo: repo, Paging: paging, Logger: logger}
+}
+
+// log возвращает логгер запроса (с request_id и субъектом)
+func (h *AuditHandler) log(r *http.Request) *zap.Logger {
+	return logging.FromContext(r.Context(), h.Logger)
+}
+
+// 1. Поиск по журналу аудита: ?entity=&entity_id=&actor=&from=&to= (время в RFC 3339)
+func (h *AuditHandler) FindHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.FromRequest(r, h.Paging)
+	if err != nil {
+		h.log(r).Error("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.AuditFilter{
+		EntityType: query.Get("entity"),
+		EntityID:   query.Get("entity_id"),
+		Actor:      query.Get("actor"),
+	}
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.Repo.Find(r.Context(), filter, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to fetch audit log", zap.Error(err))
+		http.Error(w, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		h.log(r).Error("Failed to encode audit response", zap.Error(err))
+		http.Error(w, "Failed to encode audit response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -2,31 +2,41 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
 	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
 	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/services"
 	"go.uber.org/zap"
 )
 
 type InventoryHandler struct {
-	Repo          repository.InventoryRepository
-	AnalyticsRepo repository.AnalyticsRepository
-	Logger        *zap.Logger
+	Service *services.InventoryService
+	Paging  pagination.Limits
+	Logger  *zap.Logger
 }
 
-func NewInventoryHandler(repo repository.InventoryRepository, analyticsRepo repository.AnalyticsRepository, logger *zap.Logger) *InventoryHandler {
+func NewInventoryHandler(service *services.InventoryService, logger *zap.Logger, paging pagination.Limits) *InventoryHandler {
 	return &InventoryHandler{
-		Repo:          repo,
-		AnalyticsRepo: analyticsRepo,
-		Logger:        logger,
+		Service: service,
+		Paging:  paging,
+		Logger:  logger,
 	}
 }
 
+// log возвращает логгер запроса (с request_id и субъектом)
+func (h *InventoryHandler) log(r *http.Request) *zap.Logger {
+	return logging.FromContext(r.Context(), h.Logger)
+}
+
 // 1. Создание связи товара и склада (указание цены)
 func (h *InventoryHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	var inventory models.Inventory
@@ -35,32 +45,33 @@ func (h *InventoryHandler) CreateHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.Logger.Debug("Received inventory data", zap.Any("inventory", inventory))
+	h.log(r).Debug("Received inventory data", zap.Any("inventory", inventory))
 
-	inventory.ID = uuid.New()
-
-	err := h.Repo.Create(r.Context(), inventory)
-	if err != nil {
-		h.Logger.Error("Failed to create inventory record", zap.Error(err))
-		http.Error(w, "Failed to create inventory record", http.StatusInternalServerError)
+	// Склад передается в теле, поэтому право на него проверяется здесь
+	if !auth.Allowed(r.Context(), auth.PermInventoryWrite, &inventory.WarehouseID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "created"}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	if _, err := h.Service.Create(r.Context(), inventory); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to create inventory record")
 		return
 	}
+
+	w.WriteHeader(http.StatusCreated)
+	h.writeStatus(w, r, "created")
 }
 
 // 2. Обновление количества товара (поступление на склад)
 func (h *InventoryHandler) UpdateQuantityHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	productID, err := uuid.Parse(vars["productId"])
-	warehouseID, err2 := uuid.Parse(vars["warehouseId"])
-	if err != nil || err2 != nil {
-		http.Error(w, "Invalid UUID", http.StatusBadRequest)
+	productID, warehouseID, ok := h.positionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeIfMatchError(w)
 		return
 	}
 
@@ -68,111 +79,151 @@ func (h *InventoryHandler) UpdateQuantityHandler(w http.ResponseWriter, r *http.
 		Quantity int `json:"quantity"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.Logger.Error("Failed to decode quantity update request", zap.Error(err))
+		h.log(r).Error("Failed to decode quantity update request", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.Repo.UpdateQuantity(r.Context(), productID, warehouseID, request.Quantity)
-	if err != nil {
-		h.Logger.Error("Failed to update quantity", zap.Error(err))
-		http.Error(w, "Failed to update quantity", http.StatusInternalServerError)
+	if _, err := h.Service.AddQuantity(r.Context(), productID, warehouseID, request.Quantity, expectedVersion); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to update quantity")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "updated"}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
-	}
+	h.writeStatus(w, r, "updated")
 }
 
 // 3. Установка скидки
 func (h *InventoryHandler) SetDiscountHandler(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		ProductIDs []uuid.UUID `json:"product_ids"`
-		Discount   float64     `json:"discount"`
-	}
-	vars := mux.Vars(r)
-	warehouseID, err := uuid.Parse(vars["warehouseId"])
-	if err != nil {
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+	warehouseID, ok := h.warehouseID(w, r)
+	if !ok {
 		return
 	}
 
+	var request services.DiscountChange
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.Logger.Error("Failed to decode discount request", zap.Error(err))
+		h.log(r).Error("Failed to decode discount request", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.Repo.SetDiscount(r.Context(), request.ProductIDs, warehouseID, request.Discount)
-	if err != nil {
-		h.Logger.Error("Failed to set discount", zap.Error(err))
-		http.Error(w, "Failed to set discount", http.StatusInternalServerError)
+	if err := h.Service.SetDiscount(r.Context(), warehouseID, request); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to set discount")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "discount applied"}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
-	}
+	h.writeStatus(w, r, "discount applied")
 }
 
-// 4. Получение списка товаров на складе (с пагинацией)
+// 4. Получение списка товаров на складе (фильтры, сортировка, пагинация)
 func (h *InventoryHandler) GetByWarehouseHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	h.Logger.Info("Received warehouse ID", zap.String("warehouseId", vars["warehouseId"]))
+	h.log(r).Info("Received warehouse ID", zap.String("warehouseId", mux.Vars(r)["warehouseId"]))
+	warehouseID, ok := h.warehouseID(w, r)
+	if !ok {
+		return
+	}
 
-	warehouseID, err := uuid.Parse(vars["warehouseId"])
+	page, err := pagination.FromRequest(r, h.Paging)
 	if err != nil {
-		h.Logger.Error("Invalid warehouse UUID", zap.Error(err))
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		h.log(r).Error("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-
-	inventory, err := h.Repo.GetByWarehouse(r.Context(), warehouseID, limit, offset)
+	filter, err := parseInventoryFilter(r)
 	if err != nil {
-		h.Logger.Error("Failed to get inventory by warehouse", zap.Error(err))
-		http.Error(w, "Failed to get inventory", http.StatusInternalServerError)
+		h.log(r).Error("Invalid inventory filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(inventory); err != nil {
-		h.Logger.Error("Failed to encode inventory response", zap.Error(err))
-		http.Error(w, "Failed to encode inventory response", http.StatusInternalServerError)
+	inventory, err := h.Service.ListByWarehouse(r.Context(), warehouseID, filter, page)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to get inventory")
 		return
 	}
+
+	writeList(w, r, h.log(r), inventory)
+}
+
+// parseInventoryFilter читает параметры sort, order, in_stock, discounted,
+// min_price, max_price и search из строки запроса
+func parseInventoryFilter(r *http.Request) (repository.InventoryFilter, error) {
+	q := r.URL.Query()
+	filter := repository.InventoryFilter{
+		Sort:   q.Get("sort"),
+		Search: q.Get("search"),
+	}
+	if !filter.ValidSort() {
+		return filter, repository.ErrInvalidSort
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errors.New("invalid order, expected asc or desc")
+	}
+
+	parseBool := func(name string) (*bool, error) {
+		raw := q.Get(name)
+		if raw == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("invalid " + name)
+		}
+		return &v, nil
+	}
+	parsePrice := func(name string) (*decimal.Decimal, error) {
+		raw := q.Get(name)
+		if raw == "" {
+			return nil, nil
+		}
+		v, err := decimal.NewFromString(raw)
+		if err != nil || v.IsNegative() {
+			return nil, errors.New("invalid " + name)
+		}
+		return &v, nil
+	}
+
+	var err error
+	if filter.InStock, err = parseBool("in_stock"); err != nil {
+		return filter, err
+	}
+	if filter.Discounted, err = parseBool("discounted"); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice, err = parsePrice("min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parsePrice("max_price"); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.GreaterThan(*filter.MaxPrice) {
+		return filter, errors.New("min_price must not exceed max_price")
+	}
+	return filter, nil
 }
 
 // 5. Получение информации о товаре на складе
 func (h *InventoryHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	productID, err := uuid.Parse(vars["productId"])
-	warehouseID, err2 := uuid.Parse(vars["warehouseId"])
-	if err != nil || err2 != nil {
-		h.Logger.Error("Invalid UUID in GetProductHandler", zap.Error(err), zap.Error(err2))
-		http.Error(w, "Invalid UUID", http.StatusBadRequest)
+	productID, warehouseID, ok := h.positionIDs(w, r)
+	if !ok {
 		return
 	}
 
-	inventory, err := h.Repo.GetProductInWarehouse(r.Context(), productID, warehouseID)
+	inventory, err := h.Service.Get(r.Context(), productID, warehouseID)
 	if err != nil {
-		h.Logger.Error("Product not found in warehouse", zap.Error(err))
-		http.Error(w, "Product not found", http.StatusNotFound)
+		writeServiceError(w, h.log(r), err, "Failed to fetch product")
 		return
 	}
 
+	setETag(w, inventory.Version)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(inventory); err != nil {
-		h.Logger.Error("Failed to encode product response", zap.Error(err))
+		h.log(r).Error("Failed to encode product response", zap.Error(err))
 		http.Error(w, "Failed to encode product response", http.StatusInternalServerError)
 		return
 	}
@@ -183,24 +234,20 @@ func (h *InventoryHandler) CalculateTotalHandler(w http.ResponseWriter, r *http.
 	var request struct {
 		Items map[uuid.UUID]int `json:"items"`
 	}
-	vars := mux.Vars(r)
-	warehouseID, err := uuid.Parse(vars["warehouseId"])
-	if err != nil {
-		h.Logger.Error("Invalid warehouse UUID", zap.Error(err))
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+	warehouseID, ok := h.warehouseID(w, r)
+	if !ok {
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.Logger.Error("Failed to decode calculate total request", zap.Error(err))
+		h.log(r).Error("Failed to decode calculate total request", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	total, err := h.Repo.CalculateTotal(r.Context(), warehouseID, request.Items)
+	total, err := h.Service.CalculateTotal(r.Context(), warehouseID, request.Items)
 	if err != nil {
-		h.Logger.Error("Failed to calculate total", zap.Error(err))
-		http.Error(w, "Failed to calculate total", http.StatusInternalServerError)
+		writeServiceError(w, h.log(r), err, "Failed to calculate total")
 		return
 	}
 
@@ -208,7 +255,7 @@ func (h *InventoryHandler) CalculateTotalHandler(w http.ResponseWriter, r *http.
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]float64{"total": totalFloat}); err != nil {
-		h.Logger.Error("Failed to encode total response", zap.Error(err))
+		h.log(r).Error("Failed to encode total response", zap.Error(err))
 		http.Error(w, "Failed to encode total response", http.StatusInternalServerError)
 		return
 	}
@@ -219,121 +266,102 @@ func (h *InventoryHandler) PurchaseHandler(w http.ResponseWriter, r *http.Reques
 	var request struct {
 		Items map[uuid.UUID]int `json:"items"`
 	}
-	vars := mux.Vars(r)
-	warehouseID, err := uuid.Parse(vars["warehouseId"])
-	if err != nil {
-		h.Logger.Error("Invalid warehouse UUID", zap.Error(err))
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+	warehouseID, ok := h.warehouseID(w, r)
+	if !ok {
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.Logger.Error("Failed to decode purchase request", zap.Error(err))
+		h.log(r).Error("Failed to decode purchase request", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.Repo.Purchase(r.Context(), warehouseID, request.Items)
-	if err != nil {
-		h.Logger.Error("Failed to purchase items", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	for productID, quantity := range request.Items {
-		price, err := h.Repo.GetProductPrice(r.Context(), warehouseID, productID)
-		if err != nil {
-			h.Logger.Error("Failed to get product price", zap.Error(err))
-			continue
-		}
-		discount, err := h.Repo.GetProductDiscount(r.Context(), warehouseID, productID)
-		if err != nil {
-			if err.Error() == "no rows in result set" {
-				h.Logger.Warn("No discount found for product, defaulting to 0",
-					zap.String("warehouseID", warehouseID.String()),
-					zap.String("productID", productID.String()))
-				discount = 0 // Если скидка не найдена, устанавливаем 0
-			} else {
-				h.Logger.Error("Failed to get product discount", zap.Error(err))
-				continue
-			}
-		}
-
-		// Применяем скидку
-		finalPrice := price * (1 - discount/100)
-		totalPrice := decimal.NewFromFloat(float64(quantity)).Mul(decimal.NewFromFloat(finalPrice))
-
-		h.Logger.Info("Recording sale in analytics",
-			zap.String("warehouseID", warehouseID.String()),
-			zap.String("productID", productID.String()),
-			zap.Int("quantity", quantity),
-			zap.Float64("totalPrice", totalPrice.InexactFloat64()))
-
-		err = h.AnalyticsRepo.RecordSale(r.Context(), warehouseID, productID, quantity, totalPrice)
-		if err != nil {
-			h.Logger.Error("Failed to record sale in analytics",
-				zap.String("warehouseID", warehouseID.String()),
-				zap.String("productID", productID.String()),
-				zap.Int("quantity", quantity),
-				zap.Float64("totalPrice", totalPrice.InexactFloat64()),
-				zap.Error(err)) // Логируем ошибку
-			continue
-		}
+	result, err := h.Service.Purchase(r.Context(), warehouseID, request.Items)
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		h.log(r).Warn("Purchase rejected", zap.Error(err))
 	}
-
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "purchase successful"}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to purchase items")
 		return
 	}
+	h.log(r).Info("Purchase completed",
+		zap.String("warehouseID", warehouseID.String()),
+		zap.Int("units", result.Units),
+		zap.String("total", result.Total.String()))
 
+	h.writeStatus(w, r, "purchase successful")
 }
 
 func (h *InventoryHandler) DeleteProductFromWarehouseHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	warehouseID, err := uuid.Parse(vars["warehouseId"])
-	productID, err2 := uuid.Parse(vars["productId"])
-	if err != nil || err2 != nil {
-		http.Error(w, "Invalid UUID", http.StatusBadRequest)
+	productID, warehouseID, ok := h.positionIDs(w, r)
+	if !ok {
 		return
 	}
-
-	err = h.Repo.DeleteProductFromWarehouse(r.Context(), warehouseID, productID)
+	expectedVersion, err := ifMatchVersion(r)
 	if err != nil {
-		h.Logger.Error("Failed to delete product from warehouse", zap.Error(err))
-		http.Error(w, "Failed to delete product from warehouse", http.StatusInternalServerError)
+		writeIfMatchError(w)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "deleted"}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	if err := h.Service.RemoveFromWarehouse(r.Context(), warehouseID, productID, expectedVersion); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to delete product from warehouse")
 		return
 	}
+
+	h.writeStatus(w, r, "deleted")
 }
 
 func (h *InventoryHandler) DeleteInventoryHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	h.Logger.Info("Received delete request", zap.Any("vars", vars))
+	h.log(r).Info("Received delete request", zap.Any("vars", vars))
 
 	inventoryID, err := uuid.Parse(vars["inventoryID"])
 	if err != nil {
 		http.Error(w, "Invalid UUID", http.StatusBadRequest)
 		return
 	}
-
-	err = h.Repo.DeleteInventory(r.Context(), inventoryID)
+	expectedVersion, err := ifMatchVersion(r)
 	if err != nil {
-		h.Logger.Error("Failed to delete inventory record", zap.Error(err))
-		http.Error(w, "Failed to delete inventory record", http.StatusInternalServerError)
+		writeIfMatchError(w)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "deleted"}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
+	if err := h.Service.Delete(r.Context(), inventoryID, expectedVersion); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to delete inventory record")
+		return
+	}
+
+	h.writeStatus(w, r, "deleted")
+}
+
+// warehouseID извлекает ID склада из пути
+func (h *InventoryHandler) warehouseID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	warehouseID, err := uuid.Parse(mux.Vars(r)["warehouseId"])
+	if err != nil {
+		h.log(r).Error("Invalid warehouse UUID", zap.Error(err))
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return uuid.UUID{}, false
+	}
+	return warehouseID, true
+}
+
+// positionIDs извлекает ID товара и склада из пути
+func (h *InventoryHandler) positionIDs(w http.ResponseWriter, r *http.Request) (productID, warehouseID uuid.UUID, ok bool) {
+	vars := mux.Vars(r)
+	productID, err := uuid.Parse(vars["productId"])
+	warehouseID, err2 := uuid.Parse(vars["warehouseId"])
+	if err != nil || err2 != nil {
+		http.Error(w, "Invalid UUID", http.StatusBadRequest)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	return productID, warehouseID, true
+}
+
+// writeStatus отвечает {"status": status}
+func (h *InventoryHandler) writeStatus(w http.ResponseWriter, r *http.Request, status string) {
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": status}); err != nil {
+		h.log(r).Error("Failed to encode response", zap.Error(err))
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
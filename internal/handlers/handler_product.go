@@ -8,109 +8,175 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/yourusername/warehouse-service/internal/logging"
 	"github.com/yourusername/warehouse-service/internal/models"
-	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/services"
 	"go.uber.org/zap"
 )
 
 type ProductHandler struct {
-	Repo   repository.ProductRepository
-	Logger *zap.Logger
+	Service *services.ProductService
+	Paging  pagination.Limits
+	Logger  *zap.Logger
 }
 
-func NewProductHandler(repo repository.ProductRepository, logger *zap.Logger) *ProductHandler {
-	return &ProductHandler{Repo: repo, Logger: logger}
+func NewProductHandler(service *services.ProductService, logger *zap.Logger, paging pagination.Limits) *ProductHandler {
+	return &ProductHandler{Service: service, Paging: paging, Logger: logger}
+}
+
+// log возвращает логгер запроса (с request_id и субъектом)
+func (h *ProductHandler) log(r *http.Request) *zap.Logger {
+	return logging.FromContext(r.Context(), h.Logger)
 }
 
 func (h *ProductHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	h.Logger.Info("Received POST request to /api/product")
+	h.log(r).Info("Received POST request to /api/product")
 	var product models.Product
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		h.Logger.Error("Failed to decode request body", zap.Error(err))
+		h.log(r).Error("Failed to decode request body", zap.Error(err))
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	if product.Name == "" || product.Barcode == "" {
-		h.Logger.Error("Missing required fields")
-		http.Error(w, "Name and Barcode are required", http.StatusBadRequest)
+	created, err := h.Service.Create(r.Context(), product)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to create product")
 		return
 	}
 
-	product.ID = uuid.New().String()
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "created", "id": created.ID}); err != nil {
+		h.log(r).Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
 
-	err := h.Repo.Create(r.Context(), product)
-	if err != nil {
-		h.Logger.Error("Failed to create product", zap.Error(err))
-		http.Error(w, "Failed to create product", http.StatusInternalServerError)
+// GetByIDHandler возвращает товар с его версией в заголовке ETag
+func (h *ProductHandler) GetByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productID(w, r)
+	if !ok {
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "created", "id": product.ID}); err != nil {
-		h.Logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	product, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to fetch product")
 		return
 	}
+
+	h.writeProduct(w, r, product)
 }
 
 func (h *ProductHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
-	//vars := mux.Vars(r)
-	products, err := h.Repo.GetAll(r.Context())
+	page, err := pagination.FromRequest(r, h.Paging)
 	if err != nil {
-		h.Logger.Error("Invalid warehouse ID", zap.Error(err))
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		h.log(r).Error("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(products); err != nil {
-		h.Logger.Error("Failed to encode products response", zap.Error(err))
-		http.Error(w, "Failed to encode products response", http.StatusInternalServerError)
+	products, err := h.Service.List(r.Context(), page)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to fetch products")
 		return
 	}
+
+	writeList(w, r, h.log(r), products)
 }
 
+// UpdateHandler полностью заменяет товар (PUT): отсутствующие поля сбрасываются
 func (h *ProductHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	if id == "" {
-		h.Logger.Error("Missing product ID")
-		http.Error(w, "Missing product ID", http.StatusBadRequest)
+	id, ok := h.productID(w, r)
+	if !ok {
+		return
+	}
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeIfMatchError(w)
 		return
 	}
 
 	var product models.Product
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		h.Logger.Error("Failed to decode request body", zap.Error(err))
+		h.log(r).Error("Failed to decode request body", zap.Error(err))
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
 	product.ID = id // Присваиваем ID из пути
+	updated, err := h.Service.Update(r.Context(), product, expectedVersion)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to update product")
+		return
+	}
+	h.writeProduct(w, r, updated)
+}
 
-	if err := h.Repo.Update(r.Context(), product); err != nil {
-		h.Logger.Error("Failed to update product", zap.Error(err))
-		http.Error(w, "Failed to update product", http.StatusInternalServerError)
+// PatchHandler частично обновляет товар (JSON Merge Patch или JSON Patch)
+func (h *ProductHandler) PatchHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productID(w, r)
+	if !ok {
+		return
+	}
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeIfMatchError(w)
+		return
+	}
+	patch, err := readPatch(r)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to read patch")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	updated, err := h.Service.Patch(r.Context(), id, expectedVersion, func(current models.Product) (models.Product, error) {
+		return applyPatch(patch, current)
+	})
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to update product")
+		return
+	}
+	h.writeProduct(w, r, updated)
 }
 
-func (h *ProductHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	if id == "" {
-		http.Error(w, "Missing product ID", http.StatusBadRequest)
+// writeProduct отвечает актуальным состоянием товара с ETag
+func (h *ProductHandler) writeProduct(w http.ResponseWriter, r *http.Request, product *models.Product) {
+	setETag(w, product.Version)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(product); err != nil {
+		h.log(r).Error("Failed to encode product response", zap.Error(err))
+		http.Error(w, "Failed to encode product response", http.StatusInternalServerError)
 		return
 	}
-	h.Logger.Info("Deleting product", zap.String("id", id))
+}
 
-	err := h.Repo.Delete(r.Context(), id)
+// productID извлекает и проверяет ID товара из пути
+func (h *ProductHandler) productID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		h.log(r).Error("Invalid product ID", zap.String("id", id))
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func (h *ProductHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productID(w, r)
+	if !ok {
+		return
+	}
+	h.log(r).Info("Deleting product", zap.String("id", id))
+	expectedVersion, err := ifMatchVersion(r)
 	if err != nil {
-		h.Logger.Error("Failed to delete product", zap.Error(err))
-		http.Error(w, "Failed to delete product", http.StatusInternalServerError)
+		writeIfMatchError(w)
+		return
+	}
+
+	if err := h.Service.Delete(r.Context(), id, expectedVersion); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to delete product")
 		return
 	}
 
@@ -2,50 +2,49 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/yourusername/warehouse-service/internal/logging"
 	"github.com/yourusername/warehouse-service/internal/models"
-	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/services"
 	"go.uber.org/zap"
 )
 
 type WarehouseHandler struct {
-	Repo   repository.WarehouseRepository
-	logger *zap.Logger
+	Service *services.WarehouseService
+	Paging  pagination.Limits
+	logger  *zap.Logger
 }
 
-func NewWarehouseHandler(repo repository.WarehouseRepository) *WarehouseHandler {
-	logger, _ := zap.NewProduction() // Initialize a new logger
+func NewWarehouseHandler(service *services.WarehouseService, logger *zap.Logger, paging pagination.Limits) *WarehouseHandler {
 	return &WarehouseHandler{
-		Repo:   repo,
-		logger: logger,
+		Service: service,
+		Paging:  paging,
+		logger:  logger,
 	}
 }
 
+// log возвращает логгер запроса (с request_id и субъектом)
+func (h *WarehouseHandler) log(r *http.Request) *zap.Logger {
+	return logging.FromContext(r.Context(), h.logger)
+}
+
 // CreateHandler обрабатывает запросы на создание склада
 func (h *WarehouseHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	var request models.Warehouse
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
+		h.log(r).Error("Failed to decode request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	warehouseID, err := h.Repo.CreateWarehouse(r.Context(), request.Name, request.Address)
-	if err != nil {
-		h.logger.Error("Failed to create warehouse", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	createdWarehouse, err := h.Repo.GetWarehouseByID(r.Context(), warehouseID)
+	createdWarehouse, err := h.Service.Create(r.Context(), request)
 	if err != nil {
-		h.logger.Error("Failed to fetch created warehouse", zap.Error(err))
-		http.Error(w, "Failed to fetch created warehouse", http.StatusInternalServerError)
+		writeServiceError(w, h.log(r), err, "Failed to create warehouse")
 		return
 	}
 
@@ -63,65 +62,128 @@ func (h *WarehouseHandler) CreateHandler(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("Failed to encode response", zap.Error(err))
+		h.log(r).Error("Failed to encode response", zap.Error(err))
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
 }
 
-// GetAllHandler обрабатывает запросы на получение всех складов
+// GetByIDHandler возвращает склад с его версией в заголовке ETag
+func (h *WarehouseHandler) GetByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.warehouseID(w, r)
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to fetch warehouse")
+		return
+	}
+
+	h.writeWarehouse(w, r, warehouse)
+}
+
+// GetAllHandler обрабатывает запросы на получение списка складов (с пагинацией и ответом 304 по If-None-Match)
 func (h *WarehouseHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
-	warehouses, err := h.Repo.GetAllWarehouses(r.Context())
+	page, err := pagination.FromRequest(r, h.Paging)
 	if err != nil {
-		h.logger.Error("Failed to fetch warehouses", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log(r).Error("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(warehouses); err != nil {
-		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	warehouses, err := h.Service.List(r.Context(), page)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to fetch warehouses")
 		return
 	}
+
+	writeList(w, r, h.log(r), warehouses)
 }
 
-// UpdateHandler обрабатывает запросы на обновление склада
+// UpdateHandler полностью заменяет склад (PUT): название, адрес и описание.
+// Тело прежнего формата {"location": "..."} меняет только адрес и получает
+// заголовок Deprecation.
 func (h *WarehouseHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := uuid.Parse(vars["id"])
+	id, ok := h.warehouseID(w, r)
+	if !ok {
+		return
+	}
+	expectedVersion, err := ifMatchVersion(r)
 	if err != nil {
-		h.logger.Error("Invalid warehouse ID", zap.Error(err))
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		writeIfMatchError(w)
 		return
 	}
 
 	var request struct {
-		Location string `json:"location"`
+		models.Warehouse
+		// Location — тело PUT прежнего формата, менявшего только адрес
+		Location *string `json:"location"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
+		h.log(r).Error("Failed to decode request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.Repo.UpdateWarehouse(r.Context(), id, request.Location)
+	var updated *models.Warehouse
+	switch {
+	case request.Location != nil && request.Name == "" && request.Address == "":
+		// {"location": ...} по-прежнему меняет только адрес; остальные поля
+		// сохраняются, как до перехода PUT на полную замену
+		w.Header().Set("Deprecation", "true")
+		updated, err = h.Service.Patch(r.Context(), id, expectedVersion, func(current models.Warehouse) (models.Warehouse, error) {
+			current.Address = *request.Location
+			return current, nil
+		})
+	default:
+		if request.Address == "" && request.Location != nil {
+			request.Address = *request.Location
+		}
+		request.ID = id
+		updated, err = h.Service.Update(r.Context(), request.Warehouse, expectedVersion)
+	}
 	if err != nil {
-		h.logger.Error("Failed to update warehouse", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, h.log(r), err, "Failed to update warehouse")
 		return
 	}
+	h.writeWarehouse(w, r, updated)
+}
 
-	updatedWarehouse, err := h.Repo.GetWarehouseByID(r.Context(), id)
+// PatchHandler частично обновляет склад (JSON Merge Patch или JSON Patch)
+func (h *WarehouseHandler) PatchHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.warehouseID(w, r)
+	if !ok {
+		return
+	}
+	expectedVersion, err := ifMatchVersion(r)
 	if err != nil {
-		h.logger.Error("Failed to fetch updated warehouse", zap.Error(err))
-		http.Error(w, "Failed to fetch updated warehouse", http.StatusInternalServerError)
+		writeIfMatchError(w)
+		return
+	}
+	patch, err := readPatch(r)
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to read patch")
 		return
 	}
 
+	updated, err := h.Service.Patch(r.Context(), id, expectedVersion, func(current models.Warehouse) (models.Warehouse, error) {
+		return applyPatch(patch, current)
+	})
+	if err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to update warehouse")
+		return
+	}
+	h.writeWarehouse(w, r, updated)
+}
+
+// writeWarehouse отвечает актуальным состоянием склада с ETag
+func (h *WarehouseHandler) writeWarehouse(w http.ResponseWriter, r *http.Request, warehouse *models.Warehouse) {
+	setETag(w, warehouse.Version)
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(updatedWarehouse); err != nil {
-		h.logger.Error("Failed to encode response", zap.Error(err))
+	if err := json.NewEncoder(w).Encode(warehouse); err != nil {
+		h.log(r).Error("Failed to encode response", zap.Error(err))
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -129,20 +191,33 @@ func (h *WarehouseHandler) UpdateHandler(w http.ResponseWriter, r *http.Request)
 
 // DeleteHandler обрабатывает запросы на удаление склада
 func (h *WarehouseHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	log.Println("Received ID:", vars["id"])
-	id, err := uuid.Parse(vars["id"])
+	h.log(r).Info("Deleting warehouse", zap.String("id", mux.Vars(r)["id"]))
+	id, ok := h.warehouseID(w, r)
+	if !ok {
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
 	if err != nil {
-		h.logger.Error("Invalid warehouse ID", zap.Error(err))
-		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		writeIfMatchError(w)
 		return
 	}
 
-	if err := h.Repo.DeleteWarehouse(r.Context(), id); err != nil {
-		h.logger.Error("Failed to delete warehouse", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Service.Delete(r.Context(), id, expectedVersion); err != nil {
+		writeServiceError(w, h.log(r), err, "Failed to delete warehouse")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// warehouseID извлекает и проверяет ID склада из пути
+func (h *WarehouseHandler) warehouseID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.log(r).Error("Invalid warehouse ID", zap.Error(err))
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
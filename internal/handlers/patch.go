@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	// errUnsupportedPatch возвращается, если Content-Type запроса не является форматом патча.
+	errUnsupportedPatch = errors.New("unsupported patch media type, expected " +
+		mergePatchContentType + " or " + jsonPatchContentType)
+	// errInvalidPatch — патч не применяется к документу или дает некорректный результат.
+	errInvalidPatch = errors.New("Invalid patch")
+)
+
+// patchDocument — тело PATCH-запроса. Поддерживаются JSON Merge Patch
+// (RFC 7396) и JSON Patch (RFC 6902); application/json трактуется как merge
+// patch. В merge patch отсутствующее поле не меняется, а null удаляет значение.
+type patchDocument struct {
+	mediaType string
+	body      []byte
+}
+
+// readPatch читает тело запроса один раз, чтобы патч можно было применить
+// повторно, если транзакция сервиса будет перезапущена.
+func readPatch(r *http.Request) (patchDocument, error) {
+	mediaType := mergePatchContentType
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return patchDocument{}, errUnsupportedPatch
+		}
+		mediaType = parsed
+	}
+	switch mediaType {
+	case mergePatchContentType, "application/json", jsonPatchContentType:
+	default:
+		return patchDocument{}, errUnsupportedPatch
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return patchDocument{}, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	return patchDocument{mediaType: mediaType, body: body}, nil
+}
+
+// apply применяет патч к JSON-документу original.
+func (p patchDocument) apply(original []byte) ([]byte, error) {
+	var patched []byte
+	var err error
+	if p.mediaType == jsonPatchContentType {
+		var patch jsonpatch.Patch
+		if patch, err = jsonpatch.DecodePatch(p.body); err == nil {
+			patched, err = patch.Apply(original)
+		}
+	} else {
+		patched, err = jsonpatch.MergePatch(original, p.body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	return patched, nil
+}
+
+// applyPatch применяет патч к значению current через его JSON-представление.
+func applyPatch[T any](p patchDocument, current T) (T, error) {
+	var result T
+	original, err := json.Marshal(current)
+	if err != nil {
+		return result, err
+	}
+	patched, err := p.apply(original)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return result, fmt.Errorf("%w result: %v", errInvalidPatch, err)
+	}
+	return result, nil
+}
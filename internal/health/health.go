@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check проверяет одну зависимость; nil означает, что она доступна.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Статусы в ответах проб
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// DependencyStatus — результат проверки одной зависимости.
+type DependencyStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report — ответ /readyz.
+type Report struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+// Checker обслуживает пробы живости и готовности. Во время остановки
+// сервиса готовность переключается в 503, чтобы балансировщик успел
+// перестать отправлять запросы до закрытия сервера.
+type Checker struct {
+	checks   []namedCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку зависимости для /readyz.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// SetDraining переводит готовность в состояние остановки.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Ready выполняет все проверки параллельно с общим таймаутом.
+func (c *Checker) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]DependencyStatus, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			status := DependencyStatus{Status: StatusOK}
+			if err := nc.check(ctx); err != nil {
+				status = DependencyStatus{Status: StatusUnavailable, Error: err.Error()}
+			}
+			mu.Lock()
+			report.Checks[nc.name] = status
+			if status.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+			mu.Unlock()
+		}(nc)
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+// LiveHandler отвечает 200, пока процесс способен обрабатывать запросы.
+// Зависимости не проверяются: их недоступность не лечится перезапуском.
+func (c *Checker) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// ReadyHandler отвечает 200, если все зависимости доступны, иначе 503
+// с состоянием каждой зависимости.
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Ready(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type (
+	ctxKey       struct{}
+	requestIDKey struct{}
+)
+
+// WithLogger кладет в контекст логгер запроса (с request_id и субъектом),
+// чтобы обработчики и репозитории писали логи с теми же полями.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// WithRequestID кладет в контекст ID запроса. Его читают логи, трассы и
+// журнал аудита, поэтому он хранится здесь, а не в пакете middleware.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID возвращает ID запроса или пустую строку вне HTTP.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext возвращает логгер запроса или fallback, если его нет
+// (фоновые задачи, вызовы вне HTTP).
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	if fallback == nil {
+		return zap.NewNop()
+	}
+	return fallback
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shopspring/decimal"
+)
+
+const namespace = "warehouse"
+
+// Причины неудачных покупок
+const (
+	PurchaseFailedInsufficientStock = "insufficient_stock"
+	PurchaseFailedError             = "error"
+)
+
+// Metrics — метрики сервиса в собственном реестре. Нулевой *Metrics
+// ничего не записывает, поэтому обработчики можно создавать без метрик.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	purchases       *prometheus.CounterVec
+	unitsSold       *prometheus.CounterVec
+	revenue         *prometheus.CounterVec
+	purchasesFailed *prometheus.CounterVec
+
+	cacheRequests *prometheus.CounterVec
+}
+
+// New регистрирует метрики HTTP, бизнес-метрики, метрики процесса и Go
+// и метрики хранилища (NewPoolCollector, ReplicaCollectors).
+func New(storage ...prometheus.Collector) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		purchases: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "purchases_total",
+			Help:      "Successful purchases per warehouse.",
+		}, []string{"warehouse_id"}),
+		unitsSold: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "units_sold_total",
+			Help:      "Units sold per warehouse.",
+		}, []string{"warehouse_id"}),
+		revenue: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "revenue_total",
+			Help:      "Revenue after discounts per warehouse.",
+		}, []string{"warehouse_id"}),
+		purchasesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "purchases_failed_total",
+			Help:      "Failed purchases per warehouse and reason.",
+		}, []string{"warehouse_id", "reason"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Read cache lookups by key group and result (hit or miss).",
+		}, []string{"group", "result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.requestDuration,
+		m.purchases, m.unitsSold, m.revenue, m.purchasesFailed,
+		m.cacheRequests,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m.registry.MustRegister(storage...)
+	return m
+}
+
+// ReplicaStatus — состояние чтения с реплики (repository.ReplicaRouter).
+type ReplicaStatus interface {
+	Lag() time.Duration
+	UsingReplica() bool
+}
+
+// ReplicaCollectors публикуют отставание реплики и то, идут ли на нее чтения.
+func ReplicaCollectors(status ReplicaStatus) []prometheus.Collector {
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_replica_lag_seconds",
+			Help:      "Replica replay lag at the last check.",
+		}, func() float64 { return status.Lag().Seconds() }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_replica_in_use",
+			Help:      "1 if read-only queries go to the replica, 0 if they fall back to the primary.",
+		}, func() float64 {
+			if status.UsingReplica() {
+				return 1
+			}
+			return 0
+		}),
+	}
+}
+
+// Handler отдает метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry нужен для регистрации дополнительных метрик.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// ObserveRequest учитывает завершенный HTTP-запрос. route — шаблон маршрута,
+// а не путь, чтобы число серий не зависело от идентификаторов в URL.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// Purchase учитывает успешную покупку.
+func (m *Metrics) Purchase(warehouseID uuid.UUID, units int, revenue decimal.Decimal) {
+	if m == nil {
+		return
+	}
+	id := warehouseID.String()
+	m.purchases.WithLabelValues(id).Inc()
+	m.unitsSold.WithLabelValues(id).Add(float64(units))
+	m.revenue.WithLabelValues(id).Add(revenue.InexactFloat64())
+}
+
+// PurchaseFailed учитывает неудачную покупку.
+func (m *Metrics) PurchaseFailed(warehouseID uuid.UUID, reason string) {
+	if m == nil {
+		return
+	}
+	m.purchasesFailed.WithLabelValues(warehouseID.String(), reason).Inc()
+}
+
+// CacheRequest учитывает обращение к кэшу чтений (repository/cached).
+func (m *Metrics) CacheRequest(group string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(group, result).Inc()
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Роли пулов соединений (метка role)
+const (
+	PoolPrimary = "primary"
+	PoolReplica = "replica"
+)
+
+// poolCollector снимает статистику pgxpool в момент сбора метрик.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceled        *prometheus.Desc
+}
+
+// NewPoolCollector снимает статистику пула pool с меткой role (PoolPrimary,
+// PoolReplica).
+func NewPoolCollector(role string, pool *pgxpool.Pool) prometheus.Collector {
+	labels := prometheus.Labels{"role": role}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, labels)
+	}
+	return &poolCollector{
+		pool:            pool,
+		acquired:        desc("acquired_connections", "Connections currently in use."),
+		idle:            desc("idle_connections", "Idle connections in the pool."),
+		total:           desc("total_connections", "Open connections in the pool."),
+		max:             desc("max_connections", "Maximum pool size."),
+		acquires:        desc("acquires_total", "Successful connection acquisitions."),
+		acquireDuration: desc("acquire_wait_seconds_total", "Total time spent waiting for a connection."),
+		emptyAcquires:   desc("empty_acquires_total", "Acquisitions that had to wait because the pool was empty."),
+		canceled:        desc("canceled_acquires_total", "Acquisitions canceled by the context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceled
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"go.uber.org/zap"
+)
+
+// Authentication требует API-ключ или JWT для всех маршрутов, кроме publicPaths,
+// и кладет аутентифицированного субъекта в контекст запроса.
+func Authentication(authenticator *auth.Authenticator, logger *zap.Logger, publicPaths ...string) func(http.Handler) http.Handler {
+	public := make(map[string]bool, len(publicPaths))
+	for _, p := range publicPaths {
+		public[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if public[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := logging.FromContext(r.Context(), logger)
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				if errors.Is(err, auth.ErrNoCredentials) || errors.Is(err, auth.ErrInvalidCredentials) {
+					log.Warn("Unauthenticated request", zap.String("url", r.URL.Path), zap.Error(err))
+					w.Header().Set("WWW-Authenticate", `Bearer, ApiKey`)
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				log.Error("Failed to authenticate request", zap.Error(err))
+				http.Error(w, "Failed to authenticate request", http.StatusInternalServerError)
+				return
+			}
+
+			// Дальнейшие логи запроса, включая итоговую запись, содержат субъекта
+			r = annotateLogger(w, r,
+				zap.String("subject", principal.Subject),
+				zap.String("auth_method", principal.Method),
+			)
+			logging.FromContext(r.Context(), logger).Info("Authenticated request")
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
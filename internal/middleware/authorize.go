@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/yourusername/warehouse-service/internal/auth"
+)
+
+// Authorize пропускает запрос, если у субъекта есть право perm. Для маршрутов
+// с переменной {warehouseId} право проверяется для этого склада, для остальных
+// требуется право на все склады. Без аутентификации проверка не выполняется.
+func Authorize(perm auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.PrincipalFromContext(r.Context())
+			if principal == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var warehouseID *uuid.UUID
+			if raw, ok := mux.Vars(r)["warehouseId"]; ok {
+				id, err := uuid.Parse(raw)
+				if err != nil {
+					http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+					return
+				}
+				warehouseID = &id
+			}
+
+			if !principal.Can(perm, warehouseID) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthorizeUnscoped пропускает запрос, если у субъекта есть право perm хотя бы
+// на один склад. Используется, когда склад передается в теле запроса:
+// обработчик обязан сам проверить его через auth.Allowed.
+func AuthorizeUnscoped(perm auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.PrincipalFromContext(r.Context())
+			if principal != nil && !principal.HasPermission(perm) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
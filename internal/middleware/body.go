@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// MaxBodySize — наибольший размер тела запроса: самые большие тела (покупка
+// и массовая скидка) занимают килобайты
+const MaxBodySize = 1 << 20
+
+// LimitBody ограничивает тело запроса limit байтами. Запрос с большим
+// Content-Length сразу получает 413; тело без длины обрезается, и чтение
+// сверх лимита возвращает *http.MaxBytesError.
+func LimitBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotencyReplayHeader = "Idempotency-Replayed"
+	maxIdempotencyKeyLength = 255
+	// idempotencyStoreTimeout ограничивает сохранение ответа и снятие
+	// резерва, которые выполняются и после отмены запроса клиентом
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// Idempotency сохраняет ответ на первый запрос с заголовком Idempotency-Key
+// и воспроизводит его на повторы с тем же ключом и телом. Повтор с другим
+// телом получает 422, повтор во время выполнения первого запроса — 409.
+// Запросы без заголовка обрабатываются как обычно. Тело читается в память
+// целиком, поэтому больше MaxBodySize оно не принимается (413). Истекшие
+// ключи удаляет фоновая задача (DeleteExpired), а не middleware.
+//
+// Ответ сохраняется (или резерв снимается) и тогда, когда клиент уже
+// отключился по таймауту: именно он затем повторит запрос, и оставшийся
+// резерв отвечал бы ему 409 до истечения ключа.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Ключи разных клиентов не должны пересекаться
+			if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+				key = principal.Subject + ":" + key
+			}
+
+			hash := sha256.Sum256(body)
+			record := repository.IdempotencyRecord{
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				RequestHash: hex.EncodeToString(hash[:]),
+			}
+
+			existing, err := repo.Reserve(r.Context(), record, ttl)
+			if err != nil {
+				logging.FromContext(r.Context(), logger).Error("Failed to reserve idempotency key", zap.Error(err))
+				http.Error(w, "Failed to process idempotency key", http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				replayIdempotent(w, record, existing)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyStoreTimeout)
+			defer cancel()
+
+			// Резерв снимается при ответе 5xx и при панике обработчика, чтобы
+			// клиент мог повторить запрос; паника после этого идет дальше
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := repo.Release(ctx, key, record.Method, record.Path); err != nil {
+					logging.FromContext(ctx, logger).Error("Failed to release idempotency key", zap.Error(err))
+				}
+			}()
+
+			capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(capture, r)
+			if capture.status >= http.StatusInternalServerError {
+				return
+			}
+
+			record.Completed = true
+			record.StatusCode = capture.status
+			record.ContentType = capture.Header().Get("Content-Type")
+			record.Body = capture.body.Bytes()
+			if err := repo.Complete(ctx, record); err != nil {
+				logging.FromContext(ctx, logger).Error("Failed to store idempotent response", zap.Error(err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replayIdempotent(w http.ResponseWriter, request repository.IdempotencyRecord, existing *repository.IdempotencyRecord) {
+	switch {
+	case existing.RequestHash != request.RequestHash:
+		http.Error(w, "Idempotency-Key was already used with a different request payload", http.StatusUnprocessableEntity)
+	case !existing.Completed:
+		http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set(idempotencyReplayHeader, "true")
+		w.WriteHeader(existing.StatusCode)
+		_, _ = w.Write(existing.Body)
+	}
+}
+
+// captureWriter дублирует статус и тело ответа для сохранения.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status = status
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(b []byte) (int, error) {
+	c.wroteHeader = true
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/middleware"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+// ctxRepository, как pgx, не выполняет запросы с отмененным контекстом
+type ctxRepository struct {
+	*memory.IdempotencyRepository
+}
+
+func (r ctxRepository) Complete(ctx context.Context, record repository.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.IdempotencyRepository.Complete(ctx, record)
+}
+
+func (r ctxRepository) Release(ctx context.Context, key, method, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.IdempotencyRepository.Release(ctx, key, method, path)
+}
+
+// purchase выполняет запрос с ключом; cancel отменяет контекст запроса до
+// завершения обработчика, как при отключении клиента по таймауту
+func purchase(handler http.Handler, cancel bool) *httptest.ResponseRecorder {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	req := httptest.NewRequest(http.MethodPost, "/api/inventory/purchase/w", strings.NewReader(`{"items": {}}`)).WithContext(ctx)
+	req.Header.Set("Idempotency-Key", "retry-1")
+	if cancel {
+		stop()
+	}
+	rec := httptest.NewRecorder()
+	func() {
+		defer func() { _ = recover() }()
+		handler.ServeHTTP(rec, req)
+	}()
+	return rec
+}
+
+func TestIdempotencyAfterClientTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		// first — обработчик первого запроса, отмененного клиентом
+		first http.HandlerFunc
+		// want — ответ на повтор с тем же ключом
+		want       int
+		wantReplay bool
+	}{
+		{
+			name: "handler completed",
+			first: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+			want:       http.StatusOK,
+			wantReplay: true,
+		},
+		{
+			name: "handler failed on cancelled context",
+			first: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, r.Context().Err().Error(), http.StatusInternalServerError)
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "handler panicked",
+			first: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			want: http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := ctxRepository{memory.NewIdempotencyRepository()}
+			idempotency := middleware.Idempotency(repo, time.Hour, zap.NewNop())
+
+			purchase(idempotency(tt.first), true)
+
+			retry := purchase(idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			})), false)
+			if retry.Code != tt.want {
+				t.Fatalf("retry status = %d, want %d: %s", retry.Code, tt.want, retry.Body)
+			}
+			if replayed := retry.Header().Get("Idempotency-Replayed") == "true"; replayed != tt.wantReplay {
+				t.Fatalf("replayed = %v, want %v", replayed, tt.wantReplay)
+			}
+		})
+	}
+}
+
+// Тело сверх MaxBodySize не буферизуется и не сохраняется: 413 без вызова
+// обработчика, даже если длина заранее не известна
+func TestIdempotencyBodyTooLarge(t *testing.T) {
+	repo := memory.NewIdempotencyRepository()
+	handler := middleware.Idempotency(repo, time.Hour, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called for an oversized body")
+	}))
+
+	body := strings.NewReader(strings.Repeat("x", middleware.MaxBodySize+1))
+	req := httptest.NewRequest(http.MethodPost, "/api/inventory/purchase/w", io.NopCloser(body))
+	req.ContentLength = -1
+	req.Header.Set("Idempotency-Key", "large-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+
+	// Ключ не зарезервирован: повтор с допустимым телом обрабатывается
+	retry := purchase(middleware.Idempotency(repo, time.Hour, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})), false)
+	if retry.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want 201", retry.Code)
+	}
+}
@@ -3,45 +3,119 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/logging"
 )
 
-type key string
-
-const requestIDKey key = "x-request-id"
+const (
+	requestIDHeader = "X-Request-Id"
+	// Более длинный X-Request-Id клиента заменяется сгенерированным
+	maxRequestIDLength = 128
+)
 
+// LoggingMiddleware присваивает запросу ID (или берет его из X-Request-Id),
+// возвращает его в ответе, кладет в контекст логгер с request_id
+// и по завершении пишет статус, размер ответа и длительность.
 func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			requestID := r.Header.Get("X-Request-Id")
-			if requestID == "" {
+			start := time.Now()
+			requestID := r.Header.Get(requestIDHeader)
+			if requestID == "" || len(requestID) > maxRequestIDLength {
 				requestID = uuid.New().String()
 			}
+			w.Header().Set(requestIDHeader, requestID)
 
-			ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+			reqLogger := logger.With(zap.String("request_id", requestID))
+			ctx := logging.WithRequestID(r.Context(), requestID)
+			ctx = logging.WithLogger(ctx, reqLogger)
 			r = r.WithContext(ctx)
 
-			logger.Info("Incoming request",
+			reqLogger.Info("Incoming request",
 				zap.String("method", r.Method),
 				zap.String("url", r.URL.Path),
-				zap.String("request_id", requestID),
 			)
 
-			// Обернем writer, если хочешь логировать ещё и ответ
-			// Можно добавить log response status в будущем
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
 
-			next.ServeHTTP(w, r)
+			// Логгер мог быть дополнен последующими middleware (например, субъектом)
+			completion := logging.FromContext(sw.ctx(r), reqLogger)
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("url", r.URL.Path),
+				zap.Int("status", sw.Status()),
+				zap.Int64("bytes", sw.bytes),
+				zap.Duration("duration", time.Since(start)),
+			}
+			switch {
+			case sw.Status() >= 500:
+				completion.Error("Request completed", fields...)
+			case sw.Status() >= 400:
+				completion.Warn("Request completed", fields...)
+			default:
+				completion.Info("Request completed", fields...)
+			}
 		})
 	}
 }
 
-// Получение ID из контекста
-func GetRequestID(ctx context.Context) string {
-	if val, ok := ctx.Value(requestIDKey).(string); ok {
-		return val
+// statusWriter запоминает код ответа и число записанных байт.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	// reqCtx — контекст, с которым запрос дошел до обработчика
+	reqCtx context.Context
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Status возвращает код ответа; 200, если обработчик ничего не записал.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap дает http.ResponseController доступ к исходному writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) ctx(r *http.Request) context.Context {
+	if w.reqCtx != nil {
+		return w.reqCtx
+	}
+	return r.Context()
+}
+
+// annotateLogger добавляет поля к логгеру запроса в контексте и сообщает
+// их LoggingMiddleware для итоговой записи. Между ними может быть несколько
+// обернутых writer'ов (метрики, трассировка), поэтому обходится вся цепочка.
+func annotateLogger(w http.ResponseWriter, r *http.Request, fields ...zap.Field) *http.Request {
+	ctx := logging.WithLogger(r.Context(), logging.FromContext(r.Context(), zap.NewNop()).With(fields...))
+	for sw, ok := w.(*statusWriter); ok; sw, ok = sw.ResponseWriter.(*statusWriter) {
+		sw.reqCtx = ctx
 	}
-	return ""
+	return r.WithContext(ctx)
 }
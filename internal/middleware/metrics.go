@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/warehouse-service/internal/metrics"
+)
+
+// Metrics учитывает число и длительность запросов по шаблону маршрута.
+// Подключается через router.Use, поэтому видит только найденные маршруты.
+func Metrics(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			route := "unknown"
+			if current := mux.CurrentRoute(r); current != nil {
+				if tmpl, err := current.GetPathTemplate(); err == nil {
+					route = tmpl
+				}
+			}
+			m.ObserveRequest(r.Method, route, sw.Status(), time.Since(start))
+		})
+	}
+}
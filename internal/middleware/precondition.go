@@ -0,0 +1,23 @@
+package middleware
+
+import "net/http"
+
+// RequireIfMatch отклоняет изменяющие запросы без заголовка If-Match
+// со статусом 428, если required = true.
+func RequireIfMatch(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !required {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				if r.Header.Get("If-Match") == "" {
+					http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// RateLimit ограничивает частоту запросов клиента по корзинам токенов.
+// Клиент определяется по субъекту аутентификации (API-ключ, JWT), а без
+// нее — по IP-адресу; X-Forwarded-For учитывается только при trustProxy.
+// У каждой группы маршрутов (чтение, изменения, покупки) своя корзина.
+// Ответы содержат заголовки RateLimit-*, отказ — 429 с Retry-After. Если
+// запрос прошел и квоту адреса (RateLimitByIP), заголовки описывают ту из
+// двух квот, в которой осталось меньше запросов.
+// Если хранилище недоступно, запрос пропускается.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy, trustProxy bool, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			group := routeGroup(r)
+			if take(w, r, store, group, clientKey(r, trustProxy), policy.For(group), logger) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimitByIP ограничивает все запросы с одного IP-адреса квотой
+// GroupIP. Подключается до аутентификации: поток запросов с неверными
+// ключами отклоняется, не доходя до поиска ключа в базе. Пути skip
+// (пробы, /metrics) не ограничиваются.
+func RateLimitByIP(store ratelimit.Store, limit ratelimit.Limit, trustProxy bool, logger *zap.Logger, skip ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(skip, r.URL.Path) || take(w, r, store, ratelimit.GroupIP, clientIP(r, trustProxy), limit, logger) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// take расходует токен корзины группы и сообщает, можно ли выполнять
+// запрос; при отказе ответ 429 уже записан
+func take(w http.ResponseWriter, r *http.Request, store ratelimit.Store, group, client string, limit ratelimit.Limit, logger *zap.Logger) bool {
+	if !limit.Enabled() {
+		return true
+	}
+	result, err := store.Take(r.Context(), group+":"+client, limit)
+	if err != nil {
+		logging.FromContext(r.Context(), logger).Error("Rate limiter unavailable", zap.Error(err), zap.String("client", client))
+		return true
+	}
+
+	// Заголовки предыдущей квоты заменяются, только если эта строже
+	h := w.Header()
+	if remaining, err := strconv.Atoi(h.Get("RateLimit-Remaining")); err != nil || result.Remaining <= remaining || !result.Allowed {
+		h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Per))
+	}
+
+	if !result.Allowed {
+		logging.FromContext(r.Context(), logger).Warn("Rate limit exceeded",
+			zap.String("client", client),
+			zap.String("group", group))
+		h.Set("Retry-After", ceilSeconds(result.RetryAfter))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// routeGroup относит запрос к группе квот. Расчет стоимости выполняется
+// POST-запросом, но ничего не меняет, поэтому считается чтением.
+func routeGroup(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/api/inventory/purchase/"):
+		return ratelimit.GroupPurchases
+	case strings.HasPrefix(path, "/api/inventory/calculate/"):
+		return ratelimit.GroupReads
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return ratelimit.GroupReads
+	}
+	return ratelimit.GroupWrites
+}
+
+func clientKey(r *http.Request, trustProxy bool) string {
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		return "sub:" + principal.Subject
+	}
+	return clientIP(r, trustProxy)
+}
+
+// clientIP возвращает адрес клиента. За доверенным прокси это последняя
+// запись X-Forwarded-For: ее дописывает сам прокси, а предыдущие присылает
+// клиент, и по ним он получал бы новую корзину на каждый запрос.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if values := r.Header.Values("X-Forwarded-For"); trustProxy && len(values) > 0 {
+		last := values[len(values)-1]
+		if i := strings.LastIndexByte(last, ','); i >= 0 {
+			last = last[i+1:]
+		}
+		if ip := strings.TrimSpace(last); ip != "" {
+			return "ip:" + ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,117 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/auth"
+	"github.com/yourusername/warehouse-service/internal/middleware"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// countingKeys считает обращения к хранилищу ключей; ключей в нем нет
+type countingKeys struct{ lookups int }
+
+func (k *countingKeys) GetActiveByHash(context.Context, string) (*models.APIKey, error) {
+	k.lookups++
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+func TestRateLimitByIPRunsBeforeAuthentication(t *testing.T) {
+	keys := &countingKeys{}
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 3, Per: time.Minute}
+	logger := zap.NewNop()
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler = middleware.Authentication(auth.NewAuthenticator(keys, nil, nil), logger, "/livez")(handler)
+	handler = middleware.RateLimitByIP(store, limit, false, logger, "/livez")(handler)
+
+	statuses := map[int]int{}
+	for range 10 {
+		req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+		req.Header.Set(auth.APIKeyHeader, "wh_forged")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		statuses[rec.Code]++
+	}
+	if statuses[http.StatusUnauthorized] != 3 || statuses[http.StatusTooManyRequests] != 7 {
+		t.Fatalf("statuses = %v, want 3×401 and 7×429", statuses)
+	}
+	if keys.lookups != 3 {
+		t.Fatalf("%d key lookups, want 3: rejected requests must not reach the key store", keys.lookups)
+	}
+
+	// Пробы не ограничиваются
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/livez status = %d, want 200", rec.Code)
+	}
+}
+
+// За доверенным прокси клиент не меняет корзину, подставляя свои записи в
+// начало X-Forwarded-For: учитывается адрес, дописанный прокси
+func TestRateLimitByIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+	handler := middleware.RateLimitByIP(store, limit, true, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	statuses := map[int]int{}
+	for i := range 5 {
+		req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("10.0.0.%d, 203.0.113.7", i))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		statuses[rec.Code]++
+	}
+	if statuses[http.StatusOK] != 2 || statuses[http.StatusTooManyRequests] != 3 {
+		t.Fatalf("statuses = %v, want 2×200 and 3×429", statuses)
+	}
+
+	// Другой адрес от прокси — другая корзина
+	req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 203.0.113.8")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status for another client = %d, want 200", rec.Code)
+	}
+}
+
+// Квоты адреса и группы: заголовки RateLimit-* описывают более строгую
+func TestRateLimitHeadersDescribeTighterQuota(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	logger := zap.NewNop()
+	ip := ratelimit.Limit{Requests: 100, Per: time.Minute}
+	tests := []struct {
+		name  string
+		reads ratelimit.Limit
+		want  string
+	}{
+		{"group is tighter", ratelimit.Limit{Requests: 5, Per: time.Minute}, "5"},
+		{"address is tighter", ratelimit.Limit{Requests: 1000, Per: time.Minute}, "100"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			handler = middleware.RateLimit(store, ratelimit.Policy{Reads: tt.reads}, false, logger)(handler)
+			handler = middleware.RateLimitByIP(store, ip, false, logger)(handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+			req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", i+1)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if got := rec.Header().Get("RateLimit-Limit"); got != tt.want {
+				t.Fatalf("RateLimit-Limit = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
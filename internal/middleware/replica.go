@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// ReadYourWrites связывает запрос с клиентом (как в RateLimit: субъект или
+// IP) и отмечает его записи в tracker, чтобы следующие чтения клиента не
+// попали на реплику, которая еще не получила изменения. Запись отмечается
+// до обработки и после нее: окно начинается до фиксации и отсчитывается
+// заново, когда ответ уже готов. Подключается после аутентификации.
+func ReadYourWrites(tracker repository.WriteTracker, trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientKey(r, trustProxy)
+			r = r.WithContext(repository.WithClient(r.Context(), client))
+			if routeGroup(r) == ratelimit.GroupReads {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tracker.MarkWrite(client)
+			defer tracker.MarkWrite(client)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/middleware"
+)
+
+type recordingTracker struct{ writes []string }
+
+func (t *recordingTracker) MarkWrite(client string) { t.writes = append(t.writes, client) }
+func (t *recordingTracker) Window() time.Duration   { return time.Second }
+
+func TestReadYourWrites(t *testing.T) {
+	tracker := &recordingTracker{}
+	handler := middleware.ReadYourWrites(tracker, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		method, path string
+		writes       int
+	}{
+		{http.MethodGet, "/api/warehouses", 0},
+		{http.MethodPost, "/api/inventory/calculate/1", 0},
+		{http.MethodPost, "/api/warehouse", 2},
+		{http.MethodPost, "/api/inventory/purchase/1", 2},
+	} {
+		tracker.writes = nil
+		r := httptest.NewRequest(tc.method, tc.path, strings.NewReader("{}"))
+		r.RemoteAddr = "192.0.2.1:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if len(tracker.writes) != tc.writes {
+			t.Errorf("%s %s: marked %d writes, want %d", tc.method, tc.path, len(tracker.writes), tc.writes)
+		}
+		for _, client := range tracker.writes {
+			if client != "ip:192.0.2.1" {
+				t.Errorf("%s %s: marked client %q", tc.method, tc.path, client)
+			}
+		}
+	}
+}
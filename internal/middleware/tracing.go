@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Tracing открывает спан на каждый запрос, продолжая трассу из заголовка
+// traceparent, и добавляет trace_id и span_id в логгер запроса. Ответ
+// получает заголовок traceparent, чтобы клиент мог найти трассу.
+func Tracing() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			propagator := otel.GetTextMapPropagator()
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if tmpl, err := current.GetPathTemplate(); err == nil {
+					route = tmpl
+				}
+			}
+
+			ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+					attribute.String("http.request_id", logging.RequestID(r.Context())),
+				))
+			defer span.End()
+
+			propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+			r = r.WithContext(ctx)
+			if sc := span.SpanContext(); sc.IsValid() {
+				r = annotateLogger(w, r,
+					zap.String("trace_id", sc.TraceID().String()),
+					zap.String("span_id", sc.SpanID().String()),
+				)
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			status := sw.Status()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// Package migrate применяет встроенные SQL-миграции. Состояние хранится в
+// таблице schema_migrations в формате golang-migrate, поэтому базы, которые
+// мигрировались контейнером migrate/migrate, продолжают работать без изменений.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotMigrated     = errors.New("database schema is not migrated")
+	ErrDirty           = errors.New("database schema is dirty")
+	ErrVersionMismatch = errors.New("database schema version mismatch")
+	ErrUnknownVersion  = errors.New("unknown migration version")
+)
+
+// Ключ pg_advisory_lock, чтобы две реплики не мигрировали одновременно
+const lockKey = 7_215_390_104
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration — пара up/down одной версии.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Parse читает миграции из fsys. Номера версий должны быть уникальны, и у каждой
+// миграции должны быть оба файла: иначе откат на нее невозможен.
+func Parse(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: unexpected file name", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: duplicate version (%s and %s)", version, m.Name, match[2])
+		}
+		target := &m.Up
+		if match[3] == "down" {
+			target = &m.Down
+		}
+		*target = string(body)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down files are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator применяет миграции к базе.
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// New разбирает миграции из fsys (обычно migrations.FS).
+func New(db *pgxpool.Pool, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := Parse(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Migrations возвращает известные миграции по возрастанию версии.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest — версия последней встроенной миграции, которую ожидает код.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы; 0 — миграции не применялись.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	return version(ctx, m.db)
+}
+
+// Check проверяет, что схема чистая и ровно той версии, которую ожидает код.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case dirty:
+		return fmt.Errorf("%w: migration %d did not finish", ErrDirty, current)
+	case current == 0 && m.Latest() > 0:
+		return ErrNotMigrated
+	case current != m.Latest():
+		return fmt.Errorf("%w: database at %d, code expects %d", ErrVersionMismatch, current, m.Latest())
+	}
+	return nil
+}
+
+// Up применяет все непримененные миграции.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down откатывает steps последних примененных миграций.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *pgx.Conn) error {
+		current, err := m.clean(ctx, conn)
+		if err != nil {
+			return err
+		}
+		index := m.index(current)
+		if index < 0 {
+			return nil
+		}
+		target := int64(0)
+		if index-steps >= 0 {
+			target = m.migrations[index-steps].Version
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Goto приводит схему к версии target, применяя или откатывая миграции.
+// target 0 откатывает все миграции.
+func (m *Migrator) Goto(ctx context.Context, target int64) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	return m.locked(ctx, func(conn *pgx.Conn) error {
+		current, err := m.clean(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Force записывает версию без выполнения миграций и снимает признак dirty.
+// Нужен после ручного исправления базы, на которой миграция упала.
+func (m *Migrator) Force(ctx context.Context, target int64) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	return m.locked(ctx, func(conn *pgx.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return setVersion(ctx, tx, target)
+		})
+	})
+}
+
+// 1. Выполнение миграций между версиями. Каждая миграция и запись ее версии
+// выполняются в одной транзакции, поэтому упавшая миграция не оставляет
+// частично примененную схему.
+func (m *Migrator) migrate(ctx context.Context, conn *pgx.Conn, current, target int64) error {
+	if target == current {
+		m.logger.Info("Schema is up to date", zap.Int64("version", current))
+		return nil
+	}
+
+	for _, step := range m.plan(current, target) {
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, step.sql); err != nil {
+				return err
+			}
+			return setVersion(ctx, tx, step.version)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s %s: %w", step.migration.Version, step.migration.Name, step.direction, err)
+		}
+		m.logger.Info("Applied migration",
+			zap.Int64("version", step.migration.Version),
+			zap.String("name", step.migration.Name),
+			zap.String("direction", step.direction),
+		)
+	}
+	return nil
+}
+
+type step struct {
+	migration Migration
+	direction string
+	sql       string
+	// version — версия схемы после шага
+	version int64
+}
+
+// 2. Список шагов от current к target
+func (m *Migrator) plan(current, target int64) []step {
+	var steps []step
+	if target > current {
+		for _, migration := range m.migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, step{migration: migration, direction: "up", sql: migration.Up, version: migration.Version})
+			}
+		}
+		return steps
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= current && migration.Version > target {
+			previous := int64(0)
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			steps = append(steps, step{migration: migration, direction: "down", sql: migration.Down, version: previous})
+		}
+	}
+	return steps
+}
+
+// 3. Выполнение fn на выделенном соединении под advisory-блокировкой
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return err
+	}
+	return fn(conn.Conn())
+}
+
+// 4. Текущая версия с отказом работать поверх незавершенной миграции
+func (m *Migrator) clean(ctx context.Context, conn *pgx.Conn) (int64, error) {
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w: fix migration %d manually, then run migrate force", ErrDirty, current)
+	}
+	if current != 0 && m.index(current) < 0 {
+		return 0, fmt.Errorf("%w: database at %d", ErrUnknownVersion, current)
+	}
+	return current, nil
+}
+
+func (m *Migrator) index(version int64) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func version(ctx context.Context, q querier) (int64, bool, error) {
+	var exists bool
+	if err := q.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, false, err
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var current int64
+	var dirty bool
+	err := q.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return current, dirty, err
+}
+
+// В таблице не больше одной строки; версия 0 хранится как пустая таблица
+func setVersion(ctx context.Context, tx pgx.Tx, version int64) error {
+	if _, err := tx.Exec(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version)
+	return err
+}
@@ -0,0 +1,145 @@
+package migrate_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/migrate"
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/migrations"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestParseEmbedded(t *testing.T) {
+	for name, fsys := range map[string]fs.FS{"postgres": migrations.FS, "sqlite": migrations.SQLite} {
+		parsed, err := migrate.Parse(fsys)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(parsed) == 0 {
+			t.Fatalf("%s: no migrations", name)
+		}
+	}
+}
+
+// Все миграции по одной откатываются до пустой схемы и применяются заново,
+// а схема после повторного применения совпадает с исходной.
+func TestUpDownUp(t *testing.T) {
+	ctx := context.Background()
+	pool, migrator := pgtest.NewTestDatabaseWithMigrator(t)
+
+	want := schema(t, pool)
+	all := migrator.Migrations()
+	for i := len(all) - 1; i >= 0; i-- {
+		if err := migrator.Down(ctx, 1); err != nil {
+			t.Fatalf("down %d_%s: %v", all[i].Version, all[i].Name, err)
+		}
+		wantVersion := int64(0)
+		if i > 0 {
+			wantVersion = all[i-1].Version
+		}
+		if got, dirty, err := migrator.Version(ctx); err != nil || dirty || got != wantVersion {
+			t.Fatalf("after down %d: version %d dirty %v err %v, want %d", all[i].Version, got, dirty, err, wantVersion)
+		}
+	}
+	if left := schema(t, pool); len(left) != 0 {
+		t.Fatalf("objects left after rolling back every migration: %v", left)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up again: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := schema(t, pool); !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema after up-down-up differs:\n got %v\nwant %v", got, want)
+	}
+}
+
+// Продажи, накопленные в analytics до журнала продаж (000012), переносятся
+// без даты: они входят в выручку склада за все время, но не в выручку по
+// дням, где оказались бы в дне применения миграции.
+func TestReportsBackfill(t *testing.T) {
+	ctx := context.Background()
+	pool, migrator := pgtest.NewTestDatabaseWithMigrator(t)
+	if err := migrator.Goto(ctx, 11); err != nil {
+		t.Fatal(err)
+	}
+	var warehouseID, productID string
+	err := pool.QueryRow(ctx, "INSERT INTO warehouses (name, address) VALUES ('Backfill', 'Old street 1') RETURNING id").Scan(&warehouseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pool.QueryRow(ctx, `
+		INSERT INTO products (name, attributes, weight, barcode)
+		VALUES ('Old product', '{}', 1, '4600000000899') RETURNING id`).Scan(&productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(ctx, "INSERT INTO analytics (warehouse_id, product_id, sold_quantity, total_sum) VALUES ($1, $2, 7, 350.00)", warehouseID, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := repository.NewReportRepository(pool, nil)
+	if err := reports.RefreshRevenue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var revenue string
+	err = pool.QueryRow(ctx, "SELECT revenue::text FROM report_warehouse_revenue WHERE warehouse_id = $1", warehouseID).Scan(&revenue)
+	if err != nil || revenue != "350.00" {
+		t.Fatalf("total revenue %q (%v), want 350.00", revenue, err)
+	}
+	var days int
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM report_daily_revenue").Scan(&days); err != nil {
+		t.Fatal(err)
+	}
+	if days != 0 {
+		t.Fatalf("%d daily revenue rows from backfilled sales, want 0", days)
+	}
+}
+
+// schema описывает объекты схемы public, кроме schema_migrations: колонки,
+// индексы и функции
+func schema(t *testing.T, pool *pgxpool.Pool) []string {
+	t.Helper()
+	rows, err := pool.Query(context.Background(), `
+		SELECT 'column ' || table_name || '.' || column_name || ' ' || data_type || ' ' || is_nullable || ' ' || coalesce(column_default, '')
+		FROM information_schema.columns
+		WHERE table_schema = 'public' AND table_name <> 'schema_migrations'
+		UNION ALL
+		SELECT 'index ' || indexdef
+		FROM pg_indexes
+		WHERE schemaname = 'public' AND tablename <> 'schema_migrations'
+		UNION ALL
+		SELECT 'function ' || p.oid::regprocedure::text
+		FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = 'public'
+		ORDER BY 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var objects []string
+	for rows.Next() {
+		var object string
+		if err := rows.Scan(&object); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
@@ -12,6 +12,13 @@ type Inventory struct {
 	Quantity    int             `json:"quantity"`
 	Price       decimal.Decimal `json:"price"`
 	Discount    decimal.Decimal `json:"discount"`
+	Version     int64           `json:"version"`
+}
+
+// InventoryPrice — цена и скидка товара на складе
+type InventoryPrice struct {
+	Price    decimal.Decimal `json:"price"`
+	Discount decimal.Decimal `json:"discount"`
 }
 
 type InventoryWithNames struct {
@@ -23,4 +30,5 @@ type InventoryWithNames struct {
 	Discount      decimal.Decimal `json:"discount"`
 	WarehouseName string          `json:"warehouse_name"`
 	ProductName   string          `json:"product_name"`
+	Version       int64           `json:"version"`
 }
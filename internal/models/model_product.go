@@ -7,4 +7,5 @@ type Product struct {
 	Attributes  map[string]string `json:"attributes"`
 	Weight      float64           `json:"weight"`
 	Barcode     string            `json:"barcode"`
+	Version     int64             `json:"version"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Report — строки отчета и момент, по состоянию на который он построен.
+// AsOf = nil, если отчет еще ни разу не обновлялся.
+type Report[T any] struct {
+	AsOf  *time.Time `json:"as_of"`
+	Items []T        `json:"items"`
+}
+
+// WarehouseRevenue — выручка склада за все время.
+type WarehouseRevenue struct {
+	WarehouseID uuid.UUID       `json:"warehouse_id"`
+	Address     string          `json:"address"`
+	TotalSum    decimal.Decimal `json:"total_sum"`
+}
+
+// DailyRevenue — продажи склада за день (UTC, формат YYYY-MM-DD).
+type DailyRevenue struct {
+	Date         string          `json:"date"`
+	SoldQuantity int64           `json:"sold_quantity"`
+	Revenue      decimal.Decimal `json:"revenue"`
+}
+
+// StockValue — стоимость остатков склада по текущим ценам со скидкой.
+type StockValue struct {
+	WarehouseID uuid.UUID       `json:"warehouse_id"`
+	Units       int64           `json:"units"`
+	Value       decimal.Decimal `json:"value"`
+}
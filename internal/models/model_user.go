@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type User struct {
+	ID           uuid.UUID   `json:"id"`
+	Username     string      `json:"username"`
+	Roles        []string    `json:"roles"`
+	WarehouseIDs []uuid.UUID `json:"warehouse_ids"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
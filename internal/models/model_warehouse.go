@@ -7,4 +7,5 @@ type Warehouse struct {
 	Name        string    `json:"name"`
 	Address     string    `json:"address"`
 	Description string    `json:"description,omitempty"`
+	Version     int64     `json:"version"`
 }
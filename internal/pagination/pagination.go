@@ -0,0 +1,139 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor возвращается, если курсор из запроса не удалось разобрать.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidLimit возвращается при некорректном значении limit.
+var ErrInvalidLimit = errors.New("invalid limit")
+
+// Limits задает размер страницы по умолчанию и максимально допустимый.
+type Limits struct {
+	Default int
+	Max     int
+}
+
+// DefaultLimits используются, если лимиты не заданы в конфигурации.
+var DefaultLimits = Limits{Default: 50, Max: 500}
+
+// Cursor указывает на последнюю запись предыдущей страницы.
+// Key хранит значение ключа сортировки, ID — идентификатор для стабильного порядка,
+// Sort — сортировку, с которой курсор был выдан.
+type Cursor struct {
+	ID   string `json:"id"`
+	Key  string `json:"k,omitempty"`
+	Sort string `json:"s,omitempty"`
+}
+
+// Encode сериализует курсор в непрозрачную строку.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает строку, полученную из Cursor.Encode. ID записи
+// проверяется здесь, чтобы поддельный курсор давал ErrInvalidCursor (400)
+// на любом хранилище, а не ошибку приведения к uuid в SQL.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(c.ID)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c.ID = id.String()
+	return c, nil
+}
+
+// Params описывает запрошенную страницу.
+type Params struct {
+	Limit        int
+	After        *Cursor
+	IncludeTotal bool
+}
+
+// AfterID возвращает ID из курсора или пустую строку для первой страницы.
+func (p Params) AfterID() string {
+	if p.After == nil {
+		return ""
+	}
+	return p.After.ID
+}
+
+// FromRequest читает limit, cursor и include_total из query-параметров.
+func FromRequest(r *http.Request, limits Limits) (Params, error) {
+	if limits.Default <= 0 {
+		limits.Default = DefaultLimits.Default
+	}
+	if limits.Max <= 0 {
+		limits.Max = DefaultLimits.Max
+	}
+
+	q := r.URL.Query()
+	p := Params{Limit: limits.Default}
+
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return p, ErrInvalidLimit
+		}
+		p.Limit = limit
+	}
+	if p.Limit > limits.Max {
+		p.Limit = limits.Max
+	}
+
+	if raw := q.Get("cursor"); raw != "" {
+		c, err := DecodeCursor(raw)
+		if err != nil {
+			return p, err
+		}
+		p.After = &c
+	}
+
+	if raw := q.Get("include_total"); raw != "" {
+		includeTotal, err := strconv.ParseBool(raw)
+		if err != nil {
+			return p, errors.New("invalid include_total")
+		}
+		p.IncludeTotal = includeTotal
+	}
+
+	return p, nil
+}
+
+// Page — конверт ответа для списочных эндпоинтов.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// NewPage формирует страницу из items, выбранных с лимитом Limit+1:
+// лишняя запись отбрасывается и служит признаком наличия следующей страницы.
+func NewPage[T any](items []T, p Params, cursor func(T) Cursor) Page[T] {
+	page := Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(page.Items) > p.Limit {
+		page.Items = page.Items[:p.Limit]
+		page.NextCursor = cursor(page.Items[len(page.Items)-1]).Encode()
+	}
+	return page
+}
@@ -0,0 +1,32 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	const id = "0b7c6f3e-9d2a-4c55-8f1e-2a6b3c4d5e6f"
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	c, err := DecodeCursor(Cursor{ID: id, Key: "10", Sort: "quantity"}.Encode())
+	if err != nil || c.ID != id || c.Key != "10" || c.Sort != "quantity" {
+		t.Fatalf("round trip = %+v, %v", c, err)
+	}
+
+	for name, raw := range map[string]string{
+		"not base64":    "garbage!",
+		"not json":      encode("id"),
+		"empty id":      encode(`{"id":""}`),
+		"id not uuid":   encode(`{"id":"not-a-uuid"}`),
+		"sql in id":     encode(`{"id":"1' OR '1'='1"}`),
+		"numeric id":    encode(`{"id":"42"}`),
+		"missing id":    encode(`{"k":"10"}`),
+		"wrong id type": encode(`{"id":42}`),
+	} {
+		if _, err := DecodeCursor(raw); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
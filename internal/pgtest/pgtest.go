@@ -0,0 +1,275 @@
+// Package pgtest поднимает одноразовый PostgreSQL для интеграционных
+// проверок: кластер создается initdb во временном каталоге и удаляется при
+// остановке, а каждая проверка получает свою базу с примененными миграциями.
+//
+// Бинарники initdb и postgres ищутся в PG_BIN, в PATH и в стандартных
+// каталогах пакетов PostgreSQL. Если задан PGTEST_URL, вместо запуска
+// кластера используется уже работающий сервер (например, в CI).
+//
+// Пакет импортируется только из _test.go и в сборку сервера не входит.
+package pgtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/migrate"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/repository/contract"
+	"github.com/yourusername/warehouse-service/migrations"
+	"go.uber.org/zap"
+)
+
+// Переменные окружения harness
+const (
+	EnvURL = "PGTEST_URL"
+	EnvBin = "PG_BIN"
+)
+
+// ErrNoPostgres возвращается, если не задан PGTEST_URL и бинарники
+// PostgreSQL не найдены: проверки на PostgreSQL следует пропустить.
+var ErrNoPostgres = errors.New("postgres binaries not found (set " + EnvBin + " or " + EnvURL + ")")
+
+// startTimeout — сколько ждать, пока новый кластер начнет принимать соединения
+const startTimeout = 30 * time.Second
+
+// Server — запущенный (или внешний) сервер PostgreSQL.
+type Server struct {
+	url    *url.URL
+	dir    string
+	cmd    *exec.Cmd
+	exited chan error
+	logger *zap.Logger
+}
+
+// Start запускает одноразовый кластер или подключается к PGTEST_URL.
+func Start(ctx context.Context, logger *zap.Logger) (*Server, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if raw := os.Getenv(EnvURL); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", EnvURL, err)
+		}
+		s := &Server{url: u, logger: logger}
+		return s, s.wait(ctx)
+	}
+
+	bin, err := findBinaries()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{dir: dir, logger: logger}
+	if err := s.start(ctx, bin); err != nil {
+		s.Stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+// findBinaries возвращает каталог с initdb и postgres
+func findBinaries() (string, error) {
+	var candidates []string
+	if dir := os.Getenv(EnvBin); dir != "" {
+		candidates = append(candidates, dir)
+	}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		candidates = append(candidates, filepath.Dir(path))
+	}
+	// Debian/Ubuntu кладут бинарники в каталог версии; новые версии — первыми
+	versions, _ := filepath.Glob("/usr/lib/postgresql/*/bin")
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	candidates = append(candidates, versions...)
+	candidates = append(candidates, "/usr/local/pgsql/bin", "/opt/homebrew/bin", "/usr/local/bin")
+
+	for _, dir := range candidates {
+		if isExecutable(filepath.Join(dir, "initdb")) && isExecutable(filepath.Join(dir, "postgres")) {
+			return dir, nil
+		}
+	}
+	return "", ErrNoPostgres
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// start создает кластер с доверительной аутентификацией и без fsync:
+// данные одноразовые, важна только скорость
+func (s *Server) start(ctx context.Context, bin string) error {
+	data := filepath.Join(s.dir, "data")
+	initdb := exec.CommandContext(ctx, filepath.Join(bin, "initdb"),
+		"-D", data, "-U", "postgres", "--auth=trust", "--encoding=UTF8", "--no-sync")
+	if out, err := initdb.CombinedOutput(); err != nil {
+		return fmt.Errorf("initdb: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+	var output bytes.Buffer
+	s.cmd = exec.Command(filepath.Join(bin, "postgres"),
+		"-D", data,
+		"-p", strconv.Itoa(port),
+		"-k", s.dir,
+		"-c", "listen_addresses=127.0.0.1",
+		"-c", "fsync=off",
+		"-c", "synchronous_commit=off",
+		"-c", "full_page_writes=off",
+		"-c", "max_connections=200",
+	)
+	s.cmd.Stdout = &output
+	s.cmd.Stderr = &output
+	if err := s.cmd.Start(); err != nil {
+		return fmt.Errorf("start postgres: %w", err)
+	}
+	s.exited = make(chan error, 1)
+	go func() { s.exited <- s.cmd.Wait() }()
+
+	s.url = &url.URL{Scheme: "postgres", User: url.User("postgres"), Host: "127.0.0.1:" + strconv.Itoa(port), Path: "/postgres"}
+	if err := s.wait(ctx); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output.Bytes()))
+	}
+	s.logger.Info("Started disposable postgres", zap.String("dir", s.dir), zap.Int("port", port))
+	return nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// wait ждет, пока сервер начнет принимать соединения
+func (s *Server) wait(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	for {
+		conn, err := pgx.Connect(ctx, s.url.String())
+		if err == nil {
+			return conn.Close(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("postgres is not accepting connections: %w", err)
+		case err := <-s.exited:
+			return fmt.Errorf("postgres exited: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// URL возвращает адрес служебной базы сервера.
+func (s *Server) URL() string {
+	return s.url.String()
+}
+
+// Stop останавливает кластер и удаляет его каталог. Внешний сервер
+// (PGTEST_URL) не трогается.
+func (s *Server) Stop() {
+	if s.cmd != nil && s.cmd.Process != nil {
+		// SIGINT — fast shutdown: открытые соединения обрываются сразу
+		_ = s.cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-s.exited:
+		case <-time.After(10 * time.Second):
+			_ = s.cmd.Process.Kill()
+			<-s.exited
+		}
+	}
+	if s.dir != "" {
+		_ = os.RemoveAll(s.dir)
+	}
+}
+
+// NewDatabase создает пустую базу, применяет к ней встроенные миграции и
+// возвращает пул и мигратор (для пробы готовности приложения). cleanup
+// закрывает пул и удаляет базу.
+func (s *Server) NewDatabase(ctx context.Context) (pool *pgxpool.Pool, migrator *migrate.Migrator, cleanup func(), err error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, nil, nil, err
+	}
+	name := "pgtest_" + hex.EncodeToString(suffix)
+
+	admin, err := pgx.Connect(ctx, s.url.String())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		return nil, nil, nil, fmt.Errorf("create database: %w", err)
+	}
+	drop := func() {
+		ctx := context.Background()
+		conn, err := pgx.Connect(ctx, s.url.String())
+		if err == nil {
+			defer conn.Close(ctx)
+			_, err = conn.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+		}
+		if err != nil {
+			s.logger.Warn("Failed to drop test database", zap.String("database", name), zap.Error(err))
+		}
+	}
+
+	dbURL := *s.url
+	dbURL.Path = "/" + name
+	pool, err = pgxpool.New(ctx, dbURL.String())
+	if err != nil {
+		drop()
+		return nil, nil, nil, err
+	}
+	cleanup = func() {
+		pool.Close()
+		drop()
+	}
+
+	migrator, err = migrate.New(pool, migrations.FS, s.logger)
+	if err == nil {
+		err = migrator.Up(ctx)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, fmt.Errorf("migrate: %w", err)
+	}
+	return pool, migrator, cleanup, nil
+}
+
+// ContractFactory создает для каждого случая contract-набора отдельную базу.
+func (s *Server) ContractFactory() contract.Factory {
+	return func(ctx context.Context) (contract.Backend, func(), error) {
+		pool, _, cleanup, err := s.NewDatabase(ctx)
+		if err != nil {
+			return contract.Backend{}, nil, err
+		}
+		backend := contract.Backend{
+			Repos: repository.NewRepositories(pool, s.logger),
+			Tx:    repository.NewTxManager(pool, s.logger),
+		}
+		return backend, cleanup, nil
+	}
+}
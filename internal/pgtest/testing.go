@@ -0,0 +1,75 @@
+package pgtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/migrate"
+)
+
+// EnvRequire, если не пуст, превращает пропуск тестов без PostgreSQL в
+// ошибку (для CI, где сервер обязан быть).
+const EnvRequire = "PGTEST_REQUIRE"
+
+// shared — сервер, общий для всех тестов пакета: кластер запускается при
+// первом обращении и останавливается в Main
+var shared struct {
+	once   sync.Once
+	server *Server
+	err    error
+}
+
+// Main запускает тесты пакета и останавливает общий сервер. Пакеты с
+// тестами на PostgreSQL вызывают его из TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	if shared.server != nil {
+		shared.server.Stop()
+	}
+	return code
+}
+
+// Shared возвращает общий сервер пакета. Без PostgreSQL, а также с -short
+// тест пропускается; с PGTEST_REQUIRE — завершается ошибкой.
+func Shared(t testing.TB) *Server {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("postgres tests are skipped in -short mode")
+	}
+	shared.once.Do(func() {
+		shared.server, shared.err = Start(context.Background(), nil)
+	})
+	if errors.Is(shared.err, ErrNoPostgres) && os.Getenv(EnvRequire) == "" {
+		t.Skip(shared.err)
+	}
+	if shared.err != nil {
+		t.Fatal(shared.err)
+	}
+	return shared.server
+}
+
+// NewTestDatabase создает для теста базу с примененными миграциями и
+// удаляет ее после теста.
+func NewTestDatabase(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	pool, _ := NewTestDatabaseWithMigrator(t)
+	return pool
+}
+
+// NewTestDatabaseWithMigrator — как NewTestDatabase, но возвращает и
+// мигратор (для пробы готовности приложения).
+func NewTestDatabaseWithMigrator(t testing.TB) (*pgxpool.Pool, *migrate.Migrator) {
+	t.Helper()
+	pool, migrator, cleanup, err := Shared(t).NewDatabase(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	return pool, migrator
+}
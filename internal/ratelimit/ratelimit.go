@@ -0,0 +1,207 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit, expected <requests>/<period>, e.g. 100/1m")
+
+// Limit — квота в Requests запросов за Per. Корзина вмещает Requests
+// токенов и пополняется равномерно, так что короткий всплеск до Requests
+// запросов допустим, а средняя скорость не превышает квоту.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Enabled сообщает, задана ли квота.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// rate — скорость пополнения корзины в токенах за секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseLimit разбирает квоту вида "100/1m" или "10/s".
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	// "s", "m", "h" без числа означают один период
+	if period != "" && strings.IndexAny(period[:1], "0123456789") < 0 {
+		period = "1" + period
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	return Limit{Requests: n, Per: per}, nil
+}
+
+// Группы маршрутов с отдельными квотами. GroupIP — общая квота IP-адреса
+// на все запросы, проверяемая до аутентификации.
+const (
+	GroupReads     = "reads"
+	GroupWrites    = "writes"
+	GroupPurchases = "purchases"
+	GroupIP        = "ip"
+)
+
+// Policy задает квоты для групп маршрутов; незаданная квота не ограничивает группу.
+type Policy struct {
+	Reads     Limit
+	Writes    Limit
+	Purchases Limit
+	// IP ограничивает все запросы с одного адреса, в том числе с неверными
+	// ключами: такие запросы отклоняются до поиска ключа в базе
+	IP Limit
+}
+
+// For возвращает квоту группы.
+func (p Policy) For(group string) Limit {
+	switch group {
+	case GroupReads:
+		return p.Reads
+	case GroupWrites:
+		return p.Writes
+	case GroupPurchases:
+		return p.Purchases
+	case GroupIP:
+		return p.IP
+	}
+	return Limit{}
+}
+
+// Enabled сообщает, ограничена ли хотя бы одна группа.
+func (p Policy) Enabled() bool {
+	return p.Reads.Enabled() || p.Writes.Enabled() || p.Purchases.Enabled() || p.IP.Enabled()
+}
+
+// Result — решение по запросу и данные для заголовков RateLimit-*.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset — время до полного пополнения корзины
+	Reset time.Duration
+	// RetryAfter — время до появления следующего токена (для отказа)
+	RetryAfter time.Duration
+}
+
+// Store расходует токены корзин. Реализации: MemoryStore для одного
+// экземпляра сервиса, RedisStore для нескольких реплик и хранилище в
+// Postgres (только для небольших установок: каждый запрос — транзакция с
+// записью в основную базу).
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Expirer — хранилище корзин, из которого истекшие корзины нужно удалять
+// периодически (в Postgres они не исчезают сами, в отличие от ключей Redis).
+type Expirer interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// Bucket — состояние корзины: остаток токенов на момент Updated.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take пополняет корзину за прошедшее время и пытается взять один токен.
+// Пустая (нулевая) корзина считается полной.
+func (b *Bucket) Take(now time.Time, limit Limit) Result {
+	capacity := float64(limit.Requests)
+	if b.Updated.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*limit.rate())
+	}
+	b.Updated = now
+
+	allowed := b.Tokens >= 1
+	if allowed {
+		b.Tokens--
+	}
+	return newResult(limit, b.Tokens, allowed)
+}
+
+// newResult описывает решение по остатку tokens после попытки взять токен
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	result := Result{Limit: limit.Requests, Allowed: allowed}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+	result.Remaining = int(tokens)
+	result.Reset = seconds((float64(limit.Requests) - tokens) / limit.rate())
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryStore хранит корзины в памяти процесса.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	per time.Duration
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// Неиспользуемые корзины удаляются не чаще, чем раз в этот интервал
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	b.per = limit.Per
+	return b.Take(now, limit), nil
+}
+
+// sweep удаляет корзины, которые успели полностью пополниться:
+// они неотличимы от новых
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.Updated) > b.per {
+			delete(s.buckets, key)
+		}
+	}
+}
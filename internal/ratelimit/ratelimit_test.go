@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStores(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client, "test:"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := Limit{Requests: 3, Per: time.Minute}
+			for i := range 3 {
+				result, err := store.Take(ctx, "ip:1", limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.Allowed || result.Remaining != 2-i {
+					t.Fatalf("request %d: %+v, want allowed with %d remaining", i+1, result, 2-i)
+				}
+			}
+
+			result, err := store.Take(ctx, "ip:1", limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Токен пополняется за Per/Requests = 20s
+			if result.Allowed || result.RetryAfter <= 19*time.Second || result.RetryAfter > 20*time.Second {
+				t.Fatalf("over limit: %+v, want denied with retry after ~20s", result)
+			}
+
+			// У другого ключа своя корзина
+			if result, err := store.Take(ctx, "ip:2", limit); err != nil || !result.Allowed {
+				t.Fatalf("other key: %+v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestRedisStoreRefills(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	start := time.Now()
+	server.SetTime(start)
+	if result, _ := store.Take(ctx, "k", limit); !result.Allowed {
+		t.Fatal("first request denied")
+	}
+	if result, _ := store.Take(ctx, "k", limit); result.Allowed {
+		t.Fatal("second request allowed")
+	}
+	server.SetTime(start.Add(time.Minute))
+	if result, err := store.Take(ctx, "k", limit); err != nil || !result.Allowed {
+		t.Fatalf("after refill: %+v, %v", result, err)
+	}
+	if ttl := server.TTL("test:k"); ttl <= 0 || ttl > time.Minute+time.Second {
+		t.Fatalf("bucket ttl = %v, want until full refill", ttl)
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript — Bucket.Take на стороне Redis: пересчет и расход токена
+// выполняются атомарно, время берется из Redis, чтобы расхождение часов
+// реплик не влияло на квоты. Корзина истекает, когда полностью пополнится.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+elseif now > updated then
+	tokens = math.min(capacity, tokens + (now - updated) * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore хранит корзины в Redis: квоты общие для всех реплик, а
+// основная база не получает записи на каждый запрос.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore создает хранилище корзин; prefix добавляется к ключам.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		limit.Requests, strconv.FormatFloat(limit.rate(), 'g', -1, 64)).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := reply[0].(int64)
+	raw, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(limit, tokens, allowed == 1), nil
+}
@@ -0,0 +1,223 @@
+// Package cached — кэш чтений перед репозиториями. Кэшируются списки
+// складов и товаров и цены товаров на складах (для расчета стоимости);
+// остальные методы идут в хранилище напрямую. Цену в транзакции покупки
+// кэш не отдает: по ней списываются деньги.
+//
+// Ключи объединены в группы (списки складов, списки товаров, цены), и
+// запись сбрасывает группу целиком: ключи группы содержат ее поколение —
+// случайный токен, — и сброс заменяет токен. Старые значения становятся
+// недоступны и вытесняются по TTL. Чтение, начатое до сброса, сохраняет
+// результат под старым поколением, поэтому устаревшее значение не
+// переживает сброс. В транзакции сброс выполняется после фиксации, а
+// чтения группы после записи в нее идут в хранилище.
+//
+// С репликой чтение сразу после сброса могло бы попасть на реплику, еще не
+// получившую запись, и сохранить устаревшие данные под новым поколением
+// на весь TTL. Поэтому поколение хранит время своего создания, и пока оно
+// моложе максимального отставания реплики, промахи читаются с основной
+// базы (repository.WithPrimary).
+//
+// Ошибки кэша не ломают запросы: они логируются, и чтение идет в хранилище.
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/cache"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Группы ключей
+const (
+	GroupWarehouses = "warehouses"
+	GroupProducts   = "products"
+	GroupPrices     = "prices"
+)
+
+// Observer учитывает попадания и промахи по группам (metrics.Metrics).
+type Observer interface {
+	CacheRequest(group string, hit bool)
+}
+
+// Layer — кэш с настройками; из него строятся кэширующие репозитории.
+type Layer struct {
+	cache      cache.Cache
+	ttl        time.Duration
+	replicaLag time.Duration
+	logger     *zap.Logger
+	observer   Observer
+}
+
+// New создает слой над кэшем c; значения живут ttl. replicaLag —
+// максимальное отставание реплики (0 без реплики): столько после сброса
+// группы ее промахи читаются с основной базы. observer может быть nil.
+func New(c cache.Cache, ttl, replicaLag time.Duration, logger *zap.Logger, observer Observer) *Layer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Layer{cache: c, ttl: ttl, replicaLag: replicaLag, logger: logger, observer: observer}
+}
+
+// Repositories оборачивает репозитории вне транзакции: запись сразу
+// сбрасывает затронутые группы.
+func (l *Layer) Repositories(repos repository.Repositories) repository.Repositories {
+	return l.wrap(repos, &scope{layer: l})
+}
+
+// Transactor оборачивает транзакции: репозитории в транзакции читают
+// через кэш, а сброс групп откладывается до фиксации.
+func (l *Layer) Transactor(tx repository.Transactor) repository.Transactor {
+	return transactor{layer: l, next: tx}
+}
+
+func (l *Layer) wrap(repos repository.Repositories, s *scope) repository.Repositories {
+	repos.Warehouses = warehouses{WarehouseRepository: repos.Warehouses, scope: s}
+	repos.Products = products{ProductRepository: repos.Products, scope: s}
+	repos.Inventory = inventory{InventoryRepository: repos.Inventory, scope: s}
+	return repos
+}
+
+type transactor struct {
+	layer *Layer
+	next  repository.Transactor
+}
+
+func (t transactor) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	// При повторе транзакции учитываются записи только последней попытки
+	var last *scope
+	err := t.next.WithTx(ctx, func(repos repository.Repositories) error {
+		last = &scope{layer: t.layer, inTx: true, written: map[string]bool{}}
+		return fn(t.layer.wrap(repos, last))
+	})
+	if err == nil && last != nil {
+		for group := range last.written {
+			t.layer.invalidate(ctx, group)
+		}
+	}
+	return err
+}
+
+// scope — репозитории вне транзакции или в одной транзакции
+type scope struct {
+	layer *Layer
+	inTx  bool
+	// written — группы, в которые транзакция уже писала
+	written map[string]bool
+}
+
+// write отмечает запись в группы. Сброс выполняется и после ошибки:
+// лишний сброс безопасен, а исход записи при ошибке может быть неизвестен.
+func (s *scope) write(ctx context.Context, groups ...string) {
+	for _, group := range groups {
+		if s.inTx {
+			s.written[group] = true
+		} else {
+			s.layer.invalidate(ctx, group)
+		}
+	}
+}
+
+// load возвращает значение из кэша или читает его через fetch и сохраняет.
+// В транзакции после записи в группу кэш не используется.
+func load[T any](ctx context.Context, s *scope, group, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	if s.inTx && s.written[group] {
+		return fetch(ctx)
+	}
+	l := s.layer
+	generation, err := l.generation(ctx, group)
+	if err != nil {
+		l.log(ctx).Warn("Cache unavailable", zap.String("group", group), zap.Error(err))
+		return fetch(ctx)
+	}
+	key = group + ":" + generation + ":" + key
+
+	data, ok, err := l.cache.Get(ctx, key)
+	if err != nil {
+		l.log(ctx).Warn("Cache read failed", zap.String("key", key), zap.Error(err))
+	}
+	if ok {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			l.observe(group, true)
+			return value, nil
+		}
+	}
+	l.observe(group, false)
+
+	// Реплика могла еще не получить запись, сбросившую группу
+	if l.replicaLag > 0 && generationAge(generation) < l.replicaLag {
+		ctx = repository.WithPrimary(ctx)
+	}
+	value, err := fetch(ctx)
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err == nil {
+		if err := l.cache.Set(ctx, key, data, l.ttl); err != nil {
+			l.log(ctx).Warn("Cache write failed", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return value, nil
+}
+
+// generation возвращает текущее поколение группы, создавая его при
+// первом обращении (или после вытеснения — это тоже сброс группы)
+func (l *Layer) generation(ctx context.Context, group string) (string, error) {
+	data, ok, err := l.cache.Get(ctx, "gen:"+group)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return string(data), nil
+	}
+	generation := newGeneration()
+	return generation, l.cache.Set(ctx, "gen:"+group, []byte(generation), 0)
+}
+
+// invalidate сбрасывает группу; значения старого поколения истекут по TTL
+func (l *Layer) invalidate(ctx context.Context, group string) {
+	if err := l.cache.Set(ctx, "gen:"+group, []byte(newGeneration()), 0); err != nil {
+		// Повторная попытка удалить поколение: без него группа тоже сбрасывается
+		if err := l.cache.Delete(ctx, "gen:"+group); err != nil {
+			l.log(ctx).Error("Cache invalidation failed, stale reads possible until TTL",
+				zap.String("group", group), zap.Duration("ttl", l.ttl), zap.Error(err))
+		}
+	}
+}
+
+// newGeneration возвращает токен поколения: время создания в миллисекундах
+// и случайная часть
+func newGeneration() string {
+	return strconv.FormatInt(time.Now().UnixMilli(), 36) + "." + strconv.FormatUint(rand.Uint64(), 36)
+}
+
+// generationAge возвращает возраст поколения; у токенов без времени
+// (записанных до его появления) возраст неизвестен и считается большим
+func generationAge(generation string) time.Duration {
+	created, _, ok := strings.Cut(generation, ".")
+	if !ok {
+		return time.Duration(1<<63 - 1)
+	}
+	millis, err := strconv.ParseInt(created, 36, 64)
+	if err != nil {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Since(time.UnixMilli(millis))
+}
+
+func (l *Layer) observe(group string, hit bool) {
+	if l.observer != nil {
+		l.observer.CacheRequest(group, hit)
+	}
+}
+
+func (l *Layer) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, l.logger)
+}
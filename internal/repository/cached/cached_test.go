@@ -0,0 +1,167 @@
+package cached_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/cache"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/internal/repository/cached"
+	"github.com/yourusername/warehouse-service/internal/repository/contract"
+	"github.com/yourusername/warehouse-service/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+// contract-набор через слой кэша над хранилищем в памяти: записи должны
+// сбрасывать кэш так, чтобы чтения не возвращали устаревшие данные
+func TestContract(t *testing.T) {
+	server := miniredis.RunT(t)
+	caches := map[string]func(t *testing.T) cache.Cache{
+		"lru": func(*testing.T) cache.Cache { return cache.NewLRU(1000) },
+		"redis": func(t *testing.T) cache.Cache {
+			// Случаи делят сервер, поэтому у каждого свой префикс ключей
+			c := cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), t.Name()+":")
+			t.Cleanup(func() { _ = c.Close() })
+			return c
+		},
+	}
+	for driver, newCache := range caches {
+		t.Run(driver, func(t *testing.T) {
+			for _, c := range contract.Cases() {
+				t.Run(c.Name, func(t *testing.T) {
+					factory := func(context.Context) (contract.Backend, func(), error) {
+						store := memory.New()
+						layer := cached.New(newCache(t), time.Minute, 0, zap.NewNop(), nil)
+						return contract.Backend{
+							Repos: layer.Repositories(store.Repositories()),
+							Tx:    layer.Transactor(store),
+						}, nil, nil
+					}
+					if err := contract.RunCase(context.Background(), factory, c); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		})
+	}
+}
+
+// primaryRecorder запоминает, требовало ли чтение списка основной базы
+type primaryRecorder struct {
+	repository.WarehouseRepository
+	reads []bool
+}
+
+func (r *primaryRecorder) GetAllWarehouses(ctx context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error) {
+	r.reads = append(r.reads, repository.PrimaryRequired(ctx))
+	return r.WarehouseRepository.GetAllWarehouses(ctx, page)
+}
+
+// Промах вскоре после сброса группы не должен читаться с реплики: она могла
+// еще не получить запись, и устаревший список остался бы в кэше на весь TTL
+func TestFreshGenerationReadsPrimary(t *testing.T) {
+	ctx := context.Background()
+	const lag = 100 * time.Millisecond
+	store := memory.New()
+	recorder := &primaryRecorder{WarehouseRepository: store.Repositories().Warehouses}
+	repos := store.Repositories()
+	repos.Warehouses = recorder
+	layer := cached.New(cache.NewLRU(100), time.Minute, lag, zap.NewNop(), nil)
+	warehouses := layer.Repositories(repos).Warehouses
+
+	read := func(limit int) {
+		t.Helper()
+		if _, err := warehouses.GetAllWarehouses(ctx, pagination.Params{Limit: limit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Первое поколение только создано, поэтому первый промах — с основной
+	// базы; когда оно старше отставания, промахи читаются с реплики
+	read(10)
+	time.Sleep(2 * lag)
+	read(20)
+
+	// Запись сбрасывает группу: следующий промах — с основной базы
+	if _, err := warehouses.CreateWarehouse(ctx, "Main", "Street 1"); err != nil {
+		t.Fatal(err)
+	}
+	read(10)
+
+	// Когда поколение старше отставания, промахи снова идут на реплику
+	time.Sleep(2 * lag)
+	read(30)
+
+	want := []bool{true, false, true, false}
+	if len(recorder.reads) != len(want) {
+		t.Fatalf("fetched %d times, want %d", len(recorder.reads), len(want))
+	}
+	for i := range want {
+		if recorder.reads[i] != want[i] {
+			t.Errorf("read %d: primary required = %t, want %t", i, recorder.reads[i], want[i])
+		}
+	}
+
+	// Без реплики основная база не требуется
+	recorder.reads = nil
+	plain := cached.New(cache.NewLRU(100), time.Minute, 0, zap.NewNop(), nil).Repositories(repos).Warehouses
+	if _, err := plain.GetAllWarehouses(ctx, pagination.Params{Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.reads) != 1 || recorder.reads[0] {
+		t.Errorf("reads without replica = %v, want [false]", recorder.reads)
+	}
+}
+
+// Покупка берет цену в транзакции: там цена читается из хранилища, даже
+// если в кэше осталась прежняя (изменение другой репликой еще не сбросило
+// кэш или сброс не дошел)
+func TestPriceInTransactionBypassesCache(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	layer := cached.New(cache.NewLRU(100), time.Minute, 0, zap.NewNop(), nil)
+	repos := layer.Repositories(store.Repositories())
+
+	warehouseID, err := repos.Warehouses.CreateWarehouse(ctx, "Main", "Street 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	productID := uuid.New()
+	if err := repos.Products.Create(ctx, models.Product{ID: productID.String(), Name: "Tea", Weight: 1, Barcode: "4600000000103"}); err != nil {
+		t.Fatal(err)
+	}
+	position := models.Inventory{ProductID: productID, WarehouseID: warehouseID, Quantity: 5, Price: decimal.RequireFromString("10")}
+	if err := repos.Inventory.Create(ctx, position); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Inventory.GetProductPrice(ctx, productID, warehouseID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Цена меняется в хранилище в обход этого кэша
+	position.Price = decimal.RequireFromString("12")
+	if err := store.Repositories().Inventory.Create(ctx, position); err != nil {
+		t.Fatal(err)
+	}
+
+	err = layer.Transactor(store).WithTx(ctx, func(repos repository.Repositories) error {
+		price, err := repos.Inventory.GetProductPrice(ctx, productID, warehouseID)
+		if err != nil {
+			return err
+		}
+		if !price.Price.Equal(position.Price) {
+			t.Errorf("price in transaction = %s, want %s", price.Price, position.Price)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
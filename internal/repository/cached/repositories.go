@@ -0,0 +1,113 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// pageKey — ключ страницы списка
+func pageKey(page pagination.Params) string {
+	after := ""
+	if page.After != nil {
+		after = page.After.Encode()
+	}
+	return fmt.Sprintf("list:%d:%t:%s", page.Limit, page.IncludeTotal, after)
+}
+
+type warehouses struct {
+	repository.WarehouseRepository
+	scope *scope
+}
+
+func (r warehouses) GetAllWarehouses(ctx context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error) {
+	return load(ctx, r.scope, GroupWarehouses, pageKey(page), func(ctx context.Context) (pagination.Page[models.Warehouse], error) {
+		return r.WarehouseRepository.GetAllWarehouses(ctx, page)
+	})
+}
+
+func (r warehouses) CreateWarehouse(ctx context.Context, name string, address string) (uuid.UUID, error) {
+	defer r.scope.write(ctx, GroupWarehouses)
+	return r.WarehouseRepository.CreateWarehouse(ctx, name, address)
+}
+
+func (r warehouses) UpdateWarehouse(ctx context.Context, warehouse models.Warehouse, expectedVersion int64) error {
+	defer r.scope.write(ctx, GroupWarehouses)
+	return r.WarehouseRepository.UpdateWarehouse(ctx, warehouse, expectedVersion)
+}
+
+// DeleteWarehouse удаляет и остатки склада, поэтому сбрасывает и цены
+func (r warehouses) DeleteWarehouse(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	defer r.scope.write(ctx, GroupWarehouses, GroupPrices)
+	return r.WarehouseRepository.DeleteWarehouse(ctx, id, expectedVersion)
+}
+
+type products struct {
+	repository.ProductRepository
+	scope *scope
+}
+
+func (r products) GetAll(ctx context.Context, page pagination.Params) (pagination.Page[models.Product], error) {
+	return load(ctx, r.scope, GroupProducts, pageKey(page), func(ctx context.Context) (pagination.Page[models.Product], error) {
+		return r.ProductRepository.GetAll(ctx, page)
+	})
+}
+
+func (r products) Create(ctx context.Context, product models.Product) error {
+	defer r.scope.write(ctx, GroupProducts)
+	return r.ProductRepository.Create(ctx, product)
+}
+
+func (r products) Update(ctx context.Context, product models.Product, expectedVersion int64) error {
+	defer r.scope.write(ctx, GroupProducts)
+	return r.ProductRepository.Update(ctx, product, expectedVersion)
+}
+
+// Delete удаляет и позиции товара на складах, поэтому сбрасывает и цены
+func (r products) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	defer r.scope.write(ctx, GroupProducts, GroupPrices)
+	return r.ProductRepository.Delete(ctx, id, expectedVersion)
+}
+
+// inventory кэширует только цены: остатки меняет каждая покупка
+type inventory struct {
+	repository.InventoryRepository
+	scope *scope
+}
+
+// GetProductPrice в транзакции читает цену из хранилища: по ней покупка
+// списывает деньги и пишет выручку, а кэш может еще хранить цену, изменение
+// которой фиксируется или сбрасывается прямо сейчас
+func (r inventory) GetProductPrice(ctx context.Context, productID, warehouseID uuid.UUID) (*models.InventoryPrice, error) {
+	if r.scope.inTx {
+		return r.InventoryRepository.GetProductPrice(ctx, productID, warehouseID)
+	}
+	return load(ctx, r.scope, GroupPrices, "price:"+warehouseID.String()+":"+productID.String(), func(ctx context.Context) (*models.InventoryPrice, error) {
+		return r.InventoryRepository.GetProductPrice(ctx, productID, warehouseID)
+	})
+}
+
+// Create заменяет цену и скидку существующей позиции
+func (r inventory) Create(ctx context.Context, inv models.Inventory) error {
+	defer r.scope.write(ctx, GroupPrices)
+	return r.InventoryRepository.Create(ctx, inv)
+}
+
+func (r inventory) SetDiscount(ctx context.Context, productIDs []uuid.UUID, warehouseID uuid.UUID, discount float64) error {
+	defer r.scope.write(ctx, GroupPrices)
+	return r.InventoryRepository.SetDiscount(ctx, productIDs, warehouseID, discount)
+}
+
+func (r inventory) DeleteProductFromWarehouse(ctx context.Context, warehouseID uuid.UUID, productID uuid.UUID, expectedVersion int64) error {
+	defer r.scope.write(ctx, GroupPrices)
+	return r.InventoryRepository.DeleteProductFromWarehouse(ctx, warehouseID, productID, expectedVersion)
+}
+
+func (r inventory) DeleteInventory(ctx context.Context, inventoryID uuid.UUID, expectedVersion int64) error {
+	defer r.scope.write(ctx, GroupPrices)
+	return r.InventoryRepository.DeleteInventory(ctx, inventoryID, expectedVersion)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrDuplicateBarcode возвращается при попытке сохранить товар со штрихкодом,
+// который уже занят другим товаром.
+var ErrDuplicateBarcode = errors.New("barcode already exists")
+
+// Нарушения ограничений, которые репозитории переводят в свои ошибки,
+// чтобы все хранилища возвращали одно и то же
+var constraintErrors = map[string]error{
+	"products_barcode_key":        ErrDuplicateBarcode,
+	"inventory_product_id_fkey":   ErrProductNotFound,
+	"inventory_warehouse_id_fkey": ErrWarehouseNotFound,
+	"inventory_quantity_check":    ErrInsufficientStock,
+	"analytics_product_id_fkey":   ErrProductNotFound,
+	"analytics_warehouse_id_fkey": ErrWarehouseNotFound,
+	"sales_warehouse_id_fkey":     ErrWarehouseNotFound,
+}
+
+// translateConstraint заменяет известное нарушение ограничения PostgreSQL
+// ошибкой репозитория; остальные ошибки возвращаются как есть
+func translateConstraint(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if mapped, ok := constraintErrors[pgErr.ConstraintName]; ok {
+		return mapped
+	}
+	return err
+}
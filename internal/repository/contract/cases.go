@@ -0,0 +1,1039 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// expect проверяет, что операция op вернула ошибку target (nil — успех)
+func expect(op string, err, target error) error {
+	if target == nil && err != nil {
+		return fmt.Errorf("%s: unexpected error: %w", op, err)
+	}
+	if !errors.Is(err, target) {
+		return fmt.Errorf("%s: got error %v, want %v", op, err, target)
+	}
+	return nil
+}
+
+func newWarehouse(ctx context.Context, b Backend, name string) (uuid.UUID, error) {
+	id, err := b.Repos.Warehouses.CreateWarehouse(ctx, name, name+" street")
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("create warehouse %s: %w", name, err)
+	}
+	return id, nil
+}
+
+func newProduct(ctx context.Context, b Backend, name, barcode string) (uuid.UUID, error) {
+	id := uuid.New()
+	err := b.Repos.Products.Create(ctx, models.Product{ID: id.String(), Name: name, Weight: 1, Barcode: barcode})
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("create product %s: %w", name, err)
+	}
+	return id, nil
+}
+
+func stock(ctx context.Context, b Backend, productID, warehouseID uuid.UUID, quantity int, price string) error {
+	err := b.Repos.Inventory.Create(ctx, models.Inventory{
+		ProductID:   productID,
+		WarehouseID: warehouseID,
+		Quantity:    quantity,
+		Price:       decimal.RequireFromString(price),
+	})
+	if err != nil {
+		return fmt.Errorf("stock product %s: %w", productID, err)
+	}
+	return nil
+}
+
+func quantityOf(ctx context.Context, b Backend, productID, warehouseID uuid.UUID) (int, error) {
+	inv, err := b.Repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID)
+	if err != nil {
+		return 0, fmt.Errorf("get inventory: %w", err)
+	}
+	return inv.Quantity, nil
+}
+
+func warehouseLifecycle(ctx context.Context, b Backend) error {
+	repo := b.Repos.Warehouses
+	id, err := newWarehouse(ctx, b, "North")
+	if err != nil {
+		return err
+	}
+
+	w, err := repo.GetWarehouseByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get warehouse: %w", err)
+	}
+	if w.Name != "North" || w.Address != "North street" || w.Version != 1 {
+		return fmt.Errorf("created warehouse = %+v", *w)
+	}
+
+	w.Description = "cold storage"
+	if err := expect("update with stale version", repo.UpdateWarehouse(ctx, *w, 7), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	if err := expect("update", repo.UpdateWarehouse(ctx, *w, 1), nil); err != nil {
+		return err
+	}
+	if w, err = repo.GetWarehouseByID(ctx, id); err != nil {
+		return fmt.Errorf("get updated warehouse: %w", err)
+	}
+	if w.Description != "cold storage" || w.Version != 2 {
+		return fmt.Errorf("updated warehouse = %+v", *w)
+	}
+	if err := expect("update without version check", repo.UpdateWarehouse(ctx, *w, repository.AnyVersion), nil); err != nil {
+		return err
+	}
+
+	missing := models.Warehouse{ID: uuid.New(), Address: "nowhere"}
+	if err := expect("update missing", repo.UpdateWarehouse(ctx, missing, repository.AnyVersion), repository.ErrWarehouseNotFound); err != nil {
+		return err
+	}
+	if err := expect("delete with stale version", repo.DeleteWarehouse(ctx, id, 1), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	if err := expect("delete", repo.DeleteWarehouse(ctx, id, 3), nil); err != nil {
+		return err
+	}
+	if err := expect("delete missing", repo.DeleteWarehouse(ctx, id, repository.AnyVersion), repository.ErrWarehouseNotFound); err != nil {
+		return err
+	}
+	_, err = repo.GetWarehouseByID(ctx, id)
+	return expect("get deleted", err, repository.ErrWarehouseNotFound)
+}
+
+func warehousePagination(ctx context.Context, b Backend) error {
+	for _, name := range []string{"A", "B", "C"} {
+		if _, err := newWarehouse(ctx, b, name); err != nil {
+			return err
+		}
+	}
+
+	first, err := b.Repos.Warehouses.GetAllWarehouses(ctx, pagination.Params{Limit: 2, IncludeTotal: true})
+	if err != nil {
+		return fmt.Errorf("first page: %w", err)
+	}
+	if len(first.Items) != 2 || first.NextCursor == "" || first.Total == nil || *first.Total != 3 {
+		return fmt.Errorf("first page: %d items, cursor %q, total %v", len(first.Items), first.NextCursor, first.Total)
+	}
+
+	cursor, err := pagination.DecodeCursor(first.NextCursor)
+	if err != nil {
+		return fmt.Errorf("decode cursor: %w", err)
+	}
+	second, err := b.Repos.Warehouses.GetAllWarehouses(ctx, pagination.Params{Limit: 2, After: &cursor})
+	if err != nil {
+		return fmt.Errorf("second page: %w", err)
+	}
+	if len(second.Items) != 1 || second.NextCursor != "" {
+		return fmt.Errorf("second page: %d items, cursor %q", len(second.Items), second.NextCursor)
+	}
+	for _, w := range first.Items {
+		if w.ID == second.Items[0].ID {
+			return fmt.Errorf("warehouse %s is on both pages", w.ID)
+		}
+	}
+	return nil
+}
+
+func productLifecycle(ctx context.Context, b Backend) error {
+	repo := b.Repos.Products
+	id := uuid.New().String()
+	product := models.Product{ID: id, Name: "Kettle", Description: "1.7 l", Weight: 1.2, Barcode: "4600000000011",
+		Attributes: map[string]string{"color": "white"}}
+	if err := repo.Create(ctx, product); err != nil {
+		return fmt.Errorf("create product: %w", err)
+	}
+
+	got, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get product: %w", err)
+	}
+	if got.Name != "Kettle" || got.Description != "1.7 l" || got.Attributes["color"] != "white" || got.Version != 1 {
+		return fmt.Errorf("created product = %+v", *got)
+	}
+
+	// Полная замена: пустые описание и атрибуты сбрасываются
+	got.Description = ""
+	got.Attributes = nil
+	if err := expect("update with stale version", repo.Update(ctx, *got, 2), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	if err := expect("update", repo.Update(ctx, *got, 1), nil); err != nil {
+		return err
+	}
+	if got, err = repo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("get updated product: %w", err)
+	}
+	if got.Description != "" || got.Attributes == nil || len(got.Attributes) != 0 || got.Version != 2 {
+		return fmt.Errorf("updated product = %+v", *got)
+	}
+
+	if err := expect("delete with stale version", repo.Delete(ctx, id, 1), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	if err := expect("delete", repo.Delete(ctx, id, 2), nil); err != nil {
+		return err
+	}
+	_, err = repo.GetByID(ctx, id)
+	if err := expect("get deleted", err, repository.ErrProductNotFound); err != nil {
+		return err
+	}
+	return expect("delete missing", repo.Delete(ctx, id, repository.AnyVersion), repository.ErrProductNotFound)
+}
+
+func uniqueBarcode(ctx context.Context, b Backend) error {
+	first, err := newProduct(ctx, b, "First", "4600000000028")
+	if err != nil {
+		return err
+	}
+	err = b.Repos.Products.Create(ctx, models.Product{ID: uuid.New().String(), Name: "Copy", Barcode: "4600000000028"})
+	if err := expect("create with taken barcode", err, repository.ErrDuplicateBarcode); err != nil {
+		return err
+	}
+
+	second, err := newProduct(ctx, b, "Second", "4600000000035")
+	if err != nil {
+		return err
+	}
+	err = b.Repos.Products.Update(ctx, models.Product{ID: second.String(), Name: "Second", Barcode: "4600000000028"}, repository.AnyVersion)
+	if err := expect("update to taken barcode", err, repository.ErrDuplicateBarcode); err != nil {
+		return err
+	}
+	// Товар может сохранить свой собственный штрихкод
+	err = b.Repos.Products.Update(ctx, models.Product{ID: first.String(), Name: "Renamed", Barcode: "4600000000028"}, repository.AnyVersion)
+	return expect("update keeping own barcode", err, nil)
+}
+
+func inventoryUpsert(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Upsert")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Lamp", "4600000000042")
+	if err != nil {
+		return err
+	}
+
+	if err := stock(ctx, b, productID, warehouseID, 5, "10.00"); err != nil {
+		return err
+	}
+	err = b.Repos.Inventory.Create(ctx, models.Inventory{
+		ProductID: productID, WarehouseID: warehouseID, Quantity: 3,
+		Price: decimal.RequireFromString("12.50"), Discount: decimal.NewFromInt(10),
+	})
+	if err != nil {
+		return fmt.Errorf("restock: %w", err)
+	}
+
+	inv, err := b.Repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID)
+	if err != nil {
+		return fmt.Errorf("get inventory: %w", err)
+	}
+	if inv.Quantity != 8 || !inv.Price.Equal(decimal.RequireFromString("12.5")) || !inv.Discount.Equal(decimal.NewFromInt(10)) || inv.Version != 2 {
+		return fmt.Errorf("restocked inventory = %+v", *inv)
+	}
+
+	if err := b.Repos.Inventory.SetDiscount(ctx, []uuid.UUID{productID}, warehouseID, 25); err != nil {
+		return fmt.Errorf("set discount: %w", err)
+	}
+	if inv, err = b.Repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID); err != nil {
+		return fmt.Errorf("get discounted inventory: %w", err)
+	}
+	if !inv.Discount.Equal(decimal.NewFromInt(25)) || inv.Version != 3 {
+		return fmt.Errorf("discounted inventory = %+v", *inv)
+	}
+
+	_, err = b.Repos.Inventory.GetProductInWarehouse(ctx, uuid.New(), warehouseID)
+	return expect("get missing position", err, repository.ErrInventoryNotFound)
+}
+
+func inventoryReferences(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Refs")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Desk", "4600000000059")
+	if err != nil {
+		return err
+	}
+
+	err = stock(ctx, b, uuid.New(), warehouseID, 1, "1")
+	if err := expect("stock unknown product", err, repository.ErrProductNotFound); err != nil {
+		return err
+	}
+	err = stock(ctx, b, productID, uuid.New(), 1, "1")
+	if err := expect("stock in unknown warehouse", err, repository.ErrWarehouseNotFound); err != nil {
+		return err
+	}
+	err = b.Repos.Analytics.RecordSale(ctx, uuid.New(), productID, 1, decimal.NewFromInt(1))
+	if err := expect("record sale in unknown warehouse", err, repository.ErrWarehouseNotFound); err != nil {
+		return err
+	}
+	err = b.Repos.Analytics.RecordSale(ctx, warehouseID, uuid.New(), 1, decimal.NewFromInt(1))
+	return expect("record sale of unknown product", err, repository.ErrProductNotFound)
+}
+
+func stockNeverNegative(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Stock")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Chair", "4600000000066")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, productID, warehouseID, 2, "40"); err != nil {
+		return err
+	}
+
+	err = b.Repos.Inventory.UpdateQuantity(ctx, productID, warehouseID, -3, repository.AnyVersion)
+	if err := expect("write off more than stock", err, repository.ErrInsufficientStock); err != nil {
+		return err
+	}
+	err = b.Repos.Inventory.Create(ctx, models.Inventory{ProductID: productID, WarehouseID: warehouseID, Quantity: -3, Price: decimal.NewFromInt(40)})
+	if err := expect("restock with negative quantity", err, repository.ErrInsufficientStock); err != nil {
+		return err
+	}
+	err = b.Repos.Inventory.Purchase(ctx, warehouseID, map[uuid.UUID]int{productID: 3})
+	if err := expect("purchase more than stock", err, repository.ErrInsufficientStock); err != nil {
+		return err
+	}
+	err = b.Repos.Inventory.UpdateQuantity(ctx, uuid.New(), warehouseID, 1, repository.AnyVersion)
+	if err := expect("update missing position", err, repository.ErrInventoryNotFound); err != nil {
+		return err
+	}
+
+	if err := b.Repos.Inventory.Purchase(ctx, warehouseID, map[uuid.UUID]int{productID: 2}); err != nil {
+		return fmt.Errorf("purchase whole stock: %w", err)
+	}
+	quantity, err := quantityOf(ctx, b, productID, warehouseID)
+	if err != nil {
+		return err
+	}
+	if quantity != 0 {
+		return fmt.Errorf("quantity after purchase = %d, want 0", quantity)
+	}
+	return nil
+}
+
+func purchaseAtomic(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Atomic")
+	if err != nil {
+		return err
+	}
+	plenty, err := newProduct(ctx, b, "Pen", "4600000000073")
+	if err != nil {
+		return err
+	}
+	scarce, err := newProduct(ctx, b, "Ink", "4600000000080")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, plenty, warehouseID, 10, "1"); err != nil {
+		return err
+	}
+	if err := stock(ctx, b, scarce, warehouseID, 1, "2"); err != nil {
+		return err
+	}
+
+	err = b.Repos.Inventory.Purchase(ctx, warehouseID, map[uuid.UUID]int{plenty: 5, scarce: 2})
+	if err := expect("purchase with one short position", err, repository.ErrInsufficientStock); err != nil {
+		return err
+	}
+	for productID, want := range map[uuid.UUID]int{plenty: 10, scarce: 1} {
+		quantity, err := quantityOf(ctx, b, productID, warehouseID)
+		if err != nil {
+			return err
+		}
+		if quantity != want {
+			return fmt.Errorf("quantity of %s after failed purchase = %d, want %d", productID, quantity, want)
+		}
+	}
+	return nil
+}
+
+func concurrentPurchases(ctx context.Context, b Backend) error {
+	const stockLevel, buyers = 10, 25
+
+	warehouseID, err := newWarehouse(ctx, b, "Rush")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Console", "4600000000097")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, productID, warehouseID, stockLevel, "300"); err != nil {
+		return err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		sold     int
+		failures []error
+	)
+	for range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Repos.Inventory.Purchase(ctx, warehouseID, map[uuid.UUID]int{productID: 1})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				sold++
+			case !errors.Is(err, repository.ErrInsufficientStock):
+				failures = append(failures, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("concurrent purchase: %w", errors.Join(failures...))
+	}
+	if sold != stockLevel {
+		return fmt.Errorf("sold %d units, want %d", sold, stockLevel)
+	}
+	quantity, err := quantityOf(ctx, b, productID, warehouseID)
+	if err != nil {
+		return err
+	}
+	if quantity != 0 {
+		return fmt.Errorf("quantity after rush = %d, want 0", quantity)
+	}
+	return nil
+}
+
+// Покупки двух товаров в транзакциях вместе с записью продаж, как в
+// InventoryService.Purchase. Все покупатели конкурируют за одни строки, поэтому
+// на PostgreSQL проверяется, что уровень изоляции и порядок списания не
+// приводят к исчерпанию повторов.
+func concurrentTxPurchases(ctx context.Context, b Backend) error {
+	const stockLevel, buyers = 10, 25
+
+	warehouseID, err := newWarehouse(ctx, b, "Hot")
+	if err != nil {
+		return err
+	}
+	first, err := newProduct(ctx, b, "Gamepad", "4600000000400")
+	if err != nil {
+		return err
+	}
+	second, err := newProduct(ctx, b, "Headset", "4600000000417")
+	if err != nil {
+		return err
+	}
+	for _, productID := range []uuid.UUID{first, second} {
+		if err := stock(ctx, b, productID, warehouseID, stockLevel, "50"); err != nil {
+			return err
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		sold     int
+		failures []error
+	)
+	items := map[uuid.UUID]int{first: 1, second: 1}
+	for range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Tx.WithTx(ctx, func(repos repository.Repositories) error {
+				if err := repos.Inventory.Purchase(ctx, warehouseID, items); err != nil {
+					return err
+				}
+				for productID, quantity := range items {
+					if err := repos.Analytics.RecordSale(ctx, warehouseID, productID, quantity, decimal.RequireFromString("50")); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				sold++
+			case !errors.Is(err, repository.ErrInsufficientStock):
+				failures = append(failures, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("concurrent purchase: %w", errors.Join(failures...))
+	}
+	if sold != stockLevel {
+		return fmt.Errorf("completed %d purchases, want %d", sold, stockLevel)
+	}
+	page, err := b.Repos.Analytics.GetWarehouseAnalytics(ctx, warehouseID, pagination.Params{Limit: 10})
+	if err != nil {
+		return fmt.Errorf("get analytics: %w", err)
+	}
+	for _, row := range page.Items {
+		if row.Quantity != stockLevel {
+			return fmt.Errorf("analytics for %s: sold %d, want %d", row.ProductID, row.Quantity, stockLevel)
+		}
+	}
+	for _, productID := range []uuid.UUID{first, second} {
+		quantity, err := quantityOf(ctx, b, productID, warehouseID)
+		if err != nil {
+			return err
+		}
+		if quantity != 0 {
+			return fmt.Errorf("quantity of %s after rush = %d, want 0", productID, quantity)
+		}
+	}
+	return nil
+}
+
+// Ключ курсора не того типа (подделанный или испорченный) — ошибка курсора
+// (400), а не ошибка базы
+func inventoryMalformedCursor(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Cursor")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Cursor tea", "4600000000220")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, productID, warehouseID, 5, "1.00"); err != nil {
+		return err
+	}
+
+	for _, filter := range []repository.InventoryFilter{{Sort: "quantity"}, {Sort: "price"}, {Sort: "price", Desc: true}} {
+		for _, key := range []string{"abc", "", "1e999999999"} {
+			cursor := pagination.Cursor{ID: uuid.NewString(), Key: key, Sort: filter.CursorSort()}
+			_, err := b.Repos.Inventory.GetByWarehouse(ctx, warehouseID, filter, pagination.Params{Limit: 10, After: &cursor})
+			if err := expect(fmt.Sprintf("sort %s, key %q", filter.CursorSort(), key), err, pagination.ErrInvalidCursor); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func inventoryListing(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Listing")
+	if err != nil {
+		return err
+	}
+	other, err := newWarehouse(ctx, b, "Other")
+	if err != nil {
+		return err
+	}
+
+	positions := []struct {
+		name, barcode, price string
+		quantity             int
+	}{
+		{"Green tea", "4600000000103", "3.50", 10},
+		{"Black tea", "4600000000110", "2.00", 0},
+		{"Coffee", "4600000000127", "7.25", 4},
+	}
+	for _, p := range positions {
+		productID, err := newProduct(ctx, b, p.name, p.barcode)
+		if err != nil {
+			return err
+		}
+		if err := stock(ctx, b, productID, warehouseID, p.quantity, p.price); err != nil {
+			return err
+		}
+		if err := stock(ctx, b, productID, other, 1, p.price); err != nil {
+			return err
+		}
+	}
+
+	list := func(filter repository.InventoryFilter, page pagination.Params) ([]string, string, error) {
+		result, err := b.Repos.Inventory.GetByWarehouse(ctx, warehouseID, filter, page)
+		if err != nil {
+			return nil, "", err
+		}
+		names := make([]string, 0, len(result.Items))
+		for _, inv := range result.Items {
+			if inv.WarehouseName != "Listing" {
+				return nil, "", fmt.Errorf("position %s has warehouse name %q", inv.ID, inv.WarehouseName)
+			}
+			names = append(names, inv.ProductName)
+		}
+		return names, result.NextCursor, nil
+	}
+
+	byPrice := repository.InventoryFilter{Sort: "price", Desc: true}
+	names, next, err := list(byPrice, pagination.Params{Limit: 2})
+	if err != nil {
+		return fmt.Errorf("sort by price: %w", err)
+	}
+	if fmt.Sprint(names) != "[Coffee Green tea]" || next == "" {
+		return fmt.Errorf("first page by price desc = %v, cursor %q", names, next)
+	}
+	cursor, err := pagination.DecodeCursor(next)
+	if err != nil {
+		return fmt.Errorf("decode cursor: %w", err)
+	}
+	if names, next, err = list(byPrice, pagination.Params{Limit: 2, After: &cursor}); err != nil {
+		return fmt.Errorf("second page by price: %w", err)
+	}
+	if fmt.Sprint(names) != "[Black tea]" || next != "" {
+		return fmt.Errorf("second page by price desc = %v, cursor %q", names, next)
+	}
+
+	_, _, err = list(repository.InventoryFilter{Sort: "quantity"}, pagination.Params{Limit: 2, After: &cursor})
+	if err := expect("cursor from another sort", err, pagination.ErrInvalidCursor); err != nil {
+		return err
+	}
+	_, _, err = list(repository.InventoryFilter{Sort: "weight"}, pagination.Params{Limit: 2})
+	if err := expect("sort by unknown field", err, repository.ErrInvalidSort); err != nil {
+		return err
+	}
+
+	inStock := true
+	minPrice := decimal.NewFromInt(3)
+	filter := repository.InventoryFilter{Sort: "product_name", InStock: &inStock, MinPrice: &minPrice}
+	if names, _, err = list(filter, pagination.Params{Limit: 10}); err != nil {
+		return fmt.Errorf("filter: %w", err)
+	}
+	if fmt.Sprint(names) != "[Coffee Green tea]" {
+		return fmt.Errorf("in stock from 3.00 by name = %v", names)
+	}
+	if names, _, err = list(repository.InventoryFilter{Search: "TEA"}, pagination.Params{Limit: 10}); err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	if len(names) != 2 {
+		return fmt.Errorf("search for TEA = %v", names)
+	}
+
+	result, err := b.Repos.Inventory.GetByWarehouse(ctx, warehouseID, repository.InventoryFilter{}, pagination.Params{Limit: 1, IncludeTotal: true})
+	if err != nil {
+		return fmt.Errorf("total: %w", err)
+	}
+	if result.Total == nil || *result.Total != 3 {
+		return fmt.Errorf("total = %v, want 3", result.Total)
+	}
+	return nil
+}
+
+func inventoryVersions(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Versions")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Shelf", "4600000000134")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, productID, warehouseID, 1, "15"); err != nil {
+		return err
+	}
+
+	repo := b.Repos.Inventory
+	if err := expect("update with stale version", repo.UpdateQuantity(ctx, productID, warehouseID, 1, 5), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	if err := expect("update", repo.UpdateQuantity(ctx, productID, warehouseID, 1, 1), nil); err != nil {
+		return err
+	}
+	if err := expect("delete with stale version", repo.DeleteProductFromWarehouse(ctx, warehouseID, productID, 1), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	inv, err := repo.GetProductInWarehouse(ctx, productID, warehouseID)
+	if err != nil {
+		return fmt.Errorf("get inventory: %w", err)
+	}
+	if err := expect("delete by id with stale version", repo.DeleteInventory(ctx, inv.ID, 1), repository.ErrVersionMismatch); err != nil {
+		return err
+	}
+	if err := expect("delete by id", repo.DeleteInventory(ctx, inv.ID, inv.Version), nil); err != nil {
+		return err
+	}
+	if err := expect("delete missing by id", repo.DeleteInventory(ctx, inv.ID, repository.AnyVersion), repository.ErrInventoryNotFound); err != nil {
+		return err
+	}
+	return expect("delete missing", repo.DeleteProductFromWarehouse(ctx, warehouseID, productID, repository.AnyVersion), repository.ErrInventoryNotFound)
+}
+
+func analyticsAccumulate(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Sales")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Mug", "4600000000141")
+	if err != nil {
+		return err
+	}
+
+	repo := b.Repos.Analytics
+	for _, sale := range []struct {
+		quantity int
+		total    string
+	}{{2, "9.90"}, {3, "14.85"}} {
+		if err := repo.RecordSale(ctx, warehouseID, productID, sale.quantity, decimal.RequireFromString(sale.total)); err != nil {
+			return fmt.Errorf("record sale: %w", err)
+		}
+	}
+
+	page, err := repo.GetWarehouseAnalytics(ctx, warehouseID, pagination.Params{Limit: 10, IncludeTotal: true})
+	if err != nil {
+		return fmt.Errorf("get analytics: %w", err)
+	}
+	if len(page.Items) != 1 || page.Total == nil || *page.Total != 1 {
+		return fmt.Errorf("analytics has %d rows, total %v, want one row per product", len(page.Items), page.Total)
+	}
+	if a := page.Items[0]; a.Quantity != 5 || !a.TotalSum.Equal(decimal.RequireFromString("24.75")) || a.ProductID != productID {
+		return fmt.Errorf("accumulated analytics = %+v", a)
+	}
+
+	if err := repo.DeleteAnalytics(ctx, warehouseID, productID); err != nil {
+		return fmt.Errorf("delete analytics: %w", err)
+	}
+	if page, err = repo.GetWarehouseAnalytics(ctx, warehouseID, pagination.Params{Limit: 10}); err != nil {
+		return fmt.Errorf("get analytics after delete: %w", err)
+	}
+	if len(page.Items) != 0 {
+		return fmt.Errorf("analytics after delete has %d rows", len(page.Items))
+	}
+	return nil
+}
+
+func topWarehouses(ctx context.Context, b Backend) error {
+	productID, err := newProduct(ctx, b, "Rug", "4600000000158")
+	if err != nil {
+		return err
+	}
+	revenue := map[string]string{"Small": "10", "Large": "500", "Medium": "120"}
+	ids := map[string]uuid.UUID{}
+	for name, total := range revenue {
+		id, err := newWarehouse(ctx, b, name)
+		if err != nil {
+			return err
+		}
+		ids[name] = id
+		if err := b.Repos.Analytics.RecordSale(ctx, id, productID, 1, decimal.RequireFromString(total)); err != nil {
+			return fmt.Errorf("record sale: %w", err)
+		}
+	}
+
+	if err := b.Repos.Reports.RefreshRevenue(ctx); err != nil {
+		return fmt.Errorf("refresh revenue: %w", err)
+	}
+	report, err := b.Repos.Reports.TopWarehouses(ctx, 2)
+	if err != nil {
+		return fmt.Errorf("top warehouses: %w", err)
+	}
+	top := report.Items
+	if len(top) != 2 || top[0].WarehouseID != ids["Large"] || top[1].WarehouseID != ids["Medium"] {
+		return fmt.Errorf("top 2 warehouses = %+v", top)
+	}
+	if top[0].Address != "Large street" || !top[0].TotalSum.Equal(decimal.NewFromInt(500)) {
+		return fmt.Errorf("top warehouse = %+v", top[0])
+	}
+	return nil
+}
+
+// dailyTotal суммирует выручку склада за вчера, сегодня и завтра (UTC),
+// чтобы проверка не зависела от смены суток во время случая
+func dailyTotal(ctx context.Context, b Backend, warehouseID uuid.UUID) (models.Report[models.DailyRevenue], int64, decimal.Decimal, error) {
+	now := time.Now().UTC()
+	report, err := b.Repos.Reports.DailyRevenue(ctx, warehouseID, now.AddDate(0, 0, -1), now.AddDate(0, 0, 1))
+	if err != nil {
+		return report, 0, decimal.Zero, fmt.Errorf("daily revenue: %w", err)
+	}
+	var units int64
+	revenue := decimal.Zero
+	for _, day := range report.Items {
+		units += day.SoldQuantity
+		revenue = revenue.Add(day.Revenue)
+	}
+	return report, units, revenue, nil
+}
+
+func revenueReports(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Reported")
+	if err != nil {
+		return err
+	}
+	cup, err := newProduct(ctx, b, "Cup", "4600000000189")
+	if err != nil {
+		return err
+	}
+	plate, err := newProduct(ctx, b, "Plate", "4600000000196")
+	if err != nil {
+		return err
+	}
+	reports := b.Repos.Reports
+
+	// До первого обновления отчет пуст и не имеет момента обновления
+	report, err := reports.TopWarehouses(ctx, 10)
+	if err != nil {
+		return fmt.Errorf("top warehouses: %w", err)
+	}
+	if report.AsOf != nil || len(report.Items) != 0 {
+		return fmt.Errorf("report before refresh = %+v", report)
+	}
+
+	sales := []struct {
+		productID uuid.UUID
+		quantity  int
+		total     string
+	}{{cup, 2, "9.90"}, {plate, 1, "5"}}
+	for _, sale := range sales {
+		if err := b.Repos.Analytics.RecordSale(ctx, warehouseID, sale.productID, sale.quantity, decimal.RequireFromString(sale.total)); err != nil {
+			return fmt.Errorf("record sale: %w", err)
+		}
+	}
+	// Повторное обновление не учитывает продажи дважды
+	for range 2 {
+		if err := reports.RefreshRevenue(ctx); err != nil {
+			return fmt.Errorf("refresh revenue: %w", err)
+		}
+	}
+	daily, units, revenue, err := dailyTotal(ctx, b, warehouseID)
+	if err != nil {
+		return err
+	}
+	if daily.AsOf == nil || units != 3 || !revenue.Equal(decimal.RequireFromString("14.90")) {
+		return fmt.Errorf("daily revenue after refresh = %+v", daily)
+	}
+	for _, day := range daily.Items {
+		if _, err := time.Parse(time.DateOnly, day.Date); err != nil {
+			return fmt.Errorf("daily revenue date %q: %w", day.Date, err)
+		}
+	}
+	past := time.Now().UTC().AddDate(0, 0, -30)
+	if old, err := reports.DailyRevenue(ctx, warehouseID, past, past.AddDate(0, 0, 7)); err != nil || len(old.Items) != 0 {
+		return fmt.Errorf("daily revenue for past days = %+v, %v", old, err)
+	}
+
+	// Новая продажа попадает в отчет только после обновления
+	if err := b.Repos.Analytics.RecordSale(ctx, warehouseID, cup, 1, decimal.RequireFromString("4.95")); err != nil {
+		return fmt.Errorf("record sale: %w", err)
+	}
+	if _, _, revenue, err = dailyTotal(ctx, b, warehouseID); err != nil || !revenue.Equal(decimal.RequireFromString("14.90")) {
+		return fmt.Errorf("daily revenue before refresh = %s, %v", revenue, err)
+	}
+	if err := reports.RefreshRevenue(ctx); err != nil {
+		return fmt.Errorf("refresh revenue: %w", err)
+	}
+	if _, units, revenue, err = dailyTotal(ctx, b, warehouseID); err != nil || units != 4 || !revenue.Equal(decimal.RequireFromString("19.85")) {
+		return fmt.Errorf("daily revenue = %d units, %s, %v", units, revenue, err)
+	}
+
+	// Удаленные продажи вычитаются из построенных отчетов
+	if err := b.Repos.Analytics.DeleteAnalytics(ctx, warehouseID, cup); err != nil {
+		return fmt.Errorf("delete analytics: %w", err)
+	}
+	if _, units, revenue, err = dailyTotal(ctx, b, warehouseID); err != nil || units != 1 || !revenue.Equal(decimal.NewFromInt(5)) {
+		return fmt.Errorf("daily revenue after delete = %d units, %s, %v", units, revenue, err)
+	}
+	if report, err = reports.TopWarehouses(ctx, 10); err != nil {
+		return fmt.Errorf("top warehouses: %w", err)
+	}
+	if len(report.Items) != 1 || !report.Items[0].TotalSum.Equal(decimal.NewFromInt(5)) {
+		return fmt.Errorf("top warehouses after delete = %+v", report.Items)
+	}
+	if err := b.Repos.Analytics.DeleteAnalytics(ctx, warehouseID, plate); err != nil {
+		return fmt.Errorf("delete analytics: %w", err)
+	}
+	if report, err = reports.TopWarehouses(ctx, 10); err != nil || len(report.Items) != 0 {
+		return fmt.Errorf("top warehouses without sales = %+v, %v", report.Items, err)
+	}
+	return nil
+}
+
+func stockValueReport(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Valued")
+	if err != nil {
+		return err
+	}
+	empty, err := newWarehouse(ctx, b, "Empty")
+	if err != nil {
+		return err
+	}
+	lamp, err := newProduct(ctx, b, "Lamp", "4600000000202")
+	if err != nil {
+		return err
+	}
+	shade, err := newProduct(ctx, b, "Shade", "4600000000219")
+	if err != nil {
+		return err
+	}
+	// 3 × 10.01 со скидкой 15% = 25.5255 → 25.53; 2 × 7 = 14
+	err = b.Repos.Inventory.Create(ctx, models.Inventory{
+		ProductID: lamp, WarehouseID: warehouseID, Quantity: 3,
+		Price: decimal.RequireFromString("10.01"), Discount: decimal.NewFromInt(15),
+	})
+	if err != nil {
+		return fmt.Errorf("stock product: %w", err)
+	}
+	if err := stock(ctx, b, shade, warehouseID, 2, "7"); err != nil {
+		return err
+	}
+	if err := stock(ctx, b, shade, empty, 0, "7"); err != nil {
+		return err
+	}
+
+	reports := b.Repos.Reports
+	if report, err := reports.StockValue(ctx); err != nil || report.AsOf != nil || len(report.Items) != 0 {
+		return fmt.Errorf("stock value before refresh = %+v, %v", report, err)
+	}
+	if err := reports.RefreshStockValue(ctx); err != nil {
+		return fmt.Errorf("refresh stock value: %w", err)
+	}
+	report, err := reports.StockValue(ctx)
+	if err != nil {
+		return fmt.Errorf("stock value: %w", err)
+	}
+	if report.AsOf == nil || len(report.Items) != 2 {
+		return fmt.Errorf("stock value = %+v", report)
+	}
+	if v := report.Items[0]; v.WarehouseID != warehouseID || v.Units != 5 || !v.Value.Equal(decimal.RequireFromString("39.53")) {
+		return fmt.Errorf("stock value of warehouse = %+v", v)
+	}
+	if v := report.Items[1]; v.WarehouseID != empty || v.Units != 0 || !v.Value.IsZero() {
+		return fmt.Errorf("stock value of empty warehouse = %+v", v)
+	}
+
+	// Отчет отражает остатки на момент обновления
+	if err := b.Repos.Inventory.Purchase(ctx, warehouseID, map[uuid.UUID]int{shade: 2}); err != nil {
+		return fmt.Errorf("purchase: %w", err)
+	}
+	if err := reports.RefreshStockValue(ctx); err != nil {
+		return fmt.Errorf("refresh stock value: %w", err)
+	}
+	if report, err = reports.StockValue(ctx); err != nil || report.Items[0].Units != 3 || !report.Items[0].Value.Equal(decimal.RequireFromString("25.53")) {
+		return fmt.Errorf("stock value after purchase = %+v, %v", report.Items, err)
+	}
+	return nil
+}
+
+func warehouseCascade(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Doomed")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Vase", "4600000000165")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, productID, warehouseID, 3, "20"); err != nil {
+		return err
+	}
+	if err := b.Repos.Analytics.RecordSale(ctx, warehouseID, productID, 1, decimal.NewFromInt(20)); err != nil {
+		return fmt.Errorf("record sale: %w", err)
+	}
+	if err := b.Repos.Reports.RefreshRevenue(ctx); err != nil {
+		return fmt.Errorf("refresh revenue: %w", err)
+	}
+
+	if err := b.Repos.Warehouses.DeleteWarehouse(ctx, warehouseID, repository.AnyVersion); err != nil {
+		return fmt.Errorf("delete warehouse: %w", err)
+	}
+	_, err = b.Repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID)
+	if err := expect("get inventory of deleted warehouse", err, repository.ErrInventoryNotFound); err != nil {
+		return err
+	}
+	page, err := b.Repos.Analytics.GetWarehouseAnalytics(ctx, warehouseID, pagination.Params{Limit: 10})
+	if err != nil {
+		return fmt.Errorf("get analytics: %w", err)
+	}
+	if len(page.Items) != 0 {
+		return fmt.Errorf("deleted warehouse still has %d analytics rows", len(page.Items))
+	}
+	top, err := b.Repos.Reports.TopWarehouses(ctx, 10)
+	if err != nil {
+		return fmt.Errorf("top warehouses: %w", err)
+	}
+	if len(top.Items) != 0 {
+		return fmt.Errorf("deleted warehouse still in revenue report: %+v", top.Items)
+	}
+	// Сам товар остается в каталоге
+	_, err = b.Repos.Products.GetByID(ctx, productID.String())
+	return expect("get product", err, nil)
+}
+
+func productCascade(ctx context.Context, b Backend) error {
+	warehouseID, err := newWarehouse(ctx, b, "Keeper")
+	if err != nil {
+		return err
+	}
+	productID, err := newProduct(ctx, b, "Clock", "4600000000172")
+	if err != nil {
+		return err
+	}
+	if err := stock(ctx, b, productID, warehouseID, 3, "20"); err != nil {
+		return err
+	}
+	if err := b.Repos.Analytics.RecordSale(ctx, warehouseID, productID, 1, decimal.NewFromInt(20)); err != nil {
+		return fmt.Errorf("record sale: %w", err)
+	}
+
+	if err := b.Repos.Products.Delete(ctx, productID.String(), repository.AnyVersion); err != nil {
+		return fmt.Errorf("delete product: %w", err)
+	}
+	_, err = b.Repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID)
+	if err := expect("get inventory of deleted product", err, repository.ErrInventoryNotFound); err != nil {
+		return err
+	}
+	page, err := b.Repos.Analytics.GetWarehouseAnalytics(ctx, warehouseID, pagination.Params{Limit: 10})
+	if err != nil {
+		return fmt.Errorf("get analytics: %w", err)
+	}
+	if len(page.Items) != 0 {
+		return fmt.Errorf("deleted product still has %d analytics rows", len(page.Items))
+	}
+	_, err = b.Repos.Warehouses.GetWarehouseByID(ctx, warehouseID)
+	return expect("get warehouse", err, nil)
+}
+
+func transactionRollback(ctx context.Context, b Backend) error {
+	errAbort := errors.New("abort")
+	var rolledBack uuid.UUID
+	err := b.Tx.WithTx(ctx, func(repos repository.Repositories) error {
+		id, err := repos.Warehouses.CreateWarehouse(ctx, "Ghost", "Ghost street")
+		if err != nil {
+			return err
+		}
+		rolledBack = id
+		// Внутри транзакции запись уже видна
+		if _, err := repos.Warehouses.GetWarehouseByID(ctx, id); err != nil {
+			return fmt.Errorf("read own write: %w", err)
+		}
+		return errAbort
+	})
+	if err := expect("failed transaction", err, errAbort); err != nil {
+		return err
+	}
+	_, err = b.Repos.Warehouses.GetWarehouseByID(ctx, rolledBack)
+	if err := expect("get rolled back warehouse", err, repository.ErrWarehouseNotFound); err != nil {
+		return err
+	}
+
+	var committed uuid.UUID
+	err = b.Tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		committed, err = repos.Warehouses.CreateWarehouse(ctx, "Solid", "Solid street")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	_, err = b.Repos.Warehouses.GetWarehouseByID(ctx, committed)
+	return expect("get committed warehouse", err, nil)
+}
@@ -0,0 +1,85 @@
+// Package contract — общий набор проверок поведения репозиториев. Он
+// выполняется на любом хранилище (в памяти, SQLite, PostgreSQL) и, как
+// testing/fstest.TestFS, возвращает ошибку со списком всех нарушений, поэтому
+// из теста достаточно одной строки:
+//
+//	if err := contract.Run(ctx, newBackend); err != nil {
+//		t.Fatal(err)
+//	}
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// Backend — репозитории проверяемого хранилища.
+type Backend struct {
+	Repos repository.Repositories
+	Tx    repository.Transactor
+}
+
+// Factory создает пустое хранилище для одного случая. cleanup (может быть
+// nil) вызывается после случая.
+type Factory func(ctx context.Context) (backend Backend, cleanup func(), err error)
+
+// Case — проверка одного свойства хранилища.
+type Case struct {
+	Name string
+	Run  func(ctx context.Context, b Backend) error
+}
+
+// Cases возвращает все проверки набора.
+func Cases() []Case {
+	return []Case{
+		{"warehouse lifecycle", warehouseLifecycle},
+		{"warehouse pagination", warehousePagination},
+		{"product lifecycle", productLifecycle},
+		{"unique barcode", uniqueBarcode},
+		{"inventory upsert", inventoryUpsert},
+		{"inventory requires product and warehouse", inventoryReferences},
+		{"stock never goes negative", stockNeverNegative},
+		{"purchase is atomic", purchaseAtomic},
+		{"concurrent purchases", concurrentPurchases},
+		{"concurrent purchases in transactions", concurrentTxPurchases},
+		{"inventory filters and sorting", inventoryListing},
+		{"inventory cursor with a malformed key", inventoryMalformedCursor},
+		{"inventory versions", inventoryVersions},
+		{"analytics accumulate sales", analyticsAccumulate},
+		{"top warehouses", topWarehouses},
+		{"revenue reports", revenueReports},
+		{"stock value report", stockValueReport},
+		{"warehouse delete cascades", warehouseCascade},
+		{"product delete cascades", productCascade},
+		{"transaction rollback", transactionRollback},
+	}
+}
+
+// Run выполняет все случаи, каждый на новом хранилище, и объединяет ошибки.
+func Run(ctx context.Context, newBackend Factory) error {
+	var errs []error
+	for _, c := range Cases() {
+		if err := RunCase(ctx, newBackend, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunCase выполняет один случай на новом хранилище.
+func RunCase(ctx context.Context, newBackend Factory, c Case) error {
+	backend, cleanup, err := newBackend(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: create backend: %w", c.Name, err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err := c.Run(ctx, backend); err != nil {
+		return fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository/contract"
+)
+
+// Тот же contract-набор, что для хранилищ в памяти и SQLite, на PostgreSQL:
+// каждый случай получает отдельную базу с примененными миграциями.
+func TestContract(t *testing.T) {
+	factory := pgtest.Shared(t).ContractFactory()
+	for _, c := range contract.Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := contract.RunCase(context.Background(), factory, c); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
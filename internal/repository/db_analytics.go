@@ -3,47 +3,47 @@ package repository
 import (
 	"context"
 
-	"log"
-
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/logging"
 	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
 	"go.uber.org/zap"
 )
 
 type AnalyticsRepository interface {
 	RecordSale(ctx context.Context, warehouseID, productID uuid.UUID, quantity int, totalSum decimal.Decimal) error
-	GetWarehouseAnalytics(ctx context.Context, warehouseID uuid.UUID) ([]models.Analytics, error)
-	GetTopWarehouses(ctx context.Context, limit int) ([]struct {
-		WarehouseID uuid.UUID       `json:"warehouse_id"`
-		Address     string          `json:"address"`
-		TotalSum    decimal.Decimal `json:"total_sum"`
-	}, error)
+	GetWarehouseAnalytics(ctx context.Context, warehouseID uuid.UUID, page pagination.Params) (pagination.Page[models.Analytics], error)
 	DeleteAnalytics(ctx context.Context, warehouseID, productID uuid.UUID) error
 }
 
 type AnalyticsRepositoryImpl struct {
-	db     *pgxpool.Pool
+	db DBTX
+	// read — соединение для отчетов; на пуле с репликой это ReplicaRouter
+	read   DBTX
 	Logger *zap.Logger
 }
 
-func NewAnalyticsRepository(db *pgxpool.Pool, logger *zap.Logger) *AnalyticsRepositoryImpl {
+func NewAnalyticsRepository(db DBTX, logger *zap.Logger) *AnalyticsRepositoryImpl {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
-	return &AnalyticsRepositoryImpl{db: db, Logger: logger}
+	return &AnalyticsRepositoryImpl{db: db, read: db, Logger: logger}
 }
 
-// 1. Запись продажи в аналитику
+// 1. Запись продажи в аналитику и в журнал продаж (для отчетов о выручке)
 func (r *AnalyticsRepositoryImpl) RecordSale(ctx context.Context, warehouseID, productID uuid.UUID, quantity int, totalSum decimal.Decimal) error {
-	r.Logger.Info("Recording sale",
+	logging.FromContext(ctx, r.Logger).Info("Recording sale",
 		zap.String("warehouseID", warehouseID.String()),
 		zap.String("productID", productID.String()),
 		zap.Int("quantity", quantity),
 		zap.String("totalPrice", totalSum.String()))
 
 	_, err := r.db.Exec(ctx, `
+        WITH sale AS (
+            INSERT INTO sales (warehouse_id, product_id, quantity, total_sum) VALUES ($1, $2, $3, $4)
+        )
         INSERT INTO analytics (warehouse_id, product_id, sold_quantity, total_sum) 
         VALUES ($1, $2, $3, $4)
         ON CONFLICT (warehouse_id, product_id) 
@@ -53,28 +53,31 @@ func (r *AnalyticsRepositoryImpl) RecordSale(ctx context.Context, warehouseID, p
     `, warehouseID, productID, quantity, totalSum)
 
 	if err != nil {
-		r.Logger.Error("Failed to execute RecordSale query", zap.Error(err))
+		logging.FromContext(ctx, r.Logger).Error("Failed to execute RecordSale query", zap.Error(err))
 	} else {
-		r.Logger.Info("RecordSale query executed successfully",
+		logging.FromContext(ctx, r.Logger).Info("RecordSale query executed successfully",
 			zap.String("warehouseID", warehouseID.String()),
 			zap.String("productID", productID.String()),
 			zap.Int("quantity", quantity),
 			zap.String("totalSum", totalSum.String()))
 	}
-	return err
+	return translateConstraint(err)
 }
 
-// 2. Получение аналитики по складу
-func (r *AnalyticsRepositoryImpl) GetWarehouseAnalytics(ctx context.Context, warehouseID uuid.UUID) ([]models.Analytics, error) {
-	r.Logger.Info("Executing analytics query", zap.String("warehouseId", warehouseID.String()))
+// 2. Получение аналитики по складу (keyset-пагинация по id)
+func (r *AnalyticsRepositoryImpl) GetWarehouseAnalytics(ctx context.Context, warehouseID uuid.UUID, page pagination.Params) (pagination.Page[models.Analytics], error) {
+	logging.FromContext(ctx, r.Logger).Info("Executing analytics query", zap.String("warehouseId", warehouseID.String()))
 
-	rows, err := r.db.Query(ctx, `
+	rows, err := r.read.Query(ctx, `
 		SELECT id, warehouse_id, product_id, sold_quantity, total_sum 
-		FROM analytics WHERE warehouse_id = $1`, warehouseID)
+		FROM analytics
+		WHERE warehouse_id = $1 AND ($2::text = '' OR id > $2::uuid)
+		ORDER BY id
+		LIMIT $3`, warehouseID, page.AfterID(), page.Limit+1)
 
 	if err != nil {
-		log.Println("Error executing query:", err)
-		return nil, err
+		logging.FromContext(ctx, r.Logger).Error("Error executing analytics query", zap.Error(err))
+		return pagination.Page[models.Analytics]{}, err
 	}
 	defer rows.Close()
 
@@ -82,65 +85,69 @@ func (r *AnalyticsRepositoryImpl) GetWarehouseAnalytics(ctx context.Context, war
 	for rows.Next() {
 		var a models.Analytics
 		if err := rows.Scan(&a.ID, &a.WarehouseID, &a.ProductID, &a.Quantity, &a.TotalSum); err != nil {
-			log.Println("Error scanning row:", err)
-			return nil, err
+			logging.FromContext(ctx, r.Logger).Error("Error scanning analytics row", zap.Error(err))
+			return pagination.Page[models.Analytics]{}, err
 		}
 		analytics = append(analytics, a)
 	}
-	log.Println("Returning analytics data:", analytics)
-	return analytics, nil
-}
-
-// 3. Топ-10 складов по выручке
-func (r *AnalyticsRepositoryImpl) GetTopWarehouses(ctx context.Context, limit int) ([]struct {
-	WarehouseID uuid.UUID       `json:"warehouse_id"`
-	Address     string          `json:"address"`
-	TotalSum    decimal.Decimal `json:"total_sum"`
-}, error) {
-	r.Logger.Info("Executing query to fetch top warehouses by revenue", zap.Int("limit", limit))
-
-	rows, err := r.db.Query(ctx, `
-		SELECT w.id, w.address, COALESCE(SUM(a.total_sum), 0) AS total_sum
-		FROM analytics a
-		JOIN warehouses w ON a.warehouse_id = w.id
-		GROUP BY w.id, w.address
-		ORDER BY total_sum DESC
-		LIMIT $1
-	`, limit)
-	if err != nil {
-		r.Logger.Error("Failed to execute query for top warehouses", zap.Error(err))
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.Analytics]{}, err
 	}
-	defer rows.Close()
 
-	var results []struct {
-		WarehouseID uuid.UUID       `json:"warehouse_id"`
-		Address     string          `json:"address"`
-		TotalSum    decimal.Decimal `json:"total_sum"`
-	}
-	for rows.Next() {
-		var res struct {
-			WarehouseID uuid.UUID       `json:"warehouse_id"`
-			Address     string          `json:"address"`
-			TotalSum    decimal.Decimal `json:"total_sum"`
-		}
-		if err := rows.Scan(&res.WarehouseID, &res.Address, &res.TotalSum); err != nil {
-			r.Logger.Error("Error scanning row for top warehouses", zap.Error(err))
-			return nil, err
+	result := pagination.NewPage(analytics, page, func(a models.Analytics) pagination.Cursor {
+		return pagination.Cursor{ID: a.ID.String()}
+	})
+	if page.IncludeTotal {
+		var total int64
+		err := r.read.QueryRow(ctx, `SELECT count(*) FROM analytics WHERE warehouse_id = $1`, warehouseID).Scan(&total)
+		if err != nil {
+			return pagination.Page[models.Analytics]{}, err
 		}
-		results = append(results, res)
+		result.Total = &total
 	}
-	return results, nil
+	return result, nil
 }
 
+// 3. Удаление продаж товара на складе: из аналитики, журнала и уже
+// построенных отчетов о выручке (учтенные продажи вычитаются)
 func (r *AnalyticsRepositoryImpl) DeleteAnalytics(ctx context.Context, warehouseID, productID uuid.UUID) error {
-	r.Logger.Info("Deleting analytics data",
+	logging.FromContext(ctx, r.Logger).Info("Deleting analytics data",
 		zap.String("warehouseID", warehouseID.String()),
 		zap.String("productID", productID.String()))
 
-	_, err := r.db.Exec(ctx, `
-		DELETE FROM analytics 
-		WHERE warehouse_id = $1 AND product_id = $2
-	`, warehouseID, productID)
-	return err
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM analytics 
+			WHERE warehouse_id = $1 AND product_id = $2
+		`, warehouseID, productID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			WITH removed AS (
+				DELETE FROM sales
+				WHERE warehouse_id = $1 AND product_id = $2
+				RETURNING (sold_at AT TIME ZONE 'UTC')::date AS day, quantity, total_sum, aggregated
+			),
+			daily AS (
+				UPDATE report_daily_revenue d
+				SET sold_quantity = d.sold_quantity - r.quantity, revenue = d.revenue - r.total_sum
+				FROM (SELECT day, SUM(quantity) AS quantity, SUM(total_sum) AS total_sum FROM removed WHERE aggregated GROUP BY day) r
+				WHERE d.warehouse_id = $1 AND d.day = r.day
+			)
+			UPDATE report_warehouse_revenue t
+			SET sold_quantity = t.sold_quantity - r.quantity, revenue = t.revenue - r.total_sum
+			FROM (SELECT SUM(quantity) AS quantity, SUM(total_sum) AS total_sum FROM removed WHERE aggregated) r
+			WHERE t.warehouse_id = $1 AND r.quantity IS NOT NULL
+		`, warehouseID, productID)
+		if err != nil {
+			return err
+		}
+		// Дни и склады без продаж убираются из отчетов
+		if _, err := tx.Exec(ctx, "DELETE FROM report_daily_revenue WHERE warehouse_id = $1 AND sold_quantity <= 0", warehouseID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM report_warehouse_revenue WHERE warehouse_id = $1 AND sold_quantity <= 0", warehouseID)
+		return err
+	})
 }
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yourusername/warehouse-service/internal/models"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, name, subject, keyHash string) (*models.APIKey, error)
+	GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+}
+
+type APIKeyRepositoryImpl struct {
+	db DBTX
+}
+
+var _ APIKeyRepository = (*APIKeyRepositoryImpl)(nil)
+
+func NewAPIKeyRepository(db DBTX) *APIKeyRepositoryImpl {
+	return &APIKeyRepositoryImpl{db: db}
+}
+
+// 1. Создание ключа (хранится только хэш)
+func (r *APIKeyRepositoryImpl) Create(ctx context.Context, name, subject, keyHash string) (*models.APIKey, error) {
+	key := models.APIKey{Name: name, Subject: subject}
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO api_keys (name, subject, key_hash) VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, name, subject, keyHash).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// apiKeyTouchInterval — как часто обновляется last_used_at. Ключ
+// проверяется на каждом запросе, и запись при каждой проверке блокировала бы
+// строку ключа, выстраивая в очередь все запросы с ним.
+const apiKeyTouchInterval = time.Minute
+
+// 2. Поиск действующего ключа по хэшу. Время использования обновляется не
+// чаще apiKeyTouchInterval: в остальных случаях это только чтение
+func (r *APIKeyRepositoryImpl) GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.QueryRow(ctx, `
+		WITH found AS (
+			SELECT id, name, subject, created_at, last_used_at
+			FROM api_keys
+			WHERE key_hash = $1 AND revoked_at IS NULL
+		), touched AS (
+			UPDATE api_keys SET last_used_at = now()
+			FROM found
+			WHERE api_keys.id = found.id
+				AND (api_keys.last_used_at IS NULL OR api_keys.last_used_at < now() - $2::interval)
+			RETURNING api_keys.last_used_at
+		)
+		SELECT id, name, subject, created_at, COALESCE((SELECT last_used_at FROM touched), last_used_at)
+		FROM found
+	`, keyHash, apiKeyTouchInterval).Scan(&key.ID, &key.Name, &key.Subject, &key.CreatedAt, &key.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// 3. Отзыв ключа
+func (r *APIKeyRepositoryImpl) Revoke(ctx context.Context, id uuid.UUID) error {
+	commandTag, err := r.db.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if commandTag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+func TestAPIKeyLastUsedIsThrottled(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewTestDatabase(t)
+	keys := repository.NewAPIKeyRepository(pool)
+
+	created, err := keys.Create(ctx, "ci", "user:1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := keys.GetActiveByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != created.ID || first.LastUsedAt == nil {
+		t.Fatalf("first lookup = %+v, want key with last_used_at", first)
+	}
+
+	// Повторная проверка в пределах интервала ничего не пишет
+	second, err := keys.GetActiveByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.LastUsedAt.Equal(*first.LastUsedAt) {
+		t.Fatalf("last_used_at changed within the interval: %v → %v", first.LastUsedAt, second.LastUsedAt)
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE api_keys SET last_used_at = now() - interval '2 minutes' WHERE id = $1`, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	third, err := keys.GetActiveByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !third.LastUsedAt.After(*first.LastUsedAt) {
+		t.Fatalf("last_used_at = %v, want refreshed after the interval", third.LastUsedAt)
+	}
+
+	if err := keys.Revoke(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.GetActiveByHash(ctx, "hash-1"); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		t.Fatalf("revoked key: err = %v, want ErrAPIKeyNotFound", err)
+	}
+}
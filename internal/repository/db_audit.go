@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+)
+
+// AuditFilter — условия выборки журнала аудита; пустые поля не применяются.
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	Actor      string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry models.AuditEntry) error
+	Find(ctx context.Context, filter AuditFilter, page pagination.Params) (pagination.Page[models.AuditEntry], error)
+}
+
+type AuditRepositoryImpl struct {
+	db DBTX
+}
+
+var _ AuditRepository = (*AuditRepositoryImpl)(nil)
+
+func NewAuditRepository(db DBTX) *AuditRepositoryImpl {
+	return &AuditRepositoryImpl{db: db}
+}
+
+// 1. Запись события
+func (r *AuditRepositoryImpl) Record(ctx context.Context, entry models.AuditEntry) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO audit_log (actor, action, entity_type, entity_id, before, after, diff, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+	`, entry.Actor, entry.Action, entry.EntityType, entry.EntityID,
+		nullJSON(entry.Before), nullJSON(entry.After), nullJSON(entry.Diff), entry.RequestID)
+	return err
+}
+
+// 2. Поиск событий (хронологически, keyset-пагинация по времени и id)
+func (r *AuditRepositoryImpl) Find(ctx context.Context, filter AuditFilter, page pagination.Params) (pagination.Page[models.AuditEntry], error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"TRUE"}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = "+arg(filter.EntityType))
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "entity_id = "+arg(filter.EntityID))
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = "+arg(filter.Actor))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "occurred_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "occurred_at < "+arg(*filter.To))
+	}
+
+	var total int64
+	if page.IncludeTotal {
+		err := r.db.QueryRow(ctx, `SELECT count(*) FROM audit_log WHERE `+strings.Join(conditions, " AND "), args...).Scan(&total)
+		if err != nil {
+			return pagination.Page[models.AuditEntry]{}, err
+		}
+	}
+
+	if page.After != nil {
+		after, err := time.Parse(time.RFC3339Nano, page.After.Key)
+		if err != nil {
+			return pagination.Page[models.AuditEntry]{}, pagination.ErrInvalidCursor
+		}
+		conditions = append(conditions, "(occurred_at, id) > ("+arg(after)+", "+arg(page.After.ID)+"::uuid)")
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, occurred_at, actor, action, entity_type, entity_id, before, after, diff, COALESCE(request_id, '')
+		FROM audit_log
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY occurred_at, id
+		LIMIT `+arg(page.Limit+1), args...)
+	if err != nil {
+		return pagination.Page[models.AuditEntry]{}, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &e.Actor, &e.Action, &e.EntityType, &e.EntityID,
+			&e.Before, &e.After, &e.Diff, &e.RequestID); err != nil {
+			return pagination.Page[models.AuditEntry]{}, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.AuditEntry]{}, err
+	}
+
+	result := pagination.NewPage(entries, page, func(e models.AuditEntry) pagination.Cursor {
+		return pagination.Cursor{ID: e.ID.String(), Key: e.OccurredAt.Format(time.RFC3339Nano)}
+	})
+	if page.IncludeTotal {
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// nullJSON превращает пустой JSON в NULL
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
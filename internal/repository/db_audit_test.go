@@ -0,0 +1,47 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// Границы периода с часовым поясом клиента сравниваются как моменты времени
+func TestAuditFindPeriodWithOffset(t *testing.T) {
+	ctx := context.Background()
+	audit := repository.NewAuditRepository(pgtest.NewTestDatabase(t))
+	entry := models.AuditEntry{Actor: "user:1", Action: "create", EntityType: "warehouse", EntityID: "w1"}
+	if err := audit.Record(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d).In(moscow)
+		return &t
+	}
+	tests := []struct {
+		name   string
+		filter repository.AuditFilter
+		want   int
+	}{
+		{"from before", repository.AuditFilter{From: at(-time.Minute)}, 1},
+		{"from after", repository.AuditFilter{From: at(time.Minute)}, 0},
+		{"to after", repository.AuditFilter{To: at(time.Minute)}, 1},
+		{"to before", repository.AuditFilter{To: at(-time.Minute)}, 0},
+	}
+	for _, tt := range tests {
+		page, err := audit.Find(ctx, tt.filter, pagination.Params{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != tt.want {
+			t.Errorf("%s: %d entries, want %d", tt.name, len(page.Items), tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyRecord — сохраненный результат запроса с ключом идемпотентности.
+// Completed = false означает, что первый запрос с этим ключом еще выполняется.
+type IdempotencyRecord struct {
+	Key         string
+	Method      string
+	Path        string
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyLease — сколько действует резерв ключа без сохраненного ответа.
+// Обычно резерв завершается или снимается самим запросом, время которого
+// ограничено write_timeout. Резерв, брошенный при сбое процесса, после
+// этого срока переиспользуется, и повтор не получает 409 до истечения ключа.
+const IdempotencyLease = time.Minute
+
+type IdempotencyRepository interface {
+	// Reserve закрепляет ключ за текущим запросом. Если ключ уже занят
+	// (не истек, а без ответа — моложе IdempotencyLease), возвращается
+	// существующая запись.
+	Reserve(ctx context.Context, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error)
+	Complete(ctx context.Context, record IdempotencyRecord) error
+	Release(ctx context.Context, key, method, path string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type IdempotencyRepositoryImpl struct {
+	db DBTX
+}
+
+var _ IdempotencyRepository = (*IdempotencyRepositoryImpl)(nil)
+
+func NewIdempotencyRepository(db DBTX) *IdempotencyRepositoryImpl {
+	return &IdempotencyRepositoryImpl{db: db}
+}
+
+// 1. Резервирование ключа (истекший ключ и брошенный резерв переиспользуются)
+func (r *IdempotencyRepositoryImpl) Reserve(ctx context.Context, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	var reserved bool
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (key, method, path, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, now() + $5 * interval '1 second')
+		ON CONFLICT (key, method, path) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < now()
+			OR idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < now() - $6 * interval '1 second'
+		RETURNING true
+	`, record.Key, record.Method, record.Path, record.RequestHash, ttl.Seconds(), IdempotencyLease.Seconds()).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	// Ключ занят другим (возможно, еще выполняющимся) запросом
+	existing := IdempotencyRecord{Key: record.Key, Method: record.Method, Path: record.Path}
+	var status *int
+	var contentType *string
+	err = r.db.QueryRow(ctx, `
+		SELECT request_hash, status_code, content_type, response_body
+		FROM idempotency_keys
+		WHERE key = $1 AND method = $2 AND path = $3
+	`, record.Key, record.Method, record.Path).Scan(&existing.RequestHash, &status, &contentType, &existing.Body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Ключ освободили между запросами — повторяем резервирование
+		return r.Reserve(ctx, record, ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		existing.Completed = true
+		existing.StatusCode = *status
+	}
+	if contentType != nil {
+		existing.ContentType = *contentType
+	}
+	return &existing, nil
+}
+
+// 2. Сохранение ответа для повторов
+func (r *IdempotencyRepositoryImpl) Complete(ctx context.Context, record IdempotencyRecord) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2, response_body = $3
+		WHERE key = $4 AND method = $5 AND path = $6
+	`, record.StatusCode, record.ContentType, record.Body, record.Key, record.Method, record.Path)
+	return err
+}
+
+// 3. Освобождение ключа, если запрос завершился ошибкой сервера и его можно повторить
+func (r *IdempotencyRepositoryImpl) Release(ctx context.Context, key, method, path string) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM idempotency_keys WHERE key = $1 AND method = $2 AND path = $3
+	`, key, method, path)
+	return err
+}
+
+// 4. Удаление истекших ключей
+func (r *IdempotencyRepositoryImpl) DeleteExpired(ctx context.Context) (int64, error) {
+	commandTag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return commandTag.RowsAffected(), nil
+}
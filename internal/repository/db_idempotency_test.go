@@ -0,0 +1,50 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// Резерв без ответа, брошенный при сбое, освобождается через
+// IdempotencyLease, а сохраненный ответ действует до истечения ключа
+func TestIdempotencyAbandonedReservation(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewTestDatabase(t)
+	repo := repository.NewIdempotencyRepository(pool)
+
+	record := repository.IdempotencyRecord{Key: "k1", Method: "POST", Path: "/api/inventory", RequestHash: "h1"}
+	if existing, err := repo.Reserve(ctx, record, time.Hour); err != nil || existing != nil {
+		t.Fatalf("first reserve = %+v, %v", existing, err)
+	}
+	existing, err := repo.Reserve(ctx, record, time.Hour)
+	if err != nil || existing == nil || existing.Completed {
+		t.Fatalf("reserve within lease = %+v, %v, want in-progress record", existing, err)
+	}
+
+	age := func() {
+		t.Helper()
+		_, err := pool.Exec(ctx, "UPDATE idempotency_keys SET created_at = created_at - $1 * interval '1 second'",
+			(repository.IdempotencyLease + time.Second).Seconds())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	age()
+	if existing, err := repo.Reserve(ctx, record, time.Hour); err != nil || existing != nil {
+		t.Fatalf("reserve after lease = %+v, %v, want reclaimed", existing, err)
+	}
+
+	record.Completed, record.StatusCode = true, 201
+	if err := repo.Complete(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	age()
+	existing, err = repo.Reserve(ctx, record, time.Hour)
+	if err != nil || existing == nil || !existing.Completed || existing.StatusCode != 201 {
+		t.Fatalf("completed key after lease = %+v, %v, want stored response", existing, err)
+	}
+}
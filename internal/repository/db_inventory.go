@@ -1,37 +1,145 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	//"log"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
 )
 
 type InventoryRepository interface {
 	Create(ctx context.Context, inventory models.Inventory) error
-	UpdateQuantity(ctx context.Context, productID, warehouseID uuid.UUID, quantity int) error
+	UpdateQuantity(ctx context.Context, productID, warehouseID uuid.UUID, quantity int, expectedVersion int64) error
 	SetDiscount(ctx context.Context, productIDs []uuid.UUID, warehouseID uuid.UUID, discount float64) error
-	GetByWarehouse(ctx context.Context, warehouseID uuid.UUID, limit, offset int) ([]models.InventoryWithNames, error)
+	GetByWarehouse(ctx context.Context, warehouseID uuid.UUID, filter InventoryFilter, page pagination.Params) (pagination.Page[models.InventoryWithNames], error)
 	GetProductInWarehouse(ctx context.Context, productID, warehouseID uuid.UUID) (*models.Inventory, error)
-	CalculateTotal(ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) (decimal.Decimal, error)
+	// GetProductPrice возвращает только цену и скидку: их читает каждая
+	// покупка, а меняются они реже остатков, поэтому их можно кэшировать
+	GetProductPrice(ctx context.Context, productID, warehouseID uuid.UUID) (*models.InventoryPrice, error)
 	Purchase(ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) error
-	GetProductPrice(ctx context.Context, warehouseID uuid.UUID, productID uuid.UUID) (float64, error)
-	GetProductDiscount(ctx context.Context, warehouseID, productID uuid.UUID) (float64, error)
-	DeleteProductFromWarehouse(ctx context.Context, warehouseID uuid.UUID, productID uuid.UUID) error
-	DeleteInventory(ctx context.Context, inventoryID uuid.UUID) error
+	DeleteProductFromWarehouse(ctx context.Context, warehouseID uuid.UUID, productID uuid.UUID, expectedVersion int64) error
+	DeleteInventory(ctx context.Context, inventoryID uuid.UUID, expectedVersion int64) error
+}
+
+var (
+	ErrInventoryNotFound = errors.New("inventory record not found")
+	ErrInsufficientStock = errors.New("not enough stock")
+)
+
+// InventoryFilter задает сортировку и фильтры для списка товаров на складе.
+type InventoryFilter struct {
+	Sort       string // quantity, price или product_name; пусто — по id
+	Desc       bool
+	InStock    *bool
+	Discounted *bool
+	MinPrice   *decimal.Decimal
+	MaxPrice   *decimal.Decimal
+	Search     string // подстрока в названии товара
+}
+
+type inventorySortColumn struct {
+	expr string
+	cast string
+	// key — значение колонки в записи из курсора
+	key func(inv models.InventoryWithNames) any
+}
+
+// Разрешенные колонки сортировки: в SQL попадают только значения из этого списка
+var inventorySortColumns = map[string]inventorySortColumn{
+	"quantity":     {expr: "i.quantity", cast: "int", key: func(inv models.InventoryWithNames) any { return inv.Quantity }},
+	"price":        {expr: "i.price", cast: "numeric", key: func(inv models.InventoryWithNames) any { return inv.Price }},
+	"product_name": {expr: "p.name", cast: "text", key: func(inv models.InventoryWithNames) any { return inv.ProductName }},
+}
+
+// ErrInvalidSort возвращается при попытке сортировки по неразрешенной колонке.
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// ValidSort проверяет, что поле сортировки входит в белый список.
+func (f InventoryFilter) ValidSort() bool {
+	if f.Sort == "" {
+		return true
+	}
+	_, ok := inventorySortColumns[f.Sort]
+	return ok
+}
+
+// CursorSort однозначно описывает порядок выдачи, чтобы курсор нельзя было
+// применить к списку с другой сортировкой.
+func (f InventoryFilter) CursorSort() string {
+	if f.Sort == "" {
+		return ""
+	}
+	if f.Desc {
+		return "-" + f.Sort
+	}
+	return f.Sort
+}
+
+// SortKey возвращает значение ключа сортировки записи для курсора.
+func (f InventoryFilter) SortKey(inv models.InventoryWithNames) string {
+	switch f.Sort {
+	case "quantity":
+		return strconv.Itoa(inv.Quantity)
+	case "price":
+		return inv.Price.String()
+	case "product_name":
+		return inv.ProductName
+	}
+	return ""
+}
+
+// CursorPosition восстанавливает из курсора запись, после которой
+// начинается страница (обратно SortKey). Курсор другой сортировки или с
+// ключом не того типа — ErrInvalidCursor, а не ошибка базы.
+func (f InventoryFilter) CursorPosition(cursor pagination.Cursor) (models.InventoryWithNames, error) {
+	var inv models.InventoryWithNames
+	if cursor.Sort != f.CursorSort() {
+		return inv, pagination.ErrInvalidCursor
+	}
+	id, err := uuid.Parse(cursor.ID)
+	if err != nil {
+		return inv, pagination.ErrInvalidCursor
+	}
+	inv.ID = id
+
+	switch f.Sort {
+	case "quantity":
+		inv.Quantity, err = strconv.Atoi(cursor.Key)
+	case "price":
+		// SortKey не пишет экспоненту, а ключ вида 1e999999999 при
+		// пересчете в копейки занял бы всю память
+		if strings.ContainsAny(cursor.Key, "eE") {
+			return models.InventoryWithNames{}, pagination.ErrInvalidCursor
+		}
+		inv.Price, err = decimal.NewFromString(cursor.Key)
+	case "product_name":
+		inv.ProductName = cursor.Key
+	}
+	if err != nil {
+		return models.InventoryWithNames{}, pagination.ErrInvalidCursor
+	}
+	return inv, nil
 }
 
 type InventoryRepositoryImpl struct {
-	db *pgxpool.Pool
+	db DBTX
+	// read — соединение для списков; на пуле с репликой это ReplicaRouter
+	read DBTX
 }
 
-func NewInventoryRepository(db *pgxpool.Pool) *InventoryRepositoryImpl {
-	return &InventoryRepositoryImpl{db: db}
+func NewInventoryRepository(db DBTX) *InventoryRepositoryImpl {
+	return &InventoryRepositoryImpl{db: db, read: db}
 }
 
 // 1. Создание связи товара и склада (указание цены)
@@ -43,56 +151,149 @@ func (r *InventoryRepositoryImpl) Create(ctx context.Context, inventory models.I
 		DO UPDATE SET 
 			quantity = inventory.quantity + EXCLUDED.quantity,
 			price = EXCLUDED.price,
-			discount = EXCLUDED.discount
+			discount = EXCLUDED.discount,
+			version = inventory.version + 1
 	`, inventory.ProductID, inventory.WarehouseID, inventory.Quantity, inventory.Price, inventory.Discount)
-	return err
+	return translateConstraint(err)
 }
 
 // 2. Обновление количества товара (поступление на склад)
 func (r *InventoryRepositoryImpl) UpdateQuantity(
-	ctx context.Context, productID, warehouseID uuid.UUID, quantity int) error {
-	_, err := r.db.Exec(ctx, `
-		UPDATE inventory SET quantity = quantity + $1 WHERE product_id = $2 AND warehouse_id = $3
-	`, quantity, productID, warehouseID)
-	return err
+	ctx context.Context, productID, warehouseID uuid.UUID, quantity int, expectedVersion int64) error {
+	commandTag, err := r.db.Exec(ctx, `
+		UPDATE inventory SET quantity = quantity + $1, version = version + 1
+		WHERE product_id = $2 AND warehouse_id = $3 AND ($4::bigint = 0 OR version = $4)
+	`, quantity, productID, warehouseID, expectedVersion)
+	if err != nil {
+		return translateConstraint(err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return r.missingError(ctx, `SELECT EXISTS(SELECT 1 FROM inventory WHERE product_id = $1 AND warehouse_id = $2)`, productID, warehouseID)
+	}
+	return nil
 }
 
 // 3. Установка скидки на список товаров
 func (r *InventoryRepositoryImpl) SetDiscount(
 	ctx context.Context, productIDs []uuid.UUID, warehouseID uuid.UUID, discount float64) error {
 	_, err := r.db.Exec(ctx, `
-		UPDATE inventory SET discount = $1 WHERE product_id = ANY($2) AND warehouse_id = $3
+		UPDATE inventory SET discount = $1, version = version + 1 WHERE product_id = ANY($2) AND warehouse_id = $3
 	`, discount, productIDs, warehouseID)
 	return err
 }
 
-// 4. Получение списка товаров на складе (с пагинацией)
+// 4. Получение списка товаров на складе (фильтры, сортировка и keyset-пагинация)
 func (r *InventoryRepositoryImpl) GetByWarehouse(
-	ctx context.Context, warehouseID uuid.UUID, limit, offset int) ([]models.InventoryWithNames, error) {
-	rows, err := r.db.Query(ctx, `
+	ctx context.Context, warehouseID uuid.UUID, filter InventoryFilter, page pagination.Params) (pagination.Page[models.InventoryWithNames], error) {
+	if !filter.ValidSort() {
+		return pagination.Page[models.InventoryWithNames]{}, ErrInvalidSort
+	}
+	var after models.InventoryWithNames
+	if page.After != nil {
+		var err error
+		if after, err = filter.CursorPosition(*page.After); err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
+		}
+	}
+
+	args := []any{warehouseID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"i.warehouse_id = $1"}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			conditions = append(conditions, "i.quantity > 0")
+		} else {
+			conditions = append(conditions, "i.quantity = 0")
+		}
+	}
+	if filter.Discounted != nil {
+		if *filter.Discounted {
+			conditions = append(conditions, "COALESCE(i.discount, 0) > 0")
+		} else {
+			conditions = append(conditions, "COALESCE(i.discount, 0) = 0")
+		}
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "i.price >= "+arg(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "i.price <= "+arg(*filter.MaxPrice))
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, "p.name ILIKE '%' || "+arg(escapeLike(filter.Search))+" || '%'")
+	}
+
+	// Общее количество считается по фильтрам без учета курсора
+	var total int64
+	if page.IncludeTotal {
+		err := r.read.QueryRow(ctx, `
+			SELECT count(*)
+			FROM inventory i
+			JOIN products p ON i.product_id = p.id
+			WHERE `+strings.Join(conditions, " AND "), args...).Scan(&total)
+		if err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
+		}
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	orderBy := "i.id " + direction
+	if col, ok := inventorySortColumns[filter.Sort]; ok {
+		orderBy = col.expr + " " + direction + ", " + orderBy
+		if page.After != nil {
+			conditions = append(conditions, fmt.Sprintf("(%s, i.id) %s (%s::%s, %s::uuid)",
+				col.expr, comparison, arg(col.key(after)), col.cast, arg(after.ID)))
+		}
+	} else if page.After != nil {
+		conditions = append(conditions, fmt.Sprintf("i.id %s %s::uuid", comparison, arg(after.ID)))
+	}
+
+	rows, err := r.read.Query(ctx, `
 		SELECT 
-			i.id, i.product_id, i.warehouse_id, i.quantity, i.price, i.discount,
+			i.id, i.product_id, i.warehouse_id, i.quantity, i.price, i.discount, i.version,
 			w.name AS warehouse_name, p.name AS product_name
 		FROM inventory i
 		JOIN warehouses w ON i.warehouse_id = w.id
 		JOIN products p ON i.product_id = p.id
-		WHERE i.warehouse_id = $1
-		LIMIT $2 OFFSET $3
-	`, warehouseID, limit, offset)
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY `+orderBy+`
+		LIMIT `+arg(page.Limit+1), args...)
 	if err != nil {
-		return nil, err
+		return pagination.Page[models.InventoryWithNames]{}, err
 	}
 	defer rows.Close()
 
 	var inventoryList []models.InventoryWithNames
 	for rows.Next() {
 		var inv models.InventoryWithNames
-		if err := rows.Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &inv.Price, &inv.Discount, &inv.WarehouseName, &inv.ProductName); err != nil {
-			return nil, err
+		if err := rows.Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &inv.Price, &inv.Discount, &inv.Version, &inv.WarehouseName, &inv.ProductName); err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
 		}
 		inventoryList = append(inventoryList, inv)
 	}
-	return inventoryList, nil
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.InventoryWithNames]{}, err
+	}
+
+	result := pagination.NewPage(inventoryList, page, func(inv models.InventoryWithNames) pagination.Cursor {
+		return pagination.Cursor{ID: inv.ID.String(), Key: filter.SortKey(inv), Sort: filter.CursorSort()}
+	})
+	if page.IncludeTotal {
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE в пользовательском вводе
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
 // 5. Получение информации о товаре на складе
@@ -100,86 +301,95 @@ func (r *InventoryRepositoryImpl) GetProductInWarehouse(
 	ctx context.Context, productID, warehouseID uuid.UUID) (*models.Inventory, error) {
 	var inv models.Inventory
 	err := r.db.QueryRow(ctx, `
-		SELECT id, product_id, warehouse_id, quantity, price, discount FROM inventory
+		SELECT id, product_id, warehouse_id, quantity, price, discount, version FROM inventory
 		WHERE product_id = $1 AND warehouse_id = $2
-	`, productID, warehouseID).Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &inv.Price, &inv.Discount)
+	`, productID, warehouseID).Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &inv.Price, &inv.Discount, &inv.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInventoryNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &inv, nil
 }
 
-// 6. Подсчёт стоимости товаров
-func (r *InventoryRepositoryImpl) CalculateTotal(
-	ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) (decimal.Decimal, error) {
-
-	var total decimal.Decimal
-	for productID, quantity := range items {
-		var price, discount decimal.Decimal
-		err := r.db.QueryRow(ctx, `
-			SELECT price, discount FROM inventory WHERE product_id = $1 AND warehouse_id = $2
-		`, productID, warehouseID).Scan(&price, &discount)
-		if err != nil {
-			return decimal.Zero, err
-		}
-		total = total.Add(price.Mul(discount.Add(decimal.NewFromInt(100)).Div(decimal.NewFromInt(100))).Mul(decimal.NewFromInt(int64(quantity))))
+// Цена и скидка товара на складе
+func (r *InventoryRepositoryImpl) GetProductPrice(ctx context.Context, productID, warehouseID uuid.UUID) (*models.InventoryPrice, error) {
+	var price models.InventoryPrice
+	err := r.db.QueryRow(ctx, `
+		SELECT price, COALESCE(discount, 0) FROM inventory
+		WHERE product_id = $1 AND warehouse_id = $2
+	`, productID, warehouseID).Scan(&price.Price, &price.Discount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInventoryNotFound
 	}
-	return total, nil
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
 }
 
-// 7. Покупка товаров (уменьшение количества)
+// 6. Покупка товаров (уменьшение количества). Списание всех позиций атомарно:
+// при нехватке любой из них остатки не меняются. Строки блокируются в порядке
+// ID товара, чтобы параллельные покупки одних и тех же товаров не
+// взаимоблокировались
 func (r *InventoryRepositoryImpl) Purchase(
 	ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) error {
 
-	for productID, quantity := range items {
-		result, err := r.db.Exec(ctx, `
-			UPDATE inventory SET quantity = quantity - $1 WHERE product_id = $2 AND warehouse_id = $3 AND quantity >= $1
-		`, quantity, productID, warehouseID)
-		if err != nil {
-			return err
-		}
-		if result.RowsAffected() == 0 {
-			return errors.New("not enough stock for product " + productID.String())
-		}
+	productIDs := make([]uuid.UUID, 0, len(items))
+	for productID := range items {
+		productIDs = append(productIDs, productID)
 	}
-	return nil
+	slices.SortFunc(productIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for _, productID := range productIDs {
+			quantity := items[productID]
+			result, err := tx.Exec(ctx, `
+				UPDATE inventory SET quantity = quantity - $1, version = version + 1
+				WHERE product_id = $2 AND warehouse_id = $3 AND quantity >= $1
+			`, quantity, productID, warehouseID)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected() == 0 {
+				return fmt.Errorf("%w for product %s", ErrInsufficientStock, productID)
+			}
+		}
+		return nil
+	})
 }
 
-func (r *InventoryRepositoryImpl) GetProductPrice(ctx context.Context, warehouseID uuid.UUID, productID uuid.UUID) (float64, error) {
-	var price float64
-	err := r.db.QueryRow(ctx, "SELECT price FROM inventory WHERE warehouse_id = $1 AND product_id = $2",
-		warehouseID, productID).Scan(&price)
+func (r *InventoryRepositoryImpl) DeleteProductFromWarehouse(ctx context.Context, warehouseID, productID uuid.UUID, expectedVersion int64) error {
+	commandTag, err := r.db.Exec(ctx, `
+		DELETE FROM inventory WHERE product_id = $1 AND warehouse_id = $2 AND ($3::bigint = 0 OR version = $3)`,
+		productID, warehouseID, expectedVersion)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	return price, nil
+	if commandTag.RowsAffected() == 0 {
+		return r.missingError(ctx, `SELECT EXISTS(SELECT 1 FROM inventory WHERE product_id = $1 AND warehouse_id = $2)`, productID, warehouseID)
+	}
+	return nil
 }
 
-func (r *InventoryRepositoryImpl) GetProductDiscount(ctx context.Context, warehouseID, productID uuid.UUID) (float64, error) {
-	var discount float64
-	err := r.db.QueryRow(ctx, `
-		SELECT discount 
-		FROM inventory 
-		WHERE warehouse_id = $1 AND product_id = $2
-	`, warehouseID, productID).Scan(&discount)
-
+func (r *InventoryRepositoryImpl) DeleteInventory(ctx context.Context, inventoryID uuid.UUID, expectedVersion int64) error {
+	commandTag, err := r.db.Exec(ctx, `
+		DELETE FROM inventory WHERE id = $1 AND ($2::bigint = 0 OR version = $2)`, inventoryID, expectedVersion)
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return 0, nil // Если скидка отсутствует, возвращаем 0
-		}
-		return 0, err
+		return err
 	}
-	return discount, nil
-}
-
-func (r *InventoryRepositoryImpl) DeleteProductFromWarehouse(ctx context.Context, productID, warehouseID uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `
-		DELETE FROM inventory WHERE product_id = $1 AND warehouse_id = $2`, productID, warehouseID)
-	return err
+	if commandTag.RowsAffected() == 0 {
+		return r.missingError(ctx, `SELECT EXISTS(SELECT 1 FROM inventory WHERE id = $1)`, inventoryID)
+	}
+	return nil
 }
 
-func (r *InventoryRepositoryImpl) DeleteInventory(ctx context.Context, inventoryID uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `
-		DELETE FROM inventory WHERE id = $1`, inventoryID)
-	return err
+// missingError различает отсутствие записи и конфликт версий
+func (r *InventoryRepositoryImpl) missingError(ctx context.Context, existsQuery string, args ...any) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, existsQuery, args...).Scan(&exists); err != nil {
+		return err
+	}
+	return versionError(exists, ErrInventoryNotFound)
 }
@@ -2,84 +2,156 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 
 	"encoding/json"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
 type ProductRepository interface {
-	GetAll(ctx context.Context) ([]models.Product, error)
+	GetAll(ctx context.Context, page pagination.Params) (pagination.Page[models.Product], error)
+	GetByID(ctx context.Context, id string) (*models.Product, error)
 	Create(ctx context.Context, product models.Product) error
-	Update(ctx context.Context, product models.Product) error
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, product models.Product, expectedVersion int64) error
+	Delete(ctx context.Context, id string, expectedVersion int64) error
 }
 
 type ProductRepositoryImpl struct {
-	db *pgxpool.Pool
+	db DBTX
+	// read — соединение для списков; на пуле с репликой это ReplicaRouter
+	read DBTX
 }
 
 var _ ProductRepository = (*ProductRepositoryImpl)(nil)
 
-func NewProductRepository(db *pgxpool.Pool) *ProductRepositoryImpl {
-	return &ProductRepositoryImpl{db: db}
+func NewProductRepository(db DBTX) *ProductRepositoryImpl {
+	return &ProductRepositoryImpl{db: db, read: db}
 }
 
-func (r *ProductRepositoryImpl) GetAll(ctx context.Context) ([]models.Product, error) {
-	rows, err := r.db.Query(ctx, "SELECT id, name, description, attributes, weight, barcode FROM products")
+// GetAll возвращает товары постранично (keyset-пагинация по id)
+func (r *ProductRepositoryImpl) GetAll(ctx context.Context, page pagination.Params) (pagination.Page[models.Product], error) {
+	rows, err := r.read.Query(ctx, `
+		SELECT id, name, description, attributes, weight, barcode, version FROM products
+		WHERE ($1::text = '' OR id > $1::uuid)
+		ORDER BY id
+		LIMIT $2
+	`, page.AfterID(), page.Limit+1)
 	if err != nil {
-		return nil, err
+		return pagination.Page[models.Product]{}, err
 	}
 	defer rows.Close()
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		var attributes []byte
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &attributes, &p.Weight, &p.Barcode); err != nil {
-			return nil, err
+		p, err := scanProduct(rows)
+		if err != nil {
+			return pagination.Page[models.Product]{}, err
 		}
-		if err := json.Unmarshal(attributes, &p.Attributes); err != nil {
-			return nil, err
+		products = append(products, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.Product]{}, err
+	}
+
+	result := pagination.NewPage(products, page, func(p models.Product) pagination.Cursor {
+		return pagination.Cursor{ID: p.ID}
+	})
+	if page.IncludeTotal {
+		var total int64
+		if err := r.read.QueryRow(ctx, "SELECT count(*) FROM products").Scan(&total); err != nil {
+			return pagination.Page[models.Product]{}, err
 		}
-		products = append(products, p)
+		result.Total = &total
 	}
-	return products, nil
+	return result, nil
+}
+
+// GetByID возвращает товар по id
+func (r *ProductRepositoryImpl) GetByID(ctx context.Context, id string) (*models.Product, error) {
+	row := r.db.QueryRow(ctx, "SELECT id, name, description, attributes, weight, barcode, version FROM products WHERE id = $1", id)
+	p, err := scanProduct(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	return p, err
+}
+
+func scanProduct(row pgx.Row) (*models.Product, error) {
+	var p models.Product
+	var description sql.NullString // description может быть NULL
+	var attributes []byte
+	if err := row.Scan(&p.ID, &p.Name, &description, &attributes, &p.Weight, &p.Barcode, &p.Version); err != nil {
+		return nil, err
+	}
+	p.Description = description.String
+	if err := json.Unmarshal(attributes, &p.Attributes); err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
 
 func (r *ProductRepositoryImpl) Create(ctx context.Context, product models.Product) error {
-	_, err := r.db.Exec(ctx, "INSERT INTO products (id, name, description, attributes, weight, barcode) VALUES ($1, $2, $3, $4, $5, $6)",
+	if product.Attributes == nil {
+		product.Attributes = map[string]string{}
+	}
+	_, err := r.db.Exec(ctx, "INSERT INTO products (id, name, description, attributes, weight, barcode) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)",
 		product.ID, product.Name, product.Description, product.Attributes, product.Weight, product.Barcode)
-	return err
+	return translateConstraint(err)
 }
 
-func (r *ProductRepositoryImpl) Update(ctx context.Context, product models.Product) error {
+// Update полностью заменяет данные товара: пустое описание сохраняется как NULL,
+// нулевой вес и пустой набор атрибутов записываются как есть.
+// expectedVersion = AnyVersion отключает проверку версии.
+func (r *ProductRepositoryImpl) Update(ctx context.Context, product models.Product, expectedVersion int64) error {
+	if product.Attributes == nil {
+		product.Attributes = map[string]string{}
+	}
 	query := `
 		UPDATE products
 		SET 
-			name = COALESCE(NULLIF($1, ''), name),
-			description = COALESCE(NULLIF($2, ''), description),
-			attributes = COALESCE($3, attributes),
-			weight = COALESCE(NULLIF($4, 0), weight),
-			barcode = COALESCE(NULLIF($5, ''), barcode)
-		WHERE id = $6
+			name = $1,
+			description = NULLIF($2, ''),
+			attributes = $3,
+			weight = $4,
+			barcode = $5,
+			version = version + 1
+		WHERE id = $6 AND ($7::bigint = 0 OR version = $7)
 	`
-	_, err := r.db.Exec(ctx, query,
+	commandTag, err := r.db.Exec(ctx, query,
 		product.Name, product.Description, product.Attributes,
-		product.Weight, product.Barcode, product.ID,
+		product.Weight, product.Barcode, product.ID, expectedVersion,
 	)
-	return err
+	if err != nil {
+		return translateConstraint(err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return r.missingError(ctx, product.ID)
+	}
+	return nil
 }
 
-func (r *ProductRepositoryImpl) Delete(ctx context.Context, id string) error {
-	commandTag, err := r.db.Exec(ctx, "DELETE FROM products WHERE id=$1", id)
+func (r *ProductRepositoryImpl) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	commandTag, err := r.db.Exec(ctx, "DELETE FROM products WHERE id=$1 AND ($2::bigint = 0 OR version = $2)", id, expectedVersion)
 	if err != nil {
 		return err
 	}
 	if commandTag.RowsAffected() == 0 {
-		return errors.New("product not found")
+		return r.missingError(ctx, id)
 	}
 	return nil
 }
+
+// missingError различает отсутствие товара и конфликт версий
+func (r *ProductRepositoryImpl) missingError(ctx context.Context, id string) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", id).Scan(&exists); err != nil {
+		return err
+	}
+	return versionError(exists, ErrProductNotFound)
+}
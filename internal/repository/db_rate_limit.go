@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+)
+
+// RateLimitRepositoryImpl хранит корзины ограничителя в Postgres, чтобы
+// квоты клиента были общими для всех реплик сервиса.
+type RateLimitRepositoryImpl struct {
+	db DBTX
+}
+
+var (
+	_ ratelimit.Store   = (*RateLimitRepositoryImpl)(nil)
+	_ ratelimit.Expirer = (*RateLimitRepositoryImpl)(nil)
+)
+
+func NewRateLimitRepository(db DBTX) *RateLimitRepositoryImpl {
+	return &RateLimitRepositoryImpl{db: db}
+}
+
+// 1. Расход токена. Строка корзины блокируется на время пересчета, время
+// берется из базы, чтобы расхождение часов реплик не влияло на квоты.
+func (r *RateLimitRepositoryImpl) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var result ratelimit.Result
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var bucket ratelimit.Bucket
+		var now time.Time
+		err := tx.QueryRow(ctx, `
+			SELECT tokens, updated_at, now() FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+		`, key).Scan(&bucket.Tokens, &bucket.Updated, &now)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if err := tx.QueryRow(ctx, `SELECT now()`).Scan(&now); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		result = bucket.Take(now, limit)
+		// Конкурентная вставка той же корзины разрешается в пользу более
+		// позднего состояния: в худшем случае клиент получит один лишний запрос
+		_, err = tx.Exec(ctx, `
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (key) DO UPDATE
+			SET tokens = EXCLUDED.tokens, updated_at = EXCLUDED.updated_at, expires_at = EXCLUDED.expires_at
+		`, key, bucket.Tokens, bucket.Updated, now.Add(result.Reset))
+		return err
+	})
+	return result, err
+}
+
+// 2. Удаление полных корзин: они неотличимы от отсутствующих
+func (r *RateLimitRepositoryImpl) DeleteExpired(ctx context.Context) (int64, error) {
+	commandTag, err := r.db.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return commandTag.RowsAffected(), nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"go.uber.org/zap"
+)
+
+// Имена отчетов в report_state
+const (
+	ReportRevenue    = "revenue"
+	ReportStockValue = "stock_value"
+)
+
+// ReportRepository — предрассчитанные отчеты. Они обновляются фоновой
+// задачей (или по запросу) и читаются без агрегации по analytics и
+// inventory; каждый ответ содержит момент последнего обновления.
+type ReportRepository interface {
+	// RefreshRevenue переносит в отчеты о выручке продажи, записанные после
+	// прошлого обновления
+	RefreshRevenue(ctx context.Context) error
+	// RefreshStockValue пересчитывает стоимость остатков всех складов
+	RefreshStockValue(ctx context.Context) error
+	// TopWarehouses — склады с наибольшей выручкой
+	TopWarehouses(ctx context.Context, limit int) (models.Report[models.WarehouseRevenue], error)
+	// DailyRevenue — выручка склада по дням с from по to включительно (UTC)
+	DailyRevenue(ctx context.Context, warehouseID uuid.UUID, from, to time.Time) (models.Report[models.DailyRevenue], error)
+	StockValue(ctx context.Context) (models.Report[models.StockValue], error)
+}
+
+type ReportRepositoryImpl struct {
+	db DBTX
+	// read — соединение для чтения отчетов; на пуле с репликой это ReplicaRouter
+	read   DBTX
+	Logger *zap.Logger
+}
+
+var _ ReportRepository = (*ReportRepositoryImpl)(nil)
+
+func NewReportRepository(db DBTX, logger *zap.Logger) *ReportRepositoryImpl {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ReportRepositoryImpl{db: db, read: db, Logger: logger}
+}
+
+// 1. Инкрементальное обновление выручки: еще не учтенные продажи
+// помечаются и прибавляются к отчетам одним запросом. Продажи без даты
+// (перенесенные из analytics) входят только в выручку за все время.
+// Конкурирующие обновления (несколько реплик сервиса) не учтут продажу
+// дважды: строки sales блокируются, а условие NOT aggregated перепроверяется.
+func (r *ReportRepositoryImpl) RefreshRevenue(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, `
+			WITH batch AS (
+				UPDATE sales SET aggregated = true
+				WHERE NOT aggregated
+				RETURNING warehouse_id, (sold_at AT TIME ZONE 'UTC')::date AS day, quantity, total_sum
+			),
+			daily AS (
+				INSERT INTO report_daily_revenue (warehouse_id, day, sold_quantity, revenue)
+				SELECT warehouse_id, day, SUM(quantity), SUM(total_sum) FROM batch WHERE day IS NOT NULL GROUP BY warehouse_id, day
+				ON CONFLICT (warehouse_id, day) DO UPDATE SET
+					sold_quantity = report_daily_revenue.sold_quantity + EXCLUDED.sold_quantity,
+					revenue = report_daily_revenue.revenue + EXCLUDED.revenue
+			)
+			INSERT INTO report_warehouse_revenue (warehouse_id, sold_quantity, revenue)
+			SELECT warehouse_id, SUM(quantity), SUM(total_sum) FROM batch GROUP BY warehouse_id
+			ON CONFLICT (warehouse_id) DO UPDATE SET
+				sold_quantity = report_warehouse_revenue.sold_quantity + EXCLUDED.sold_quantity,
+				revenue = report_warehouse_revenue.revenue + EXCLUDED.revenue
+		`)
+		if err != nil {
+			return err
+		}
+		logging.FromContext(ctx, r.Logger).Debug("Revenue reports refreshed", zap.Int64("warehouses", commandTag.RowsAffected()))
+		_, err = tx.Exec(ctx, "UPDATE report_state SET as_of = now() WHERE name = $1", ReportRevenue)
+		return err
+	})
+}
+
+// 2. Полный пересчет стоимости остатков. Блокировка строки report_state
+// выстраивает конкурирующие пересчеты в очередь.
+func (r *ReportRepositoryImpl) RefreshStockValue(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT 1 FROM report_state WHERE name = $1 FOR UPDATE", ReportStockValue); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM report_stock_value"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO report_stock_value (warehouse_id, units, stock_value)
+			SELECT warehouse_id, SUM(quantity), SUM(ROUND(price * (100 - COALESCE(discount, 0)) / 100 * quantity, 2))
+			FROM inventory
+			GROUP BY warehouse_id
+		`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "UPDATE report_state SET as_of = now() WHERE name = $1", ReportStockValue)
+		return err
+	})
+}
+
+// asOf возвращает момент последнего обновления отчета
+func (r *ReportRepositoryImpl) asOf(ctx context.Context, name string) (*time.Time, error) {
+	var asOf *time.Time
+	err := r.read.QueryRow(ctx, "SELECT as_of FROM report_state WHERE name = $1", name).Scan(&asOf)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return asOf, err
+}
+
+// 3. Топ складов по выручке
+func (r *ReportRepositoryImpl) TopWarehouses(ctx context.Context, limit int) (models.Report[models.WarehouseRevenue], error) {
+	asOf, err := r.asOf(ctx, ReportRevenue)
+	if err != nil {
+		return models.Report[models.WarehouseRevenue]{}, err
+	}
+	rows, err := r.read.Query(ctx, `
+		SELECT w.id, w.address, rr.revenue
+		FROM report_warehouse_revenue rr
+		JOIN warehouses w ON rr.warehouse_id = w.id
+		ORDER BY rr.revenue DESC, rr.warehouse_id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		logging.FromContext(ctx, r.Logger).Error("Failed to execute query for top warehouses", zap.Error(err))
+		return models.Report[models.WarehouseRevenue]{}, err
+	}
+	defer rows.Close()
+
+	report := models.Report[models.WarehouseRevenue]{AsOf: asOf}
+	for rows.Next() {
+		var res models.WarehouseRevenue
+		if err := rows.Scan(&res.WarehouseID, &res.Address, &res.TotalSum); err != nil {
+			return models.Report[models.WarehouseRevenue]{}, err
+		}
+		report.Items = append(report.Items, res)
+	}
+	return report, rows.Err()
+}
+
+// 4. Выручка склада по дням
+func (r *ReportRepositoryImpl) DailyRevenue(ctx context.Context, warehouseID uuid.UUID, from, to time.Time) (models.Report[models.DailyRevenue], error) {
+	asOf, err := r.asOf(ctx, ReportRevenue)
+	if err != nil {
+		return models.Report[models.DailyRevenue]{}, err
+	}
+	rows, err := r.read.Query(ctx, `
+		SELECT day, sold_quantity, revenue
+		FROM report_daily_revenue
+		WHERE warehouse_id = $1 AND day BETWEEN $2::date AND $3::date
+		ORDER BY day
+	`, warehouseID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return models.Report[models.DailyRevenue]{}, err
+	}
+	defer rows.Close()
+
+	report := models.Report[models.DailyRevenue]{AsOf: asOf}
+	for rows.Next() {
+		var res models.DailyRevenue
+		var day time.Time
+		if err := rows.Scan(&day, &res.SoldQuantity, &res.Revenue); err != nil {
+			return models.Report[models.DailyRevenue]{}, err
+		}
+		res.Date = day.Format(time.DateOnly)
+		report.Items = append(report.Items, res)
+	}
+	return report, rows.Err()
+}
+
+// 5. Стоимость остатков по складам (по убыванию)
+func (r *ReportRepositoryImpl) StockValue(ctx context.Context) (models.Report[models.StockValue], error) {
+	asOf, err := r.asOf(ctx, ReportStockValue)
+	if err != nil {
+		return models.Report[models.StockValue]{}, err
+	}
+	rows, err := r.read.Query(ctx, `
+		SELECT warehouse_id, units, stock_value
+		FROM report_stock_value
+		ORDER BY stock_value DESC, warehouse_id
+	`)
+	if err != nil {
+		return models.Report[models.StockValue]{}, err
+	}
+	defer rows.Close()
+
+	report := models.Report[models.StockValue]{AsOf: asOf}
+	for rows.Next() {
+		var res models.StockValue
+		if err := rows.Scan(&res.WarehouseID, &res.Units, &res.Value); err != nil {
+			return models.Report[models.StockValue]{}, err
+		}
+		report.Items = append(report.Items, res)
+	}
+	return report, rows.Err()
+}
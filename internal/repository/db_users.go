@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yourusername/warehouse-service/internal/models"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, username string) (*models.User, error)
+	GetUsers(ctx context.Context) ([]models.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	// GetUserBySubject ищет пользователя по id или username из токена/API-ключа
+	GetUserBySubject(ctx context.Context, subject string) (*models.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	SetRoles(ctx context.Context, id uuid.UUID, roles []string) error
+	SetWarehouses(ctx context.Context, id uuid.UUID, warehouseIDs []uuid.UUID) error
+}
+
+type UserRepositoryImpl struct {
+	db DBTX
+}
+
+var _ UserRepository = (*UserRepositoryImpl)(nil)
+
+func NewUserRepository(db DBTX) *UserRepositoryImpl {
+	return &UserRepositoryImpl{db: db}
+}
+
+// Роли и склады собираются в массивы, чтобы получить пользователя одним запросом
+const selectUsers = `
+	SELECT u.id, u.username, u.created_at,
+		COALESCE((SELECT array_agg(role ORDER BY role) FROM user_roles WHERE user_id = u.id), '{}'),
+		COALESCE((SELECT array_agg(warehouse_id ORDER BY warehouse_id) FROM user_warehouses WHERE user_id = u.id), '{}')
+	FROM users u`
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var u models.User
+	if err := row.Scan(&u.ID, &u.Username, &u.CreatedAt, &u.Roles, &u.WarehouseIDs); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// 1. Создание пользователя
+func (r *UserRepositoryImpl) CreateUser(ctx context.Context, username string) (*models.User, error) {
+	u := models.User{Username: username, Roles: []string{}, WarehouseIDs: []uuid.UUID{}}
+	err := r.db.QueryRow(ctx, `INSERT INTO users (username) VALUES ($1) RETURNING id, created_at`, username).
+		Scan(&u.ID, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// 2. Список пользователей
+func (r *UserRepositoryImpl) GetUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.Query(ctx, selectUsers+` ORDER BY u.username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, rows.Err()
+}
+
+// 3. Пользователь по id
+func (r *UserRepositoryImpl) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, selectUsers+` WHERE u.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+// 4. Пользователь по subject аутентификации
+func (r *UserRepositoryImpl) GetUserBySubject(ctx context.Context, subject string) (*models.User, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, selectUsers+` WHERE u.id::text = $1 OR u.username = $1`, subject))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+// 5. Удаление пользователя (роли и склады удаляются каскадно)
+func (r *UserRepositoryImpl) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	commandTag, err := r.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if commandTag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// 6. Замена набора ролей
+func (r *UserRepositoryImpl) SetRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := lockUser(ctx, tx, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM user_roles WHERE user_id = $1`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_roles (user_id, role) SELECT $1, unnest($2::text[])
+		`, id, roles)
+		return err
+	})
+}
+
+// 7. Замена набора закрепленных складов
+func (r *UserRepositoryImpl) SetWarehouses(ctx context.Context, id uuid.UUID, warehouseIDs []uuid.UUID) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := lockUser(ctx, tx, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM user_warehouses WHERE user_id = $1`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_warehouses (user_id, warehouse_id) SELECT $1, unnest($2::uuid[])
+		`, id, warehouseIDs)
+		return err
+	})
+}
+
+func lockUser(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	var locked uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
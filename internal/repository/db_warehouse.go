@@ -7,28 +7,30 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
 )
 
 var ErrWarehouseNotFound = errors.New("warehouse not found")
 
 type WarehouseRepository interface {
 	CreateWarehouse(ctx context.Context, name string, address string) (uuid.UUID, error)
-	GetAllWarehouses(ctx context.Context) ([]models.Warehouse, error)
-	UpdateWarehouse(ctx context.Context, id uuid.UUID, newAddress string) error
-	DeleteWarehouse(ctx context.Context, id uuid.UUID) error
+	GetAllWarehouses(ctx context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error)
+	UpdateWarehouse(ctx context.Context, warehouse models.Warehouse, expectedVersion int64) error
+	DeleteWarehouse(ctx context.Context, id uuid.UUID, expectedVersion int64) error
 	GetWarehouseByID(ctx context.Context, id uuid.UUID) (*models.Warehouse, error)
 }
 
 type WarehouseRepositoryImpl struct {
-	db *pgxpool.Pool
+	db DBTX
+	// read — соединение для списков; на пуле с репликой это ReplicaRouter
+	read DBTX
 }
 
 var _ WarehouseRepository = (*WarehouseRepositoryImpl)(nil)
 
-func NewWarehouseRepository(db *pgxpool.Pool) *WarehouseRepositoryImpl {
-	return &WarehouseRepositoryImpl{db: db}
+func NewWarehouseRepository(db DBTX) *WarehouseRepositoryImpl {
+	return &WarehouseRepositoryImpl{db: db, read: db}
 }
 
 // Добавить склад
@@ -41,11 +43,16 @@ func (r *WarehouseRepositoryImpl) CreateWarehouse(ctx context.Context, name stri
 	return id, nil
 }
 
-// Получить все склады
-func (r *WarehouseRepositoryImpl) GetAllWarehouses(ctx context.Context) ([]models.Warehouse, error) {
-	rows, err := r.db.Query(ctx, "SELECT id, name, address, description FROM warehouses")
+// Получить склады постранично (keyset-пагинация по id)
+func (r *WarehouseRepositoryImpl) GetAllWarehouses(ctx context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error) {
+	rows, err := r.read.Query(ctx, `
+		SELECT id, name, address, description, version FROM warehouses
+		WHERE ($1::text = '' OR id > $1::uuid)
+		ORDER BY id
+		LIMIT $2
+	`, page.AfterID(), page.Limit+1)
 	if err != nil {
-		return nil, err
+		return pagination.Page[models.Warehouse]{}, err
 	}
 	defer rows.Close()
 
@@ -53,48 +60,76 @@ func (r *WarehouseRepositoryImpl) GetAllWarehouses(ctx context.Context) ([]model
 	for rows.Next() {
 		var w models.Warehouse
 		var description sql.NullString // Используем sql.NullString для обработки NULL
-		if err := rows.Scan(&w.ID, &w.Name, &w.Address, &description); err != nil {
-			return nil, err
+		if err := rows.Scan(&w.ID, &w.Name, &w.Address, &description, &w.Version); err != nil {
+			return pagination.Page[models.Warehouse]{}, err
 		}
 		w.Description = description.String // Преобразуем в строку
 		warehouses = append(warehouses, w)
 	}
-	return warehouses, nil
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.Warehouse]{}, err
+	}
+
+	result := pagination.NewPage(warehouses, page, func(w models.Warehouse) pagination.Cursor {
+		return pagination.Cursor{ID: w.ID.String()}
+	})
+	if page.IncludeTotal {
+		var total int64
+		if err := r.read.QueryRow(ctx, "SELECT count(*) FROM warehouses").Scan(&total); err != nil {
+			return pagination.Page[models.Warehouse]{}, err
+		}
+		result.Total = &total
+	}
+	return result, nil
 }
 
-// Обновить склад
-func (r *WarehouseRepositoryImpl) UpdateWarehouse(ctx context.Context, id uuid.UUID, newAddress string) error {
-	commandTag, err := r.db.Exec(ctx, "UPDATE warehouses SET address = $1 WHERE id = $2", newAddress, id)
+// Обновить склад (полная замена названия, адреса и описания).
+// expectedVersion = AnyVersion отключает проверку версии.
+func (r *WarehouseRepositoryImpl) UpdateWarehouse(ctx context.Context, warehouse models.Warehouse, expectedVersion int64) error {
+	commandTag, err := r.db.Exec(ctx, `
+		UPDATE warehouses
+		SET name = $1, address = $2, description = NULLIF($3, ''), version = version + 1
+		WHERE id = $4 AND ($5::bigint = 0 OR version = $5)`,
+		warehouse.Name, warehouse.Address, warehouse.Description, warehouse.ID, expectedVersion)
 	if err != nil {
 		return err
 	}
 
 	if commandTag.RowsAffected() == 0 {
-		return ErrWarehouseNotFound
+		return r.missingError(ctx, warehouse.ID)
 	}
 
 	return nil
 }
 
 // Удалить склад
-func (r *WarehouseRepositoryImpl) DeleteWarehouse(ctx context.Context, id uuid.UUID) error {
-	commandTag, err := r.db.Exec(ctx, "DELETE FROM warehouses WHERE id = $1", id)
+func (r *WarehouseRepositoryImpl) DeleteWarehouse(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	commandTag, err := r.db.Exec(ctx, "DELETE FROM warehouses WHERE id = $1 AND ($2::bigint = 0 OR version = $2)", id, expectedVersion)
 	if err != nil {
 		return err
 	}
 
 	if commandTag.RowsAffected() == 0 {
-		return ErrWarehouseNotFound
+		return r.missingError(ctx, id)
 	}
 
 	return nil
 }
 
+// missingError различает отсутствие склада и конфликт версий
+func (r *WarehouseRepositoryImpl) missingError(ctx context.Context, id uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = $1)", id).Scan(&exists); err != nil {
+		return err
+	}
+	return versionError(exists, ErrWarehouseNotFound)
+}
+
 func (r *WarehouseRepositoryImpl) GetWarehouseByID(ctx context.Context, id uuid.UUID) (*models.Warehouse, error) {
 	var warehouse models.Warehouse
 	var description sql.NullString // Используем sql.NullString для обработки NULL
-	query := `SELECT id, name, address, description FROM warehouses WHERE id = $1`
-	err := r.db.QueryRow(ctx, query, id).Scan(&warehouse.ID, &warehouse.Name, &warehouse.Address, &description)
+	query := `SELECT id, name, address, description, version FROM warehouses WHERE id = $1`
+	err := r.db.QueryRow(ctx, query, id).Scan(&warehouse.ID, &warehouse.Name, &warehouse.Address, &description, &warehouse.Version)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrWarehouseNotFound
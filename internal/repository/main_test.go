@@ -0,0 +1,12 @@
+package repository_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/pgtest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
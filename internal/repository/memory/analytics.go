@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type analyticsRepository struct {
+	db db
+}
+
+var _ repository.AnalyticsRepository = analyticsRepository{}
+
+// 1. Запись продажи: продажи товара на складе накапливаются в одной записи,
+// а в журнал продаж добавляется новая
+func (r analyticsRepository) RecordSale(_ context.Context, warehouseID, productID uuid.UUID, quantity int, totalSum decimal.Decimal) error {
+	return r.db.update(func(s *state) error {
+		if _, ok := s.warehouses[warehouseID]; !ok {
+			return repository.ErrWarehouseNotFound
+		}
+		if _, ok := s.products[productID.String()]; !ok {
+			return repository.ErrProductNotFound
+		}
+
+		s.nextSaleID++
+		s.sales[s.nextSaleID] = sale{warehouseID: warehouseID, productID: productID, quantity: quantity, total: totalSum.Round(2), soldAt: time.Now()}
+
+		for id, a := range s.analytics {
+			if a.WarehouseID == warehouseID && a.ProductID == productID {
+				a.Quantity += quantity
+				a.TotalSum = a.TotalSum.Add(totalSum).Round(2)
+				s.analytics[id] = a
+				return nil
+			}
+		}
+		id := uuid.New()
+		s.analytics[id] = models.Analytics{
+			ID:          id,
+			WarehouseID: warehouseID,
+			ProductID:   productID,
+			Quantity:    quantity,
+			TotalSum:    totalSum.Round(2),
+		}
+		return nil
+	})
+}
+
+// 2. Аналитика по складу (keyset-пагинация по id)
+func (r analyticsRepository) GetWarehouseAnalytics(_ context.Context, warehouseID uuid.UUID, page pagination.Params) (pagination.Page[models.Analytics], error) {
+	after, err := afterID(page)
+	if err != nil {
+		return pagination.Page[models.Analytics]{}, err
+	}
+
+	var analytics []models.Analytics
+	_ = r.db.view(func(s *state) error {
+		for _, a := range s.analytics {
+			if a.WarehouseID == warehouseID {
+				analytics = append(analytics, a)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(analytics, func(a, b models.Analytics) int { return compareIDs(a.ID, b.ID) })
+
+	return paginate(analytics, page,
+		func(a models.Analytics) bool { return compareIDs(a.ID, *after) > 0 },
+		func(a models.Analytics) pagination.Cursor { return pagination.Cursor{ID: a.ID.String()} },
+	), nil
+}
+
+// 3. Удаление продаж товара на складе: из аналитики, журнала и уже
+// построенных отчетов о выручке (учтенные продажи вычитаются)
+func (r analyticsRepository) DeleteAnalytics(_ context.Context, warehouseID, productID uuid.UUID) error {
+	return r.db.update(func(s *state) error {
+		deleteWhere(s.analytics, func(a models.Analytics) bool {
+			return a.WarehouseID == warehouseID && a.ProductID == productID
+		})
+		for id, sl := range s.sales {
+			if sl.warehouseID != warehouseID || sl.productID != productID {
+				continue
+			}
+			delete(s.sales, id)
+			if !sl.aggregated {
+				continue
+			}
+			units, total := -int64(sl.quantity), sl.total.Neg()
+			key := dailyKey{warehouseID, sl.day()}
+			if s.dailyRevenue[key] = s.dailyRevenue[key].add(units, total); s.dailyRevenue[key].units <= 0 {
+				delete(s.dailyRevenue, key)
+			}
+			if s.warehouseRevenue[warehouseID] = s.warehouseRevenue[warehouseID].add(units, total); s.warehouseRevenue[warehouseID].units <= 0 {
+				delete(s.warehouseRevenue, warehouseID)
+			}
+		}
+		return nil
+	})
+}
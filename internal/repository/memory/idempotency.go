@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// IdempotencyRepository хранит ключи идемпотентности в памяти процесса.
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]idempotencyEntry
+	now     func() time.Time
+}
+
+type idempotencyKey struct {
+	key, method, path string
+}
+
+type idempotencyEntry struct {
+	record     repository.IdempotencyRecord
+	reservedAt time.Time
+	expiresAt  time.Time
+}
+
+// taken сообщает, что ключ занят: не истек, а без ответа — резерв моложе
+// repository.IdempotencyLease
+func (e idempotencyEntry) taken(now time.Time) bool {
+	if e.expiresAt.Before(now) {
+		return false
+	}
+	return e.record.Completed || now.Sub(e.reservedAt) <= repository.IdempotencyLease
+}
+
+var _ repository.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: map[idempotencyKey]idempotencyEntry{}, now: time.Now}
+}
+
+// Reserve закрепляет ключ за запросом; истекший ключ и брошенный резерв
+// переиспользуются
+func (r *IdempotencyRepository) Reserve(_ context.Context, record repository.IdempotencyRecord, ttl time.Duration) (*repository.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := idempotencyKey{record.Key, record.Method, record.Path}
+	now := r.now()
+	if existing, ok := r.records[key]; ok && existing.taken(now) {
+		found := existing.record
+		return &found, nil
+	}
+	r.records[key] = idempotencyEntry{
+		record: repository.IdempotencyRecord{
+			Key:         record.Key,
+			Method:      record.Method,
+			Path:        record.Path,
+			RequestHash: record.RequestHash,
+		},
+		reservedAt: now,
+		expiresAt:  now.Add(ttl),
+	}
+	return nil, nil
+}
+
+func (r *IdempotencyRepository) Complete(_ context.Context, record repository.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := idempotencyKey{record.Key, record.Method, record.Path}
+	entry, ok := r.records[key]
+	if !ok {
+		return nil
+	}
+	entry.record.Completed = true
+	entry.record.StatusCode = record.StatusCode
+	entry.record.ContentType = record.ContentType
+	entry.record.Body = append([]byte(nil), record.Body...)
+	r.records[key] = entry
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(_ context.Context, key, method, path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, idempotencyKey{key, method, path})
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired(_ context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	now := r.now()
+	for key, entry := range r.records {
+		if entry.expiresAt.Before(now) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// Резерв без ответа, брошенный при сбое, освобождается через
+// IdempotencyLease, а сохраненный ответ действует до истечения ключа
+func TestIdempotencyAbandonedReservation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := NewIdempotencyRepository()
+	repo.now = func() time.Time { return now }
+
+	record := repository.IdempotencyRecord{Key: "k1", Method: "POST", Path: "/api/inventory", RequestHash: "h1"}
+	if existing, _ := repo.Reserve(ctx, record, time.Hour); existing != nil {
+		t.Fatalf("first reserve = %+v", existing)
+	}
+	if existing, _ := repo.Reserve(ctx, record, time.Hour); existing == nil || existing.Completed {
+		t.Fatalf("reserve within lease = %+v, want in-progress record", existing)
+	}
+
+	now = now.Add(repository.IdempotencyLease + time.Second)
+	if existing, _ := repo.Reserve(ctx, record, time.Hour); existing != nil {
+		t.Fatalf("reserve after lease = %+v, want reclaimed", existing)
+	}
+
+	record.Completed, record.StatusCode = true, 201
+	_ = repo.Complete(ctx, record)
+	now = now.Add(repository.IdempotencyLease + time.Second)
+	if existing, _ := repo.Reserve(ctx, record, time.Hour); existing == nil || existing.StatusCode != 201 {
+		t.Fatalf("completed key after lease = %+v, want stored response", existing)
+	}
+}
@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type inventoryRepository struct {
+	db db
+}
+
+var _ repository.InventoryRepository = inventoryRepository{}
+
+// findPosition ищет позицию товара на складе
+func findPosition(s *state, productID, warehouseID uuid.UUID) (models.Inventory, bool) {
+	for _, inv := range s.inventory {
+		if inv.ProductID == productID && inv.WarehouseID == warehouseID {
+			return inv, true
+		}
+	}
+	return models.Inventory{}, false
+}
+
+// 1. Создание связи товара и склада; для существующей позиции количество
+// добавляется, а цена и скидка заменяются
+func (r inventoryRepository) Create(_ context.Context, inventory models.Inventory) error {
+	return r.db.update(func(s *state) error {
+		if _, ok := s.products[inventory.ProductID.String()]; !ok {
+			return repository.ErrProductNotFound
+		}
+		if _, ok := s.warehouses[inventory.WarehouseID]; !ok {
+			return repository.ErrWarehouseNotFound
+		}
+
+		inv, ok := findPosition(s, inventory.ProductID, inventory.WarehouseID)
+		if ok {
+			inv.Quantity += inventory.Quantity
+			inv.Version++
+		} else {
+			inv = models.Inventory{
+				ID:          uuid.New(),
+				ProductID:   inventory.ProductID,
+				WarehouseID: inventory.WarehouseID,
+				Quantity:    inventory.Quantity,
+				Version:     1,
+			}
+		}
+		if inv.Quantity < 0 {
+			return repository.ErrInsufficientStock
+		}
+		// NUMERIC(10, 2) и NUMERIC(5, 2) в PostgreSQL
+		inv.Price = inventory.Price.Round(2)
+		inv.Discount = inventory.Discount.Round(2)
+		s.inventory[inv.ID] = inv
+		return nil
+	})
+}
+
+// 2. Изменение количества товара на quantity
+func (r inventoryRepository) UpdateQuantity(_ context.Context, productID, warehouseID uuid.UUID, quantity int, expectedVersion int64) error {
+	return r.db.update(func(s *state) error {
+		inv, ok := findPosition(s, productID, warehouseID)
+		if !ok {
+			return repository.ErrInventoryNotFound
+		}
+		if err := checkVersion(inv.Version, expectedVersion); err != nil {
+			return err
+		}
+		if inv.Quantity+quantity < 0 {
+			return repository.ErrInsufficientStock
+		}
+		inv.Quantity += quantity
+		inv.Version++
+		s.inventory[inv.ID] = inv
+		return nil
+	})
+}
+
+// 3. Установка скидки на список товаров
+func (r inventoryRepository) SetDiscount(_ context.Context, productIDs []uuid.UUID, warehouseID uuid.UUID, discount float64) error {
+	value := decimal.NewFromFloat(discount).Round(2)
+	return r.db.update(func(s *state) error {
+		for id, inv := range s.inventory {
+			if inv.WarehouseID == warehouseID && slices.Contains(productIDs, inv.ProductID) {
+				inv.Discount = value
+				inv.Version++
+				s.inventory[id] = inv
+			}
+		}
+		return nil
+	})
+}
+
+// 4. Список товаров на складе с фильтрами, сортировкой и keyset-пагинацией
+func (r inventoryRepository) GetByWarehouse(_ context.Context, warehouseID uuid.UUID, filter repository.InventoryFilter, page pagination.Params) (pagination.Page[models.InventoryWithNames], error) {
+	if !filter.ValidSort() {
+		return pagination.Page[models.InventoryWithNames]{}, repository.ErrInvalidSort
+	}
+	var after models.InventoryWithNames
+	if page.After != nil {
+		var err error
+		if after, err = filter.CursorPosition(*page.After); err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
+		}
+	}
+
+	var items []models.InventoryWithNames
+	_ = r.db.view(func(s *state) error {
+		for _, inv := range s.inventory {
+			if inv.WarehouseID != warehouseID {
+				continue
+			}
+			item := models.InventoryWithNames{
+				ID:            inv.ID,
+				ProductID:     inv.ProductID,
+				WarehouseID:   inv.WarehouseID,
+				Quantity:      inv.Quantity,
+				Price:         inv.Price,
+				Discount:      inv.Discount,
+				WarehouseName: s.warehouses[inv.WarehouseID].Name,
+				ProductName:   s.products[inv.ProductID.String()].Name,
+				Version:       inv.Version,
+			}
+			if matchesFilter(filter, item) {
+				items = append(items, item)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(items, func(a, b models.InventoryWithNames) int { return compareInventory(filter, a, b) })
+
+	return paginate(items, page,
+		func(inv models.InventoryWithNames) bool { return compareInventory(filter, inv, after) > 0 },
+		func(inv models.InventoryWithNames) pagination.Cursor {
+			return pagination.Cursor{ID: inv.ID.String(), Key: filter.SortKey(inv), Sort: filter.CursorSort()}
+		},
+	), nil
+}
+
+func matchesFilter(filter repository.InventoryFilter, inv models.InventoryWithNames) bool {
+	switch {
+	case filter.InStock != nil && *filter.InStock != (inv.Quantity > 0):
+		return false
+	case filter.Discounted != nil && *filter.Discounted != inv.Discount.IsPositive():
+		return false
+	case filter.MinPrice != nil && inv.Price.LessThan(*filter.MinPrice):
+		return false
+	case filter.MaxPrice != nil && inv.Price.GreaterThan(*filter.MaxPrice):
+		return false
+	case filter.Search != "" && !strings.Contains(strings.ToLower(inv.ProductName), strings.ToLower(filter.Search)):
+		return false
+	}
+	return true
+}
+
+// compareInventory задает порядок выдачи: ключ сортировки, затем id;
+// Desc меняет направление обоих
+func compareInventory(filter repository.InventoryFilter, a, b models.InventoryWithNames) int {
+	var c int
+	switch filter.Sort {
+	case "quantity":
+		c = cmp.Compare(a.Quantity, b.Quantity)
+	case "price":
+		c = a.Price.Cmp(b.Price)
+	case "product_name":
+		c = strings.Compare(a.ProductName, b.ProductName)
+	}
+	if c == 0 {
+		c = compareIDs(a.ID, b.ID)
+	}
+	if filter.Desc {
+		return -c
+	}
+	return c
+}
+
+// 5. Информация о товаре на складе
+func (r inventoryRepository) GetProductInWarehouse(_ context.Context, productID, warehouseID uuid.UUID) (*models.Inventory, error) {
+	var inv models.Inventory
+	err := r.db.view(func(s *state) error {
+		var ok bool
+		if inv, ok = findPosition(s, productID, warehouseID); !ok {
+			return repository.ErrInventoryNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// Цена и скидка товара на складе
+func (r inventoryRepository) GetProductPrice(ctx context.Context, productID, warehouseID uuid.UUID) (*models.InventoryPrice, error) {
+	inv, err := r.GetProductInWarehouse(ctx, productID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InventoryPrice{Price: inv.Price, Discount: inv.Discount}, nil
+}
+
+// 6. Покупка: сначала проверяются все позиции, затем списываются, так что
+// при нехватке любой из них остатки не меняются
+func (r inventoryRepository) Purchase(_ context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) error {
+	return r.db.update(func(s *state) error {
+		positions := make([]models.Inventory, 0, len(items))
+		for productID, quantity := range items {
+			inv, ok := findPosition(s, productID, warehouseID)
+			if !ok || inv.Quantity < quantity {
+				return fmt.Errorf("%w for product %s", repository.ErrInsufficientStock, productID)
+			}
+			inv.Quantity -= quantity
+			inv.Version++
+			positions = append(positions, inv)
+		}
+		for _, inv := range positions {
+			s.inventory[inv.ID] = inv
+		}
+		return nil
+	})
+}
+
+func (r inventoryRepository) DeleteProductFromWarehouse(_ context.Context, warehouseID, productID uuid.UUID, expectedVersion int64) error {
+	return r.db.update(func(s *state) error {
+		inv, ok := findPosition(s, productID, warehouseID)
+		if !ok {
+			return repository.ErrInventoryNotFound
+		}
+		if err := checkVersion(inv.Version, expectedVersion); err != nil {
+			return err
+		}
+		delete(s.inventory, inv.ID)
+		return nil
+	})
+}
+
+func (r inventoryRepository) DeleteInventory(_ context.Context, inventoryID uuid.UUID, expectedVersion int64) error {
+	return r.db.update(func(s *state) error {
+		inv, ok := s.inventory[inventoryID]
+		if !ok {
+			return repository.ErrInventoryNotFound
+		}
+		if err := checkVersion(inv.Version, expectedVersion); err != nil {
+			return err
+		}
+		delete(s.inventory, inventoryID)
+		return nil
+	})
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type productRepository struct {
+	db db
+}
+
+var _ repository.ProductRepository = productRepository{}
+
+func (r productRepository) GetAll(_ context.Context, page pagination.Params) (pagination.Page[models.Product], error) {
+	after, err := afterID(page)
+	if err != nil {
+		return pagination.Page[models.Product]{}, err
+	}
+
+	var products []models.Product
+	_ = r.db.view(func(s *state) error {
+		for _, p := range s.products {
+			products = append(products, copyProduct(p))
+		}
+		return nil
+	})
+	// В PostgreSQL id товара — UUID, строки в каноническом виде сравниваются так же
+	slices.SortFunc(products, func(a, b models.Product) int { return strings.Compare(a.ID, b.ID) })
+
+	return paginate(products, page,
+		func(p models.Product) bool { return p.ID > after.String() },
+		func(p models.Product) pagination.Cursor { return pagination.Cursor{ID: p.ID} },
+	), nil
+}
+
+func (r productRepository) GetByID(_ context.Context, id string) (*models.Product, error) {
+	id, ok := productKey(id)
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+
+	var product models.Product
+	err := r.db.view(func(s *state) error {
+		p, ok := s.products[id]
+		if !ok {
+			return repository.ErrProductNotFound
+		}
+		product = copyProduct(p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r productRepository) Create(_ context.Context, product models.Product) error {
+	id, ok := productKey(product.ID)
+	if !ok {
+		return fmt.Errorf("invalid product id %q", product.ID)
+	}
+	product.ID = id
+	return r.db.update(func(s *state) error {
+		if _, ok := s.products[product.ID]; ok {
+			return fmt.Errorf("product %s already exists", product.ID)
+		}
+		if barcodeTaken(s, product.Barcode, product.ID) {
+			return repository.ErrDuplicateBarcode
+		}
+		product.Version = 1
+		s.products[product.ID] = copyProduct(product)
+		return nil
+	})
+}
+
+func (r productRepository) Update(_ context.Context, product models.Product, expectedVersion int64) error {
+	id, ok := productKey(product.ID)
+	if !ok {
+		return repository.ErrProductNotFound
+	}
+	product.ID = id
+
+	return r.db.update(func(s *state) error {
+		current, ok := s.products[product.ID]
+		if !ok {
+			return repository.ErrProductNotFound
+		}
+		if err := checkVersion(current.Version, expectedVersion); err != nil {
+			return err
+		}
+		if barcodeTaken(s, product.Barcode, product.ID) {
+			return repository.ErrDuplicateBarcode
+		}
+		product.Version = current.Version + 1
+		s.products[product.ID] = copyProduct(product)
+		return nil
+	})
+}
+
+// Delete удаляет товар вместе с его остатками на складах и аналитикой
+func (r productRepository) Delete(_ context.Context, id string, expectedVersion int64) error {
+	id, ok := productKey(id)
+	if !ok {
+		return repository.ErrProductNotFound
+	}
+
+	return r.db.update(func(s *state) error {
+		current, ok := s.products[id]
+		if !ok {
+			return repository.ErrProductNotFound
+		}
+		if err := checkVersion(current.Version, expectedVersion); err != nil {
+			return err
+		}
+		delete(s.products, id)
+		deleteWhere(s.inventory, func(inv models.Inventory) bool { return inv.ProductID.String() == id })
+		deleteWhere(s.analytics, func(a models.Analytics) bool { return a.ProductID.String() == id })
+		return nil
+	})
+}
+
+// productKey приводит id товара к каноническому виду UUID, как это делает
+// колонка uuid в PostgreSQL
+func productKey(id string) (string, bool) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", false
+	}
+	return parsed.String(), true
+}
+
+// barcodeTaken сообщает, занят ли штрихкод другим товаром
+func barcodeTaken(s *state, barcode, exceptID string) bool {
+	for id, p := range s.products {
+		if p.Barcode == barcode && id != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// copyProduct отвязывает атрибуты от вызывающего кода; nil сохраняется
+// как пустой набор, как и в PostgreSQL
+func copyProduct(p models.Product) models.Product {
+	p.Attributes = maps.Clone(p.Attributes)
+	if p.Attributes == nil {
+		p.Attributes = map[string]string{}
+	}
+	return p
+}
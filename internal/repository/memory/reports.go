@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// sale — запись журнала продаж
+type sale struct {
+	warehouseID uuid.UUID
+	productID   uuid.UUID
+	quantity    int
+	total       decimal.Decimal
+	soldAt      time.Time
+	// aggregated — продажа уже учтена в отчетах о выручке
+	aggregated bool
+}
+
+// day возвращает день продажи (UTC) в формате отчета
+func (s sale) day() string {
+	return s.soldAt.UTC().Format(time.DateOnly)
+}
+
+type dailyKey struct {
+	warehouseID uuid.UUID
+	day         string
+}
+
+// revenue — строка отчета о выручке
+type revenue struct {
+	units int64
+	total decimal.Decimal
+}
+
+// add прибавляет (или с отрицательными значениями вычитает) продажи
+func (r revenue) add(units int64, total decimal.Decimal) revenue {
+	return revenue{units: r.units + units, total: r.total.Add(total)}
+}
+
+type reportRepository struct {
+	db db
+}
+
+var _ repository.ReportRepository = reportRepository{}
+
+var hundred = decimal.NewFromInt(100)
+
+// 1. Учет в отчетах продаж, записанных после прошлого обновления
+func (r reportRepository) RefreshRevenue(_ context.Context) error {
+	return r.db.update(func(s *state) error {
+		for id, sl := range s.sales {
+			if sl.aggregated {
+				continue
+			}
+			units := int64(sl.quantity)
+			key := dailyKey{sl.warehouseID, sl.day()}
+			s.dailyRevenue[key] = s.dailyRevenue[key].add(units, sl.total)
+			s.warehouseRevenue[sl.warehouseID] = s.warehouseRevenue[sl.warehouseID].add(units, sl.total)
+			sl.aggregated = true
+			s.sales[id] = sl
+		}
+		s.reportsAsOf[repository.ReportRevenue] = time.Now()
+		return nil
+	})
+}
+
+// 2. Пересчет стоимости остатков (каждая позиция округляется до копеек, как в SQL)
+func (r reportRepository) RefreshStockValue(_ context.Context) error {
+	return r.db.update(func(s *state) error {
+		values := map[uuid.UUID]models.StockValue{}
+		for _, inv := range s.inventory {
+			value := values[inv.WarehouseID]
+			value.WarehouseID = inv.WarehouseID
+			value.Units += int64(inv.Quantity)
+			lineValue := inv.Price.Mul(hundred.Sub(inv.Discount)).Div(hundred).Mul(decimal.NewFromInt(int64(inv.Quantity))).Round(2)
+			value.Value = value.Value.Add(lineValue)
+			values[inv.WarehouseID] = value
+		}
+		s.stockValue = values
+		s.reportsAsOf[repository.ReportStockValue] = time.Now()
+		return nil
+	})
+}
+
+// asOf возвращает момент последнего обновления отчета
+func (s *state) asOf(name string) *time.Time {
+	asOf, ok := s.reportsAsOf[name]
+	if !ok {
+		return nil
+	}
+	return &asOf
+}
+
+// 3. Топ складов по выручке
+func (r reportRepository) TopWarehouses(_ context.Context, limit int) (models.Report[models.WarehouseRevenue], error) {
+	var report models.Report[models.WarehouseRevenue]
+	_ = r.db.view(func(s *state) error {
+		report.AsOf = s.asOf(repository.ReportRevenue)
+		for id, rev := range s.warehouseRevenue {
+			report.Items = append(report.Items, models.WarehouseRevenue{WarehouseID: id, Address: s.warehouses[id].Address, TotalSum: rev.total})
+		}
+		return nil
+	})
+	slices.SortFunc(report.Items, func(a, b models.WarehouseRevenue) int {
+		return cmp.Or(b.TotalSum.Cmp(a.TotalSum), compareIDs(a.WarehouseID, b.WarehouseID))
+	})
+	if limit >= 0 && len(report.Items) > limit {
+		report.Items = report.Items[:limit]
+	}
+	return report, nil
+}
+
+// 4. Выручка склада по дням
+func (r reportRepository) DailyRevenue(_ context.Context, warehouseID uuid.UUID, from, to time.Time) (models.Report[models.DailyRevenue], error) {
+	first, last := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	var report models.Report[models.DailyRevenue]
+	_ = r.db.view(func(s *state) error {
+		report.AsOf = s.asOf(repository.ReportRevenue)
+		for key, rev := range s.dailyRevenue {
+			if key.warehouseID == warehouseID && key.day >= first && key.day <= last {
+				report.Items = append(report.Items, models.DailyRevenue{Date: key.day, SoldQuantity: rev.units, Revenue: rev.total})
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(report.Items, func(a, b models.DailyRevenue) int { return cmp.Compare(a.Date, b.Date) })
+	return report, nil
+}
+
+// 5. Стоимость остатков по складам (по убыванию)
+func (r reportRepository) StockValue(_ context.Context) (models.Report[models.StockValue], error) {
+	var report models.Report[models.StockValue]
+	_ = r.db.view(func(s *state) error {
+		report.AsOf = s.asOf(repository.ReportStockValue)
+		for _, value := range s.stockValue {
+			report.Items = append(report.Items, value)
+		}
+		return nil
+	})
+	slices.SortFunc(report.Items, func(a, b models.StockValue) int {
+		return cmp.Or(b.Value.Cmp(a.Value), compareIDs(a.WarehouseID, b.WarehouseID))
+	})
+	return report, nil
+}
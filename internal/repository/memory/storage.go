@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Name — имя драйвера хранилища (storage.driver)
+const Name = "memory"
+
+// NewStorage собирает хранилище в памяти процесса (демо-режим): данные
+// теряются при остановке, аутентификация и аудит недоступны.
+func NewStorage(store *Store) repository.Storage {
+	return repository.Storage{
+		Repos:       store.Repositories(),
+		Tx:          store,
+		Idempotency: NewIdempotencyRepository(),
+	}
+}
+
+func init() {
+	repository.RegisterStorage(Name, repository.StorageDriver{
+		Open: func(_ context.Context, _ repository.StorageSettings, logger *zap.Logger) (repository.Storage, error) {
+			logger.Warn("Using in-memory storage: data is lost on shutdown")
+			return NewStorage(New()), nil
+		},
+		Demo: true,
+	})
+}
@@ -0,0 +1,194 @@
+// Package memory — реализации репозиториев в памяти процесса для демо-режима
+// и быстрых тестов. Семантика совпадает с PostgreSQL: уникальный штрихкод,
+// остаток не уходит в минус, удаление склада или товара удаляет его остатки
+// и аналитику, версии записей проверяются так же. Отчеты строятся из журнала
+// продаж и остатков так же инкрементально, как в PostgreSQL.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// Store хранит данные всех репозиториев. Операции над ним потокобезопасны,
+// а WithTx выполняет несколько операций атомарно.
+type Store struct {
+	mu   sync.RWMutex
+	data *state
+}
+
+var _ repository.Transactor = (*Store)(nil)
+
+func New() *Store {
+	return &Store{data: newState()}
+}
+
+// state — «таблицы» хранилища
+type state struct {
+	warehouses map[uuid.UUID]models.Warehouse
+	products   map[string]models.Product
+	inventory  map[uuid.UUID]models.Inventory
+	analytics  map[uuid.UUID]models.Analytics
+
+	// Журнал продаж и отчеты (reports.go)
+	sales            map[int64]sale
+	nextSaleID       int64
+	dailyRevenue     map[dailyKey]revenue
+	warehouseRevenue map[uuid.UUID]revenue
+	stockValue       map[uuid.UUID]models.StockValue
+	reportsAsOf      map[string]time.Time
+}
+
+func newState() *state {
+	return &state{
+		warehouses: map[uuid.UUID]models.Warehouse{},
+		products:   map[string]models.Product{},
+		inventory:  map[uuid.UUID]models.Inventory{},
+		analytics:  map[uuid.UUID]models.Analytics{},
+
+		sales:            map[int64]sale{},
+		dailyRevenue:     map[dailyKey]revenue{},
+		warehouseRevenue: map[uuid.UUID]revenue{},
+		stockValue:       map[uuid.UUID]models.StockValue{},
+		reportsAsOf:      map[string]time.Time{},
+	}
+}
+
+// clone копирует состояние для транзакции; атрибуты товаров копируются
+// при записи, поэтому общая ссылка на них безопасна
+func (s *state) clone() *state {
+	return &state{
+		warehouses: maps.Clone(s.warehouses),
+		products:   maps.Clone(s.products),
+		inventory:  maps.Clone(s.inventory),
+		analytics:  maps.Clone(s.analytics),
+
+		sales:            maps.Clone(s.sales),
+		nextSaleID:       s.nextSaleID,
+		dailyRevenue:     maps.Clone(s.dailyRevenue),
+		warehouseRevenue: maps.Clone(s.warehouseRevenue),
+		stockValue:       maps.Clone(s.stockValue),
+		reportsAsOf:      maps.Clone(s.reportsAsOf),
+	}
+}
+
+// db — доступ репозиториев к данным: под блокировкой хранилища или внутри
+// транзакции, которая уже держит блокировку (аналог repository.DBTX)
+type db interface {
+	view(fn func(*state) error) error
+	update(fn func(*state) error) error
+}
+
+func (s *Store) view(fn func(*state) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.data)
+}
+
+func (s *Store) update(fn func(*state) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.data)
+}
+
+// txState — копия данных, с которой работает транзакция
+type txState struct {
+	data *state
+}
+
+func (t txState) view(fn func(*state) error) error   { return fn(t.data) }
+func (t txState) update(fn func(*state) error) error { return fn(t.data) }
+
+// Repositories возвращает репозитории поверх хранилища.
+func (s *Store) Repositories() repository.Repositories {
+	return newRepositories(s)
+}
+
+func newRepositories(db db) repository.Repositories {
+	return repository.Repositories{
+		Warehouses: warehouseRepository{db: db},
+		Products:   productRepository{db: db},
+		Inventory:  inventoryRepository{db: db},
+		Analytics:  analyticsRepository{db: db},
+		Reports:    reportRepository{db: db},
+	}
+}
+
+// WithTx выполняет fn над копией данных и публикует ее, если fn вернула nil.
+// Транзакции выполняются по очереди, поэтому конфликтов и повторов нет.
+func (s *Store) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := txState{data: s.data.clone()}
+	if err := fn(newRepositories(tx)); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+// checkVersion повторяет проверку версии из SQL: AnyVersion отключает ее
+func checkVersion(current, expected int64) error {
+	if expected != repository.AnyVersion && current != expected {
+		return repository.ErrVersionMismatch
+	}
+	return nil
+}
+
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// afterID разбирает ID из курсора страницы, упорядоченной по id
+func afterID(page pagination.Params) (*uuid.UUID, error) {
+	if page.After == nil {
+		return nil, nil
+	}
+	id, err := uuid.Parse(page.After.ID)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+	return &id, nil
+}
+
+// paginate выбирает из отсортированных items страницу после курсора
+// (after сообщает, лежит ли запись за курсором) так же, как SQL с LIMIT
+// Limit+1. Total считается по всем items.
+func paginate[T any](items []T, page pagination.Params, after func(T) bool, cursor func(T) pagination.Cursor) pagination.Page[T] {
+	start := 0
+	if page.After != nil {
+		for start < len(items) && !after(items[start]) {
+			start++
+		}
+	}
+	end := min(start+page.Limit+1, len(items))
+
+	result := pagination.NewPage(items[start:end:end], page, cursor)
+	if page.IncludeTotal {
+		total := int64(len(items))
+		result.Total = &total
+	}
+	return result
+}
+
+// deleteWhere удаляет из таблицы записи, для которых match вернула true
+// (каскадное удаление)
+func deleteWhere[K comparable, V any](table map[K]V, match func(V) bool) {
+	for key, value := range table {
+		if match(value) {
+			delete(table, key)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/warehouse-service/internal/repository/contract"
+	"github.com/yourusername/warehouse-service/internal/repository/memory"
+)
+
+func TestContract(t *testing.T) {
+	factory := func(context.Context) (contract.Backend, func(), error) {
+		store := memory.New()
+		return contract.Backend{Repos: store.Repositories(), Tx: store}, nil, nil
+	}
+	for _, c := range contract.Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := contract.RunCase(context.Background(), factory, c); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
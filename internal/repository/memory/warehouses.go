@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type warehouseRepository struct {
+	db db
+}
+
+var _ repository.WarehouseRepository = warehouseRepository{}
+
+func (r warehouseRepository) CreateWarehouse(_ context.Context, name string, address string) (uuid.UUID, error) {
+	id := uuid.New()
+	err := r.db.update(func(s *state) error {
+		s.warehouses[id] = models.Warehouse{ID: id, Name: name, Address: address, Version: 1}
+		return nil
+	})
+	return id, err
+}
+
+func (r warehouseRepository) GetAllWarehouses(_ context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error) {
+	after, err := afterID(page)
+	if err != nil {
+		return pagination.Page[models.Warehouse]{}, err
+	}
+
+	var warehouses []models.Warehouse
+	_ = r.db.view(func(s *state) error {
+		warehouses = slices.Collect(maps.Values(s.warehouses))
+		return nil
+	})
+	slices.SortFunc(warehouses, func(a, b models.Warehouse) int { return compareIDs(a.ID, b.ID) })
+
+	return paginate(warehouses, page,
+		func(w models.Warehouse) bool { return compareIDs(w.ID, *after) > 0 },
+		func(w models.Warehouse) pagination.Cursor { return pagination.Cursor{ID: w.ID.String()} },
+	), nil
+}
+
+func (r warehouseRepository) UpdateWarehouse(_ context.Context, warehouse models.Warehouse, expectedVersion int64) error {
+	return r.db.update(func(s *state) error {
+		current, ok := s.warehouses[warehouse.ID]
+		if !ok {
+			return repository.ErrWarehouseNotFound
+		}
+		if err := checkVersion(current.Version, expectedVersion); err != nil {
+			return err
+		}
+		current.Name = warehouse.Name
+		current.Address = warehouse.Address
+		current.Description = warehouse.Description
+		current.Version++
+		s.warehouses[warehouse.ID] = current
+		return nil
+	})
+}
+
+// DeleteWarehouse удаляет склад вместе с его остатками и аналитикой
+func (r warehouseRepository) DeleteWarehouse(_ context.Context, id uuid.UUID, expectedVersion int64) error {
+	return r.db.update(func(s *state) error {
+		current, ok := s.warehouses[id]
+		if !ok {
+			return repository.ErrWarehouseNotFound
+		}
+		if err := checkVersion(current.Version, expectedVersion); err != nil {
+			return err
+		}
+		delete(s.warehouses, id)
+		deleteWhere(s.inventory, func(inv models.Inventory) bool { return inv.WarehouseID == id })
+		deleteWhere(s.analytics, func(a models.Analytics) bool { return a.WarehouseID == id })
+		deleteWhere(s.sales, func(sl sale) bool { return sl.warehouseID == id })
+		for key := range s.dailyRevenue {
+			if key.warehouseID == id {
+				delete(s.dailyRevenue, key)
+			}
+		}
+		delete(s.warehouseRevenue, id)
+		delete(s.stockValue, id)
+		return nil
+	})
+}
+
+func (r warehouseRepository) GetWarehouseByID(_ context.Context, id uuid.UUID) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := r.db.view(func(s *state) error {
+		var ok bool
+		if warehouse, ok = s.warehouses[id]; !ok {
+			return repository.ErrWarehouseNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yourusername/warehouse-service/internal/app"
+	"github.com/yourusername/warehouse-service/internal/health"
+	"github.com/yourusername/warehouse-service/internal/metrics"
+	"github.com/yourusername/warehouse-service/internal/migrate"
+	"github.com/yourusername/warehouse-service/internal/tracing"
+	"github.com/yourusername/warehouse-service/migrations"
+	"go.uber.org/zap"
+)
+
+// StoragePostgres — имя драйвера хранилища PostgreSQL
+const StoragePostgres = "postgres"
+
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	MaxConns        int           `yaml:"max_conns"`
+	MinConns        int           `yaml:"min_conns"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
+	// Replica — реплика для списков и аналитики; пустой url — без реплики
+	Replica ReplicaConfig `yaml:"replica"`
+}
+
+type ReplicaConfig struct {
+	URL      string `yaml:"url"`
+	MaxConns int    `yaml:"max_conns"`
+	// MaxLag — отставание, выше которого чтения возвращаются на основную базу
+	MaxLag time.Duration `yaml:"max_lag"`
+	// CheckInterval — как часто измеряется отставание
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// Validate проверяет настройки PostgreSQL и возвращает все ошибки сразу.
+func (c DatabaseConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(c.URL != "", "database.url is required (DB_URL, -db-url)")
+	if c.URL != "" {
+		_, err := pgxpool.ParseConfig(c.URL)
+		check(err == nil, "database.url is invalid: %v", err)
+	}
+	check(c.MaxConns > 0, "database.max_conns must be positive")
+	check(c.MinConns >= 0 && c.MinConns <= c.MaxConns,
+		"database.min_conns must be between 0 and database.max_conns (%d)", c.MaxConns)
+	check(c.MaxConnLifetime > 0, "database.max_conn_lifetime must be positive")
+	check(c.MaxConnIdleTime > 0, "database.max_conn_idle_time must be positive")
+
+	if replica := c.Replica; replica.URL != "" {
+		_, err := pgxpool.ParseConfig(replica.URL)
+		check(err == nil, "database.replica.url is invalid: %v", err)
+		check(replica.MaxConns > 0, "database.replica.max_conns must be positive")
+		check(replica.MaxLag > 0, "database.replica.max_lag must be positive")
+		check(replica.CheckInterval > 0, "database.replica.check_interval must be positive")
+	}
+	return errors.Join(errs...)
+}
+
+// PoolConfig возвращает настройки пула соединений.
+func (c DatabaseConfig) PoolConfig() (*pgxpool.Config, error) {
+	pc, err := pgxpool.ParseConfig(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	pc.MaxConns = int32(c.MaxConns)
+	pc.MinConns = int32(c.MinConns)
+	pc.MaxConnLifetime = c.MaxConnLifetime
+	pc.MaxConnIdleTime = c.MaxConnIdleTime
+	return pc, nil
+}
+
+// ReplicaPoolConfig возвращает настройки пула реплики; время жизни
+// соединений то же, что у основного пула.
+func (c DatabaseConfig) ReplicaPoolConfig() (*pgxpool.Config, error) {
+	pc, err := pgxpool.ParseConfig(c.Replica.URL)
+	if err != nil {
+		return nil, err
+	}
+	pc.MaxConns = int32(c.Replica.MaxConns)
+	pc.MaxConnLifetime = c.MaxConnLifetime
+	pc.MaxConnIdleTime = c.MaxConnIdleTime
+	return pc, nil
+}
+
+// OpenPostgres создает пул соединений и мигратор встроенной схемы. Схема
+// не проверяется: команды migrate работают и на устаревшей базе.
+func OpenPostgres(ctx context.Context, cfg DatabaseConfig, logger *zap.Logger) (*pgxpool.Pool, *migrate.Migrator, error) {
+	poolConfig, err := cfg.PoolConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.PgxTracer{}
+	dbpool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create database pool: %w", err)
+	}
+	// Миграции встроены в бинарник
+	migrator, err := migrate.New(dbpool, migrations.FS, logger)
+	if err != nil {
+		dbpool.Close()
+		return nil, nil, fmt.Errorf("invalid embedded migrations: %w", err)
+	}
+	return dbpool, migrator, nil
+}
+
+// openReplica создает пул реплики для чтения
+func openReplica(ctx context.Context, cfg DatabaseConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := cfg.ReplicaPoolConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid replica configuration: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.PgxTracer{}
+	replica, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create replica pool: %w", err)
+	}
+	return replica, nil
+}
+
+// NewPostgresStorage собирает хранилище на пуле PostgreSQL. Готовность
+// требует доступной базы и схемы той версии, которую ожидает код.
+func NewPostgresStorage(logger *zap.Logger, dbpool *pgxpool.Pool, migrator *migrate.Migrator) Storage {
+	return Storage{
+		Repos:       NewRepositories(dbpool, logger),
+		Tx:          NewTxManager(dbpool, logger),
+		Idempotency: NewIdempotencyRepository(dbpool),
+		Audit:       NewAuditRepository(dbpool),
+		Users:       NewUserRepository(dbpool),
+		APIKeys:     NewAPIKeyRepository(dbpool),
+		RateLimit:   NewRateLimitRepository(dbpool),
+		Collectors:  []prometheus.Collector{metrics.NewPoolCollector(metrics.PoolPrimary, dbpool)},
+		Ready: map[string]health.Check{
+			"postgres":   dbpool.Ping,
+			"migrations": migrator.Check,
+		},
+		Close: func() error {
+			dbpool.Close()
+			return nil
+		},
+	}
+}
+
+// replicaCheckTimeout ограничивает одну проверку отставания реплики
+const replicaCheckTimeout = 2 * time.Second
+
+// withReplica направляет списки и аналитику через router и добавляет
+// периодическую проверку отставания реплики.
+func (s Storage) withReplica(logger *zap.Logger, primary, replica *pgxpool.Pool, router *ReplicaRouter, interval time.Duration) Storage {
+	s.Repos = NewRoutedRepositories(primary, router, logger)
+	s.Collectors = append(s.Collectors, metrics.NewPoolCollector(metrics.PoolReplica, replica))
+	s.Collectors = append(s.Collectors, metrics.ReplicaCollectors(router)...)
+	s.Writes = router
+	// Ошибку проверки не логируем на каждом тике: смену состояния логирует router
+	s.Jobs = append(s.Jobs, app.Every("replica-lag-check", interval, logger, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+		defer cancel()
+		_, _ = router.Check(ctx)
+		return nil
+	}))
+	closePrimary := s.Close
+	s.Close = func() error {
+		replica.Close()
+		return closePrimary()
+	}
+	return s
+}
+
+func init() {
+	RegisterStorage(StoragePostgres, StorageDriver{
+		Open: func(ctx context.Context, settings StorageSettings, logger *zap.Logger) (Storage, error) {
+			cfg := settings.Database
+			dbpool, migrator, err := OpenPostgres(ctx, cfg, logger)
+			if err != nil {
+				return Storage{}, err
+			}
+			// Сервер не запускается на схеме другой версии: миграции
+			// применяются отдельно командой migrate up
+			if err := migrator.Check(ctx); err != nil {
+				dbpool.Close()
+				return Storage{}, fmt.Errorf("database schema does not match the code (expected version %d): %w", migrator.Latest(), err)
+			}
+			storage := NewPostgresStorage(logger, dbpool, migrator)
+			if cfg.Replica.URL == "" {
+				return storage, nil
+			}
+
+			// Реплика разгружает основную базу от списков и аналитики;
+			// недоступная реплика не мешает запуску, чтения идут на основную базу
+			replica, err := openReplica(ctx, cfg)
+			if err != nil {
+				dbpool.Close()
+				return Storage{}, err
+			}
+			router := NewReplicaRouter(dbpool, replica, cfg.Replica.MaxLag, logger)
+			checkCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+			defer cancel()
+			if _, err := router.Check(checkCtx); err != nil {
+				logger.Warn("Replica unavailable, reading from primary", zap.Error(err))
+			}
+			return storage.withReplica(logger, dbpool, replica, router, cfg.Replica.CheckInterval), nil
+		},
+		Validate: func(settings StorageSettings) error {
+			return settings.Database.Validate()
+		},
+		Users: true,
+	})
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"go.uber.org/zap"
+)
+
+// ReplicaRouter — DBTX для запросов только на чтение: они идут на реплику,
+// пока ее отставание не превышает порог, иначе — на основной пул. Отставание
+// обновляет Check; до первой успешной проверки используется основной пул.
+//
+// Через роутер читают только списки и аналитика (см. NewRoutedRepositories):
+// чтение после записи (GetByID, проверки версий) и транзакции всегда идут
+// на основной пул. Чтобы клиент видел в списках свои изменения, в течение
+// maxLag после его записи (MarkWrite) его чтения тоже идут на основной пул;
+// клиента в контексте задает WithClient.
+type ReplicaRouter struct {
+	primary *pgxpool.Pool
+	replica *pgxpool.Pool
+	maxLag  time.Duration
+	logger  *zap.Logger
+
+	useReplica atomic.Bool
+	lag        atomic.Int64 // time.Duration последней проверки
+
+	mu     sync.Mutex
+	writes map[string]time.Time // клиент → время последней записи
+}
+
+var _ DBTX = (*ReplicaRouter)(nil)
+
+func NewReplicaRouter(primary, replica *pgxpool.Pool, maxLag time.Duration, logger *zap.Logger) *ReplicaRouter {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ReplicaRouter{primary: primary, replica: replica, maxLag: maxLag, logger: logger, writes: map[string]time.Time{}}
+}
+
+// Ключи контекста, по которым роутер выбирает пул
+type (
+	primaryKey struct{}
+	clientKey  struct{}
+)
+
+// WithPrimary направляет чтения через ReplicaRouter в контексте ctx на
+// основной пул.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryRequired сообщает, отправлены ли чтения в ctx на основной пул
+// через WithPrimary.
+func PrimaryRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(primaryKey{}).(bool)
+	return required
+}
+
+// WithClient связывает ctx с клиентом (субъект аутентификации или IP), чьи
+// записи отмечает MarkWrite.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// Отставание реплики: 0, если все полученные WAL применены (при простое
+// основной базы время последней транзакции не растет), иначе — возраст
+// последней примененной транзакции
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// Check измеряет отставание реплики и решает, читать ли с нее. Недоступная
+// реплика выводится из работы до следующей успешной проверки. Заодно
+// удаляются истекшие отметки записей клиентов.
+func (r *ReplicaRouter) Check(ctx context.Context) (time.Duration, error) {
+	r.forgetWrites()
+
+	var seconds float64
+	err := r.replica.QueryRow(ctx, replicaLagQuery).Scan(&seconds)
+	lag := time.Duration(seconds * float64(time.Second))
+	if err == nil {
+		r.lag.Store(int64(lag))
+	}
+
+	use := err == nil && lag <= r.maxLag
+	if was := r.useReplica.Swap(use); was != use {
+		log := logging.FromContext(ctx, r.logger)
+		switch {
+		case use:
+			log.Info("Reading from replica", zap.Duration("lag", lag))
+		case err != nil:
+			log.Warn("Replica unavailable, reading from primary", zap.Error(err))
+		default:
+			log.Warn("Replica lag above threshold, reading from primary",
+				zap.Duration("lag", lag), zap.Duration("max_lag", r.maxLag))
+		}
+	}
+	return lag, err
+}
+
+// MarkWrite отмечает запись клиента: следующие maxLag его чтения идут на
+// основной пул, даже если реплика еще не получила запись.
+func (r *ReplicaRouter) MarkWrite(client string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes[client] = time.Now()
+}
+
+// Window возвращает, сколько после записи чтения идут на основной пул.
+func (r *ReplicaRouter) Window() time.Duration {
+	return r.maxLag
+}
+
+// recentWrite сообщает, писал ли клиент в последние maxLag
+func (r *ReplicaRouter) recentWrite(client string) bool {
+	if client == "" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	written, ok := r.writes[client]
+	return ok && time.Since(written) < r.maxLag
+}
+
+// forgetWrites удаляет отметки, окно которых истекло
+func (r *ReplicaRouter) forgetWrites() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for client, written := range r.writes {
+		if time.Since(written) >= r.maxLag {
+			delete(r.writes, client)
+		}
+	}
+}
+
+// UsingReplica сообщает, идут ли чтения на реплику.
+func (r *ReplicaRouter) UsingReplica() bool {
+	return r.useReplica.Load()
+}
+
+// Lag возвращает отставание по последней успешной проверке.
+func (r *ReplicaRouter) Lag() time.Duration {
+	return time.Duration(r.lag.Load())
+}
+
+func (r *ReplicaRouter) pool(ctx context.Context) *pgxpool.Pool {
+	if !r.useReplica.Load() || PrimaryRequired(ctx) {
+		return r.primary
+	}
+	if client, _ := ctx.Value(clientKey{}).(string); r.recentWrite(client) {
+		return r.primary
+	}
+	return r.replica
+}
+
+func (r *ReplicaRouter) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return r.pool(ctx).Exec(ctx, sql, args...)
+}
+
+func (r *ReplicaRouter) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return r.pool(ctx).Query(ctx, sql, args...)
+}
+
+func (r *ReplicaRouter) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return r.pool(ctx).QueryRow(ctx, sql, args...)
+}
+
+func (r *ReplicaRouter) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.pool(ctx).Begin(ctx)
+}
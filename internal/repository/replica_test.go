@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Пулы не подключаются до первого запроса, поэтому выбор пула проверяется
+// без сервера
+func newTestRouter(t *testing.T, maxLag time.Duration) *ReplicaRouter {
+	t.Helper()
+	open := func(url string) *pgxpool.Pool {
+		pool, err := pgxpool.New(context.Background(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+		return pool
+	}
+	router := NewReplicaRouter(open("postgres://primary@127.0.0.1:1/db"), open("postgres://replica@127.0.0.1:1/db"), maxLag, nil)
+	router.useReplica.Store(true)
+	return router
+}
+
+func TestReplicaRouterStickiness(t *testing.T) {
+	const window = 100 * time.Millisecond
+	router := newTestRouter(t, window)
+	alice := WithClient(context.Background(), "sub:alice")
+	bob := WithClient(context.Background(), "sub:bob")
+
+	if router.pool(alice) != router.replica {
+		t.Fatal("reads before any write must go to the replica")
+	}
+
+	router.MarkWrite("sub:alice")
+	if router.pool(alice) != router.primary {
+		t.Fatal("reads right after the client's write must go to the primary")
+	}
+	if router.pool(bob) != router.replica {
+		t.Fatal("another client's write must not move reads to the primary")
+	}
+	if router.pool(context.Background()) != router.replica {
+		t.Fatal("reads without a client must go to the replica")
+	}
+	if router.pool(WithPrimary(bob)) != router.primary {
+		t.Fatal("WithPrimary must move reads to the primary")
+	}
+
+	time.Sleep(window)
+	if router.pool(alice) != router.replica {
+		t.Fatal("reads after the window must go back to the replica")
+	}
+	router.forgetWrites()
+	if len(router.writes) != 0 {
+		t.Fatalf("expired writes were not forgotten: %v", router.writes)
+	}
+
+	// Отстающая реплика выведена из работы: все чтения идут на основной пул
+	router.useReplica.Store(false)
+	if router.pool(bob) != router.primary {
+		t.Fatal("reads must go to the primary while the replica is out")
+	}
+}
@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+type analyticsRepository struct {
+	db     conn
+	logger *zap.Logger
+}
+
+var _ repository.AnalyticsRepository = analyticsRepository{}
+
+// 1. Запись продажи: продажи товара на складе накапливаются в одной записи,
+// а в журнал продаж добавляется новая
+func (r analyticsRepository) RecordSale(ctx context.Context, warehouseID, productID uuid.UUID, quantity int, totalSum decimal.Decimal) error {
+	logging.FromContext(ctx, r.logger).Info("Recording sale",
+		zap.String("warehouseID", warehouseID.String()),
+		zap.String("productID", productID.String()),
+		zap.Int("quantity", quantity),
+		zap.String("totalPrice", totalSum.String()))
+
+	err := inTx(ctx, r.db, func(tx conn) error {
+		if err := checkReferences(ctx, tx, productID, warehouseID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO analytics (id, warehouse_id, product_id, sold_quantity, total_cents)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (warehouse_id, product_id)
+			DO UPDATE SET
+				sold_quantity = analytics.sold_quantity + excluded.sold_quantity,
+				total_cents = analytics.total_cents + excluded.total_cents
+		`, uuid.New(), warehouseID, productID, quantity, toCents(totalSum))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO sales (warehouse_id, product_id, quantity, total_cents, sold_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, warehouseID, productID, quantity, toCents(totalSum), time.Now().UnixMilli())
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to execute RecordSale query", zap.Error(err))
+	}
+	return err
+}
+
+// 2. Аналитика по складу (keyset-пагинация по id)
+func (r analyticsRepository) GetWarehouseAnalytics(ctx context.Context, warehouseID uuid.UUID, page pagination.Params) (pagination.Page[models.Analytics], error) {
+	after, err := afterID(page)
+	if err != nil {
+		return pagination.Page[models.Analytics]{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, warehouse_id, product_id, sold_quantity, total_cents
+		FROM analytics
+		WHERE warehouse_id = ? AND id > ?
+		ORDER BY id
+		LIMIT ?`, warehouseID, after, page.Limit+1)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Error executing analytics query", zap.Error(err))
+		return pagination.Page[models.Analytics]{}, err
+	}
+	defer rows.Close()
+
+	var analytics []models.Analytics
+	for rows.Next() {
+		var a models.Analytics
+		var total int64
+		if err := rows.Scan(&a.ID, &a.WarehouseID, &a.ProductID, &a.Quantity, &total); err != nil {
+			return pagination.Page[models.Analytics]{}, err
+		}
+		a.TotalSum = fromCents(total)
+		analytics = append(analytics, a)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.Analytics]{}, err
+	}
+
+	result := pagination.NewPage(analytics, page, func(a models.Analytics) pagination.Cursor {
+		return pagination.Cursor{ID: a.ID.String()}
+	})
+	if page.IncludeTotal {
+		var total int64
+		err := r.db.QueryRowContext(ctx, "SELECT count(*) FROM analytics WHERE warehouse_id = ?", warehouseID).Scan(&total)
+		if err != nil {
+			return pagination.Page[models.Analytics]{}, err
+		}
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// 3. Удаление продаж товара на складе: из аналитики, журнала и уже
+// построенных отчетов о выручке (учтенные продажи вычитаются)
+func (r analyticsRepository) DeleteAnalytics(ctx context.Context, warehouseID, productID uuid.UUID) error {
+	return inTx(ctx, r.db, func(tx conn) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM analytics WHERE warehouse_id = ? AND product_id = ?", warehouseID, productID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE report_daily_revenue
+			SET sold_quantity = sold_quantity - s.quantity, revenue_cents = revenue_cents - s.total_cents
+			FROM (
+				SELECT `+saleDay+` AS day, SUM(quantity) AS quantity, SUM(total_cents) AS total_cents
+				FROM sales
+				WHERE warehouse_id = ?1 AND product_id = ?2 AND aggregated = 1
+				GROUP BY 1
+			) s
+			WHERE report_daily_revenue.warehouse_id = ?1 AND report_daily_revenue.day = s.day
+		`, warehouseID, productID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE report_warehouse_revenue
+			SET sold_quantity = sold_quantity - s.quantity, revenue_cents = revenue_cents - s.total_cents
+			FROM (
+				SELECT SUM(quantity) AS quantity, SUM(total_cents) AS total_cents
+				FROM sales
+				WHERE warehouse_id = ?1 AND product_id = ?2 AND aggregated = 1
+			) s
+			WHERE report_warehouse_revenue.warehouse_id = ?1 AND s.quantity IS NOT NULL
+		`, warehouseID, productID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM sales WHERE warehouse_id = ? AND product_id = ?", warehouseID, productID)
+		if err != nil {
+			return err
+		}
+		// Дни и склады без продаж убираются из отчетов
+		if _, err := tx.ExecContext(ctx, "DELETE FROM report_daily_revenue WHERE warehouse_id = ? AND sold_quantity <= 0", warehouseID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM report_warehouse_revenue WHERE warehouse_id = ? AND sold_quantity <= 0", warehouseID)
+		return err
+	})
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// IdempotencyRepository хранит ключи идемпотентности в той же базе SQLite.
+// Срок действия хранится в Unix-миллисекундах и сравнивается с часами
+// процесса, а не базы.
+type IdempotencyRepository struct {
+	db  conn
+	now func() time.Time
+}
+
+var _ repository.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+func NewIdempotencyRepository(store *Store) *IdempotencyRepository {
+	return &IdempotencyRepository{db: store.db, now: time.Now}
+}
+
+// 1. Резервирование ключа (истекший ключ и брошенный резерв переиспользуются)
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record repository.IdempotencyRecord, ttl time.Duration) (*repository.IdempotencyRecord, error) {
+	now := r.now()
+	n, err := affected(r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, method, path, request_hash, created_at, expires_at)
+		VALUES (?1, ?2, ?3, ?4, ?6, ?5)
+		ON CONFLICT (key, method, path) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at < ?6
+			OR idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < ?7
+	`, record.Key, record.Method, record.Path, record.RequestHash, now.Add(ttl).UnixMilli(), now.UnixMilli(),
+		now.Add(-repository.IdempotencyLease).UnixMilli()))
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, nil
+	}
+
+	// Ключ занят другим (возможно, еще выполняющимся) запросом
+	existing := repository.IdempotencyRecord{Key: record.Key, Method: record.Method, Path: record.Path}
+	var status sql.NullInt64
+	var contentType sql.NullString
+	err = r.db.QueryRowContext(ctx, `
+		SELECT request_hash, status_code, content_type, response_body
+		FROM idempotency_keys
+		WHERE key = ? AND method = ? AND path = ?
+	`, record.Key, record.Method, record.Path).Scan(&existing.RequestHash, &status, &contentType, &existing.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Ключ освободили между запросами — повторяем резервирование
+		return r.Reserve(ctx, record, ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	existing.Completed = status.Valid
+	existing.StatusCode = int(status.Int64)
+	existing.ContentType = contentType.String
+	return &existing, nil
+}
+
+// 2. Сохранение ответа для повторов
+func (r *IdempotencyRepository) Complete(ctx context.Context, record repository.IdempotencyRecord) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = ?, content_type = ?, response_body = ?
+		WHERE key = ? AND method = ? AND path = ?
+	`, record.StatusCode, record.ContentType, record.Body, record.Key, record.Method, record.Path)
+	return err
+}
+
+// 3. Освобождение ключа, если запрос завершился ошибкой сервера и его можно повторить
+func (r *IdempotencyRepository) Release(ctx context.Context, key, method, path string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = ? AND method = ? AND path = ?", key, method, path)
+	return err
+}
+
+// 4. Удаление истекших ключей
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return affected(r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?", r.now().UnixMilli()))
+}
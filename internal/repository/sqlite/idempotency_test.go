@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Резерв без ответа, брошенный при сбое, освобождается через
+// IdempotencyLease, а сохраненный ответ действует до истечения ключа
+func TestIdempotencyAbandonedReservation(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(ctx, filepath.Join(t.TempDir(), "warehouse.db"), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	now := time.Now()
+	repo := NewIdempotencyRepository(store)
+	repo.now = func() time.Time { return now }
+
+	record := repository.IdempotencyRecord{Key: "k1", Method: "POST", Path: "/api/inventory", RequestHash: "h1"}
+	if existing, err := repo.Reserve(ctx, record, time.Hour); err != nil || existing != nil {
+		t.Fatalf("first reserve = %+v, %v", existing, err)
+	}
+	existing, err := repo.Reserve(ctx, record, time.Hour)
+	if err != nil || existing == nil || existing.Completed {
+		t.Fatalf("reserve within lease = %+v, %v, want in-progress record", existing, err)
+	}
+
+	now = now.Add(repository.IdempotencyLease + time.Second)
+	if existing, err := repo.Reserve(ctx, record, time.Hour); err != nil || existing != nil {
+		t.Fatalf("reserve after lease = %+v, %v, want reclaimed", existing, err)
+	}
+
+	record.Completed, record.StatusCode = true, 201
+	if err := repo.Complete(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(repository.IdempotencyLease + time.Second)
+	existing, err = repo.Reserve(ctx, record, time.Hour)
+	if err != nil || existing == nil || !existing.Completed || existing.StatusCode != 201 {
+		t.Fatalf("completed key after lease = %+v, %v, want stored response", existing, err)
+	}
+}
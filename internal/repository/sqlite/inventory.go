@@ -0,0 +1,321 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type inventoryRepository struct {
+	db conn
+}
+
+var _ repository.InventoryRepository = inventoryRepository{}
+
+// Разрешенные колонки сортировки (см. repository.InventoryFilter.ValidSort)
+var inventorySortColumns = map[string]string{
+	"quantity":     "i.quantity",
+	"price":        "i.price_cents",
+	"product_name": "p.name",
+}
+
+// checkReferences проверяет товар и склад до записи: SQLite не сообщает,
+// какой из внешних ключей нарушен
+func checkReferences(ctx context.Context, db conn, productID, warehouseID uuid.UUID) error {
+	found, err := exists(ctx, db, "SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", productID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return repository.ErrProductNotFound
+	}
+	if found, err = exists(ctx, db, "SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = ?)", warehouseID); err != nil {
+		return err
+	}
+	if !found {
+		return repository.ErrWarehouseNotFound
+	}
+	return nil
+}
+
+// 1. Создание связи товара и склада; для существующей позиции количество
+// добавляется, а цена и скидка заменяются
+func (r inventoryRepository) Create(ctx context.Context, inventory models.Inventory) error {
+	return inTx(ctx, r.db, func(tx conn) error {
+		if err := checkReferences(ctx, tx, inventory.ProductID, inventory.WarehouseID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO inventory (id, product_id, warehouse_id, quantity, price_cents, discount_bp)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (product_id, warehouse_id)
+			DO UPDATE SET
+				quantity = inventory.quantity + excluded.quantity,
+				price_cents = excluded.price_cents,
+				discount_bp = excluded.discount_bp,
+				version = inventory.version + 1
+		`, uuid.New(), inventory.ProductID, inventory.WarehouseID, inventory.Quantity,
+			toCents(inventory.Price), toCents(inventory.Discount))
+		return translateConstraint(err)
+	})
+}
+
+// 2. Изменение количества товара на quantity
+func (r inventoryRepository) UpdateQuantity(ctx context.Context, productID, warehouseID uuid.UUID, quantity int, expectedVersion int64) error {
+	n, err := affected(r.db.ExecContext(ctx, `
+		UPDATE inventory SET quantity = quantity + ?1, version = version + 1
+		WHERE product_id = ?2 AND warehouse_id = ?3 AND (?4 = 0 OR version = ?4)
+	`, quantity, productID, warehouseID, expectedVersion))
+	if err != nil {
+		return translateConstraint(err)
+	}
+	if n == 0 {
+		return r.missingError(ctx, "product_id = ? AND warehouse_id = ?", productID, warehouseID)
+	}
+	return nil
+}
+
+// 3. Установка скидки на список товаров
+func (r inventoryRepository) SetDiscount(ctx context.Context, productIDs []uuid.UUID, warehouseID uuid.UUID, discount float64) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	args := []any{toCents(decimal.NewFromFloat(discount)), warehouseID}
+	for _, id := range productIDs {
+		args = append(args, id)
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE inventory SET discount_bp = ?, version = version + 1
+		WHERE warehouse_id = ? AND product_id IN (`+placeholders(len(productIDs))+`)
+	`, args...)
+	return translateConstraint(err)
+}
+
+// placeholders возвращает "?, ?, ..." для n параметров
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// 4. Список товаров на складе с фильтрами, сортировкой и keyset-пагинацией
+func (r inventoryRepository) GetByWarehouse(ctx context.Context, warehouseID uuid.UUID, filter repository.InventoryFilter, page pagination.Params) (pagination.Page[models.InventoryWithNames], error) {
+	if !filter.ValidSort() {
+		return pagination.Page[models.InventoryWithNames]{}, repository.ErrInvalidSort
+	}
+
+	args := []any{warehouseID}
+	conditions := []string{"i.warehouse_id = ?"}
+	where := func(condition string, values ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			where("i.quantity > 0")
+		} else {
+			where("i.quantity = 0")
+		}
+	}
+	if filter.Discounted != nil {
+		if *filter.Discounted {
+			where("i.discount_bp > 0")
+		} else {
+			where("i.discount_bp = 0")
+		}
+	}
+	if filter.MinPrice != nil {
+		where("i.price_cents >= ?", toCents(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		where("i.price_cents <= ?", toCents(*filter.MaxPrice))
+	}
+	if filter.Search != "" {
+		where("instr(casefold(p.name), casefold(?)) > 0", filter.Search)
+	}
+
+	// Общее количество считается по фильтрам без учета курсора
+	var total int64
+	if page.IncludeTotal {
+		err := r.db.QueryRowContext(ctx, `
+			SELECT count(*)
+			FROM inventory i
+			JOIN products p ON i.product_id = p.id
+			WHERE `+strings.Join(conditions, " AND "), args...).Scan(&total)
+		if err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
+		}
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	orderBy := "i.id " + direction
+	column, sorted := inventorySortColumns[filter.Sort]
+	if sorted {
+		orderBy = column + " " + direction + ", " + orderBy
+	}
+	if page.After != nil {
+		key, id, err := cursorKey(filter, *page.After)
+		if err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
+		}
+		if sorted {
+			where(fmt.Sprintf("(%s, i.id) %s (?, ?)", column, comparison), key, id)
+		} else {
+			where("i.id "+comparison+" ?", id)
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			i.id, i.product_id, i.warehouse_id, i.quantity, i.price_cents, i.discount_bp, i.version,
+			w.name, p.name
+		FROM inventory i
+		JOIN warehouses w ON i.warehouse_id = w.id
+		JOIN products p ON i.product_id = p.id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY `+orderBy+`
+		LIMIT ?`, append(args, page.Limit+1)...)
+	if err != nil {
+		return pagination.Page[models.InventoryWithNames]{}, err
+	}
+	defer rows.Close()
+
+	var inventoryList []models.InventoryWithNames
+	for rows.Next() {
+		var inv models.InventoryWithNames
+		var price, discount int64
+		if err := rows.Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &price, &discount, &inv.Version, &inv.WarehouseName, &inv.ProductName); err != nil {
+			return pagination.Page[models.InventoryWithNames]{}, err
+		}
+		inv.Price, inv.Discount = fromCents(price), fromCents(discount)
+		inventoryList = append(inventoryList, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.InventoryWithNames]{}, err
+	}
+
+	result := pagination.NewPage(inventoryList, page, func(inv models.InventoryWithNames) pagination.Cursor {
+		return pagination.Cursor{ID: inv.ID.String(), Key: filter.SortKey(inv), Sort: filter.CursorSort()}
+	})
+	if page.IncludeTotal {
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// cursorKey разбирает курсор в значения для сравнения в SQL: ключ
+// сортировки в типе колонки и id записи
+func cursorKey(filter repository.InventoryFilter, cursor pagination.Cursor) (key any, id string, err error) {
+	after, err := filter.CursorPosition(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	switch filter.Sort {
+	case "quantity":
+		key = after.Quantity
+	case "price":
+		key = toCents(after.Price)
+	case "product_name":
+		key = after.ProductName
+	}
+	return key, after.ID.String(), nil
+}
+
+// 5. Информация о товаре на складе
+func (r inventoryRepository) GetProductInWarehouse(ctx context.Context, productID, warehouseID uuid.UUID) (*models.Inventory, error) {
+	var inv models.Inventory
+	var price, discount int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, product_id, warehouse_id, quantity, price_cents, discount_bp, version FROM inventory
+		WHERE product_id = ? AND warehouse_id = ?
+	`, productID, warehouseID).Scan(&inv.ID, &inv.ProductID, &inv.WarehouseID, &inv.Quantity, &price, &discount, &inv.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrInventoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	inv.Price, inv.Discount = fromCents(price), fromCents(discount)
+	return &inv, nil
+}
+
+// Цена и скидка товара на складе
+func (r inventoryRepository) GetProductPrice(ctx context.Context, productID, warehouseID uuid.UUID) (*models.InventoryPrice, error) {
+	var price, discount int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT price_cents, discount_bp FROM inventory WHERE product_id = ? AND warehouse_id = ?
+	`, productID, warehouseID).Scan(&price, &discount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrInventoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.InventoryPrice{Price: fromCents(price), Discount: fromCents(discount)}, nil
+}
+
+// 6. Покупка: все позиции списываются атомарно, при нехватке любой из них
+// остатки не меняются
+func (r inventoryRepository) Purchase(ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) error {
+	return inTx(ctx, r.db, func(tx conn) error {
+		for productID, quantity := range items {
+			n, err := affected(tx.ExecContext(ctx, `
+				UPDATE inventory SET quantity = quantity - ?1, version = version + 1
+				WHERE product_id = ?2 AND warehouse_id = ?3 AND quantity >= ?1
+			`, quantity, productID, warehouseID))
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("%w for product %s", repository.ErrInsufficientStock, productID)
+			}
+		}
+		return nil
+	})
+}
+
+func (r inventoryRepository) DeleteProductFromWarehouse(ctx context.Context, warehouseID, productID uuid.UUID, expectedVersion int64) error {
+	n, err := affected(r.db.ExecContext(ctx, `
+		DELETE FROM inventory WHERE product_id = ?1 AND warehouse_id = ?2 AND (?3 = 0 OR version = ?3)`,
+		productID, warehouseID, expectedVersion))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.missingError(ctx, "product_id = ? AND warehouse_id = ?", productID, warehouseID)
+	}
+	return nil
+}
+
+func (r inventoryRepository) DeleteInventory(ctx context.Context, inventoryID uuid.UUID, expectedVersion int64) error {
+	n, err := affected(r.db.ExecContext(ctx, `
+		DELETE FROM inventory WHERE id = ?1 AND (?2 = 0 OR version = ?2)`, inventoryID, expectedVersion))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.missingError(ctx, "id = ?", inventoryID)
+	}
+	return nil
+}
+
+// missingError различает отсутствие позиции и конфликт версий
+func (r inventoryRepository) missingError(ctx context.Context, condition string, args ...any) error {
+	found, err := exists(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM inventory WHERE "+condition+")", args...)
+	if err != nil {
+		return err
+	}
+	if found {
+		return repository.ErrVersionMismatch
+	}
+	return repository.ErrInventoryNotFound
+}
@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type productRepository struct {
+	db conn
+}
+
+var _ repository.ProductRepository = productRepository{}
+
+// productKey приводит ID к каноническому виду, в котором он хранится;
+// ID не в формате UUID не принадлежит ни одному товару (в PostgreSQL
+// колонка id имеет тип UUID)
+func productKey(id string) (string, bool) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", false
+	}
+	return parsed.String(), true
+}
+
+const productColumns = "id, name, COALESCE(description, ''), attributes, weight, barcode, version"
+
+// GetAll возвращает товары постранично (keyset-пагинация по id)
+func (r productRepository) GetAll(ctx context.Context, page pagination.Params) (pagination.Page[models.Product], error) {
+	after, err := afterID(page)
+	if err != nil {
+		return pagination.Page[models.Product]{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+productColumns+` FROM products
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, after, page.Limit+1)
+	if err != nil {
+		return pagination.Page[models.Product]{}, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return pagination.Page[models.Product]{}, err
+		}
+		products = append(products, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.Product]{}, err
+	}
+
+	result := pagination.NewPage(products, page, func(p models.Product) pagination.Cursor {
+		return pagination.Cursor{ID: p.ID}
+	})
+	if page.IncludeTotal {
+		var total int64
+		if err := r.db.QueryRowContext(ctx, "SELECT count(*) FROM products").Scan(&total); err != nil {
+			return pagination.Page[models.Product]{}, err
+		}
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// GetByID возвращает товар по id
+func (r productRepository) GetByID(ctx context.Context, id string) (*models.Product, error) {
+	key, ok := productKey(id)
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	p, err := scanProduct(r.db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = ?", key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrProductNotFound
+	}
+	return p, err
+}
+
+func scanProduct(row scanner) (*models.Product, error) {
+	var p models.Product
+	var attributes []byte
+	if err := row.Scan(&p.ID, &p.Name, &p.Description, &attributes, &p.Weight, &p.Barcode, &p.Version); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(attributes, &p.Attributes); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r productRepository) Create(ctx context.Context, product models.Product) error {
+	key, ok := productKey(product.ID)
+	if !ok {
+		return fmt.Errorf("invalid product id %q", product.ID)
+	}
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO products (id, name, description, attributes, weight, barcode) VALUES (?, ?, NULLIF(?, ''), ?, ?, ?)`,
+		key, product.Name, product.Description, attributes, product.Weight, product.Barcode)
+	return translateConstraint(err)
+}
+
+// Update полностью заменяет данные товара: пустое описание сохраняется как NULL
+func (r productRepository) Update(ctx context.Context, product models.Product, expectedVersion int64) error {
+	key, ok := productKey(product.ID)
+	if !ok {
+		return repository.ErrProductNotFound
+	}
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
+	n, err := affected(r.db.ExecContext(ctx, `
+		UPDATE products
+		SET name = ?1, description = NULLIF(?2, ''), attributes = ?3, weight = ?4, barcode = ?5, version = version + 1
+		WHERE id = ?6 AND (?7 = 0 OR version = ?7)`,
+		product.Name, product.Description, attributes, product.Weight, product.Barcode, key, expectedVersion))
+	if err != nil {
+		return translateConstraint(err)
+	}
+	if n == 0 {
+		return r.missingError(ctx, key)
+	}
+	return nil
+}
+
+// Delete удаляет товар (остатки и аналитика удаляются каскадно)
+func (r productRepository) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	key, ok := productKey(id)
+	if !ok {
+		return repository.ErrProductNotFound
+	}
+	n, err := affected(r.db.ExecContext(ctx, "DELETE FROM products WHERE id = ?1 AND (?2 = 0 OR version = ?2)", key, expectedVersion))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.missingError(ctx, key)
+	}
+	return nil
+}
+
+// missingError различает отсутствие товара и конфликт версий
+func (r productRepository) missingError(ctx context.Context, key string) error {
+	found, err := exists(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", key)
+	if err != nil {
+		return err
+	}
+	if found {
+		return repository.ErrVersionMismatch
+	}
+	return repository.ErrProductNotFound
+}
+
+// marshalAttributes кодирует атрибуты; nil сохраняется как пустой объект
+func marshalAttributes(attributes map[string]string) (string, error) {
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	data, err := json.Marshal(attributes)
+	return string(data), err
+}
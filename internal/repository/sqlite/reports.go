@@ -0,0 +1,195 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+type reportRepository struct {
+	db     conn
+	logger *zap.Logger
+}
+
+var _ repository.ReportRepository = reportRepository{}
+
+// День продажи (UTC) из sold_at в миллисекундах
+const saleDay = "strftime('%Y-%m-%d', sold_at / 1000, 'unixepoch')"
+
+// setAsOf отмечает момент обновления отчета
+func setAsOf(ctx context.Context, tx conn, name string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE report_state SET as_of = ? WHERE name = ?", time.Now().UnixMilli(), name)
+	return err
+}
+
+// 1. Инкрементальное обновление выручки: еще не учтенные продажи
+// прибавляются к отчетам и помечаются в одной транзакции. Продажи без даты
+// (перенесенные из analytics) входят только в выручку за все время
+func (r reportRepository) RefreshRevenue(ctx context.Context) error {
+	return inTx(ctx, r.db, func(tx conn) error {
+		// WHERE в SELECT обязателен для INSERT ... SELECT ... ON CONFLICT в SQLite
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO report_daily_revenue (warehouse_id, day, sold_quantity, revenue_cents)
+			SELECT warehouse_id, `+saleDay+`, SUM(quantity), SUM(total_cents)
+			FROM sales WHERE aggregated = 0 AND sold_at IS NOT NULL
+			GROUP BY 1, 2
+			ON CONFLICT (warehouse_id, day) DO UPDATE SET
+				sold_quantity = report_daily_revenue.sold_quantity + excluded.sold_quantity,
+				revenue_cents = report_daily_revenue.revenue_cents + excluded.revenue_cents
+		`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO report_warehouse_revenue (warehouse_id, sold_quantity, revenue_cents)
+			SELECT warehouse_id, SUM(quantity), SUM(total_cents)
+			FROM sales WHERE aggregated = 0
+			GROUP BY warehouse_id
+			ON CONFLICT (warehouse_id) DO UPDATE SET
+				sold_quantity = report_warehouse_revenue.sold_quantity + excluded.sold_quantity,
+				revenue_cents = report_warehouse_revenue.revenue_cents + excluded.revenue_cents
+		`)
+		if err != nil {
+			return err
+		}
+		n, err := affected(tx.ExecContext(ctx, "UPDATE sales SET aggregated = 1 WHERE aggregated = 0"))
+		if err != nil {
+			return err
+		}
+		logging.FromContext(ctx, r.logger).Debug("Revenue reports refreshed", zap.Int64("sales", n))
+		return setAsOf(ctx, tx, repository.ReportRevenue)
+	})
+}
+
+// 2. Полный пересчет стоимости остатков; каждая позиция округляется до
+// копейки (половина — вверх), как ROUND в PostgreSQL
+func (r reportRepository) RefreshStockValue(ctx context.Context) error {
+	return inTx(ctx, r.db, func(tx conn) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM report_stock_value"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO report_stock_value (warehouse_id, units, value_cents)
+			SELECT warehouse_id, SUM(quantity), SUM((price_cents * (10000 - discount_bp) * quantity + 5000) / 10000)
+			FROM inventory
+			GROUP BY warehouse_id
+		`)
+		if err != nil {
+			return err
+		}
+		return setAsOf(ctx, tx, repository.ReportStockValue)
+	})
+}
+
+// asOf возвращает момент последнего обновления отчета
+func (r reportRepository) asOf(ctx context.Context, name string) (*time.Time, error) {
+	var millis sql.NullInt64
+	err := r.db.QueryRowContext(ctx, "SELECT as_of FROM report_state WHERE name = ?", name).Scan(&millis)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !millis.Valid {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	asOf := time.UnixMilli(millis.Int64)
+	return &asOf, nil
+}
+
+// 3. Топ складов по выручке
+func (r reportRepository) TopWarehouses(ctx context.Context, limit int) (models.Report[models.WarehouseRevenue], error) {
+	asOf, err := r.asOf(ctx, repository.ReportRevenue)
+	if err != nil {
+		return models.Report[models.WarehouseRevenue]{}, err
+	}
+	// Отрицательный LIMIT в SQLite означает «без ограничения», как и в memory
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT w.id, w.address, rr.revenue_cents
+		FROM report_warehouse_revenue rr
+		JOIN warehouses w ON rr.warehouse_id = w.id
+		ORDER BY rr.revenue_cents DESC, rr.warehouse_id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to execute query for top warehouses", zap.Error(err))
+		return models.Report[models.WarehouseRevenue]{}, err
+	}
+	defer rows.Close()
+
+	report := models.Report[models.WarehouseRevenue]{AsOf: asOf}
+	for rows.Next() {
+		var res models.WarehouseRevenue
+		var revenue int64
+		if err := rows.Scan(&res.WarehouseID, &res.Address, &revenue); err != nil {
+			return models.Report[models.WarehouseRevenue]{}, err
+		}
+		res.TotalSum = fromCents(revenue)
+		report.Items = append(report.Items, res)
+	}
+	return report, rows.Err()
+}
+
+// 4. Выручка склада по дням
+func (r reportRepository) DailyRevenue(ctx context.Context, warehouseID uuid.UUID, from, to time.Time) (models.Report[models.DailyRevenue], error) {
+	asOf, err := r.asOf(ctx, repository.ReportRevenue)
+	if err != nil {
+		return models.Report[models.DailyRevenue]{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT day, sold_quantity, revenue_cents
+		FROM report_daily_revenue
+		WHERE warehouse_id = ? AND day BETWEEN ? AND ?
+		ORDER BY day
+	`, warehouseID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return models.Report[models.DailyRevenue]{}, err
+	}
+	defer rows.Close()
+
+	report := models.Report[models.DailyRevenue]{AsOf: asOf}
+	for rows.Next() {
+		var res models.DailyRevenue
+		var revenue int64
+		if err := rows.Scan(&res.Date, &res.SoldQuantity, &revenue); err != nil {
+			return models.Report[models.DailyRevenue]{}, err
+		}
+		res.Revenue = fromCents(revenue)
+		report.Items = append(report.Items, res)
+	}
+	return report, rows.Err()
+}
+
+// 5. Стоимость остатков по складам (по убыванию)
+func (r reportRepository) StockValue(ctx context.Context) (models.Report[models.StockValue], error) {
+	asOf, err := r.asOf(ctx, repository.ReportStockValue)
+	if err != nil {
+		return models.Report[models.StockValue]{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT warehouse_id, units, value_cents
+		FROM report_stock_value
+		ORDER BY value_cents DESC, warehouse_id
+	`)
+	if err != nil {
+		return models.Report[models.StockValue]{}, err
+	}
+	defer rows.Close()
+
+	report := models.Report[models.StockValue]{AsOf: asOf}
+	for rows.Next() {
+		var res models.StockValue
+		var value int64
+		if err := rows.Scan(&res.WarehouseID, &res.Units, &value); err != nil {
+			return models.Report[models.StockValue]{}, err
+		}
+		res.Value = fromCents(value)
+		report.Items = append(report.Items, res)
+	}
+	return report, rows.Err()
+}
@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yourusername/warehouse-service/internal/health"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Name — имя драйвера хранилища (storage.driver)
+const Name = "sqlite"
+
+// NewStorage собирает хранилище на файле SQLite (склады без постоянной
+// связи с центральной базой). Пользователей, API-ключей и журнала аудита в
+// SQLite нет, поэтому features.auth с ним не проходит проверку
+// конфигурации, а аудит и администрирование не публикуются.
+func NewStorage(store *Store) repository.Storage {
+	return repository.Storage{
+		Repos:       store.Repositories(),
+		Tx:          store,
+		Idempotency: NewIdempotencyRepository(store),
+		Ready:       map[string]health.Check{"sqlite": store.Ping},
+		Close:       store.Close,
+	}
+}
+
+func init() {
+	repository.RegisterStorage(Name, repository.StorageDriver{
+		Open: func(ctx context.Context, settings repository.StorageSettings, logger *zap.Logger) (repository.Storage, error) {
+			store, err := Open(ctx, settings.SQLite.Path, logger)
+			if err != nil {
+				return repository.Storage{}, err
+			}
+			return NewStorage(store), nil
+		},
+		Validate: func(settings repository.StorageSettings) error {
+			if settings.SQLite.Path == "" {
+				return errors.New("storage.sqlite.path is required (SQLITE_PATH, -sqlite-path)")
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,260 @@
+// Package sqlite — реализации репозиториев на SQLite для складов без
+// постоянной связи с центральной базой (edge/offline). Семантика совпадает
+// с PostgreSQL и проверяется тем же contract-набором.
+//
+// SQLite допускает одного писателя, поэтому хранилище работает через одно
+// соединение: операции и транзакции выполняются по очереди, без SQLITE_BUSY
+// и повторов. Схема (migrations.SQLite) применяется при открытии файла.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/migrate"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+	"github.com/yourusername/warehouse-service/migrations"
+	"go.uber.org/zap"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func init() {
+	// LIKE и lower() в SQLite различают регистр только для ASCII; поиск по
+	// названию должен работать и для кириллицы, как ILIKE в PostgreSQL
+	err := sqlitedriver.RegisterDeterministicScalarFunction("casefold", 1, func(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		s, _ := args[0].(string)
+		return strings.ToLower(s), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Store — открытая база SQLite.
+type Store struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+var _ repository.Transactor = (*Store)(nil)
+
+// Open открывает (или создает) файл базы и применяет миграции. Путь
+// ":memory:" создает базу в памяти, которая живет до Close.
+func Open(ctx context.Context, path string, logger *zap.Logger) (*Store, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma": {"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)", "synchronous(NORMAL)"},
+	}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// Единственное соединение сериализует запись и сохраняет базу ":memory:"
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxIdleTime(0)
+	db.SetConnMaxLifetime(0)
+
+	s := &Store{db: db, logger: logger}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate sqlite schema: %w", err)
+	}
+	return s, nil
+}
+
+// Close закрывает базу.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping проверяет доступность базы (проба готовности).
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// migrate применяет недостающие миграции; версия схемы хранится в
+// PRAGMA user_version
+func (s *Store) migrate(ctx context.Context) error {
+	list, err := migrate.Parse(migrations.SQLite)
+	if err != nil {
+		return err
+	}
+	var current int64
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&current); err != nil {
+		return err
+	}
+	if latest := list[len(list)-1].Version; current > latest {
+		return fmt.Errorf("database schema version %d is newer than the code (%d)", current, latest)
+	}
+
+	for _, m := range list {
+		if m.Version <= current {
+			continue
+		}
+		err := inTx(ctx, s.db, func(tx conn) error {
+			if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", m.Version))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		s.logger.Info("Applied sqlite migration", zap.Int64("version", m.Version), zap.String("name", m.Name))
+	}
+	return nil
+}
+
+// conn — общий интерфейс *sql.DB и *sql.Tx (аналог repository.DBTX)
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ conn = (*sql.DB)(nil)
+	_ conn = (*sql.Tx)(nil)
+)
+
+// inTx выполняет fn атомарно: на *sql.DB — в новой транзакции, внутри
+// транзакции — в точке сохранения (как pgx.BeginFunc на pgx.Tx)
+func inTx(ctx context.Context, c conn, fn func(tx conn) error) error {
+	switch c := c.(type) {
+	case *sql.DB:
+		tx, err := c.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return errors.Join(err, ignoreDone(tx.Rollback()))
+		}
+		return tx.Commit()
+	default:
+		if _, err := c.ExecContext(ctx, "SAVEPOINT atomic"); err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			_, rollbackErr := c.ExecContext(ctx, "ROLLBACK TO SAVEPOINT atomic")
+			_, releaseErr := c.ExecContext(ctx, "RELEASE SAVEPOINT atomic")
+			return errors.Join(err, rollbackErr, releaseErr)
+		}
+		_, err := c.ExecContext(ctx, "RELEASE SAVEPOINT atomic")
+		return err
+	}
+}
+
+func ignoreDone(err error) error {
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+	return err
+}
+
+// Repositories возвращает репозитории поверх базы.
+func (s *Store) Repositories() repository.Repositories {
+	return newRepositories(s.db, s.logger)
+}
+
+func newRepositories(c conn, logger *zap.Logger) repository.Repositories {
+	return repository.Repositories{
+		Warehouses: warehouseRepository{db: c},
+		Products:   productRepository{db: c},
+		Inventory:  inventoryRepository{db: c},
+		Analytics:  analyticsRepository{db: c, logger: logger},
+		Reports:    reportRepository{db: c, logger: logger},
+	}
+}
+
+// WithTx выполняет fn в одной транзакции. Транзакции не конкурируют
+// (одно соединение), поэтому повторов нет.
+func (s *Store) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return inTx(ctx, s.db, func(tx conn) error {
+		return fn(newRepositories(tx, s.logger))
+	})
+}
+
+// Нарушения ограничений с теми же именами, что и в PostgreSQL
+var constraintErrors = map[string]error{
+	"products_barcode_key":     repository.ErrDuplicateBarcode,
+	"products.barcode":         repository.ErrDuplicateBarcode,
+	"inventory_quantity_check": repository.ErrInsufficientStock,
+}
+
+// translateConstraint заменяет известное нарушение ограничения ошибкой
+// репозитория. SQLite называет в тексте ошибки имя CHECK-ограничения или
+// колонки уникального индекса, но не внешний ключ, поэтому ссылки
+// проверяются до записи.
+func translateConstraint(err error) error {
+	var sqliteErr *sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		// Текст вида "constraint failed: UNIQUE constraint failed: products.barcode (2067)"
+		const marker = "constraint failed: "
+		message := sqliteErr.Error()
+		if i := strings.LastIndex(message, marker); i >= 0 {
+			name, _, _ := strings.Cut(message[i+len(marker):], " ")
+			if mapped, ok := constraintErrors[name]; ok {
+				return mapped
+			}
+		}
+	}
+	return err
+}
+
+// exists выполняет запрос SELECT EXISTS(...)
+func exists(ctx context.Context, db conn, query string, args ...any) (bool, error) {
+	var found bool
+	err := db.QueryRowContext(ctx, query, args...).Scan(&found)
+	return found, err
+}
+
+// affected возвращает число измененных строк
+func affected(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Деньги хранятся в копейках, скидка — в сотых долях процента, чтобы
+// сравнение и сумма в SQL были точными, как NUMERIC в PostgreSQL
+func toCents(d decimal.Decimal) int64 {
+	return d.Shift(2).Round(0).IntPart()
+}
+
+func fromCents(cents int64) decimal.Decimal {
+	return decimal.New(cents, -2)
+}
+
+// afterID разбирает ID из курсора страницы, упорядоченной по id
+func afterID(page pagination.Params) (string, error) {
+	if page.After == nil {
+		return "", nil
+	}
+	id, err := uuid.Parse(page.After.ID)
+	if err != nil {
+		return "", pagination.ErrInvalidCursor
+	}
+	return id.String(), nil
+}
+
+// scanner — *sql.Row или *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
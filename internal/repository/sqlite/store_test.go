@@ -0,0 +1,91 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/warehouse-service/internal/migrate"
+	"github.com/yourusername/warehouse-service/internal/repository/contract"
+	"github.com/yourusername/warehouse-service/internal/repository/sqlite"
+	"github.com/yourusername/warehouse-service/migrations"
+	"go.uber.org/zap"
+)
+
+func TestContract(t *testing.T) {
+	for _, c := range contract.Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			// Каждый случай — на своем файле во временном каталоге теста
+			path := filepath.Join(t.TempDir(), "warehouse.db")
+			factory := func(ctx context.Context) (contract.Backend, func(), error) {
+				store, err := sqlite.Open(ctx, path, zap.NewNop())
+				if err != nil {
+					return contract.Backend{}, nil, err
+				}
+				return contract.Backend{Repos: store.Repositories(), Tx: store}, func() { _ = store.Close() }, nil
+			}
+			if err := contract.RunCase(context.Background(), factory, c); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Продажи, накопленные в analytics до журнала продаж, переносятся без даты:
+// они входят в выручку склада за все время, но не в выручку по дням
+func TestReportsBackfill(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "warehouse.db")
+
+	// База в версии 1, до журнала продаж
+	list, err := migrate.Parse(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		list[0].Up,
+		"PRAGMA user_version = 1",
+		"INSERT INTO warehouses (id, address) VALUES ('0b6f3b1e-6a0e-4a8e-9d57-3c1f6a2b7d01', 'Old street 1')",
+		"INSERT INTO products (id, name, weight, barcode) VALUES ('0b6f3b1e-6a0e-4a8e-9d57-3c1f6a2b7d02', 'Old product', 1, '4600000000899')",
+		`INSERT INTO analytics (id, warehouse_id, product_id, sold_quantity, total_cents)
+		VALUES ('0b6f3b1e-6a0e-4a8e-9d57-3c1f6a2b7d03', '0b6f3b1e-6a0e-4a8e-9d57-3c1f6a2b7d01', '0b6f3b1e-6a0e-4a8e-9d57-3c1f6a2b7d02', 7, 35000)`,
+	} {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := sqlite.Open(ctx, path, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	reports := store.Repositories().Reports
+	if err := reports.RefreshRevenue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := reports.TopWarehouses(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top.Items) != 1 || top.Items[0].TotalSum.String() != "350" {
+		t.Fatalf("top warehouses %+v, want one with 350", top.Items)
+	}
+	daily, err := reports.DailyRevenue(ctx, top.Items[0].WarehouseID, time.Unix(0, 0), time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daily.Items) != 0 {
+		t.Fatalf("daily revenue %+v from backfilled sales, want none", daily.Items)
+	}
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+type warehouseRepository struct {
+	db conn
+}
+
+var _ repository.WarehouseRepository = warehouseRepository{}
+
+// Добавить склад
+func (r warehouseRepository) CreateWarehouse(ctx context.Context, name string, address string) (uuid.UUID, error) {
+	id := uuid.New()
+	_, err := r.db.ExecContext(ctx, "INSERT INTO warehouses (id, name, address) VALUES (?, ?, ?)", id, name, address)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return id, nil
+}
+
+// Получить склады постранично (keyset-пагинация по id)
+func (r warehouseRepository) GetAllWarehouses(ctx context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error) {
+	after, err := afterID(page)
+	if err != nil {
+		return pagination.Page[models.Warehouse]{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, address, COALESCE(description, ''), version FROM warehouses
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, after, page.Limit+1)
+	if err != nil {
+		return pagination.Page[models.Warehouse]{}, err
+	}
+	defer rows.Close()
+
+	var warehouses []models.Warehouse
+	for rows.Next() {
+		var w models.Warehouse
+		if err := rows.Scan(&w.ID, &w.Name, &w.Address, &w.Description, &w.Version); err != nil {
+			return pagination.Page[models.Warehouse]{}, err
+		}
+		warehouses = append(warehouses, w)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[models.Warehouse]{}, err
+	}
+
+	result := pagination.NewPage(warehouses, page, func(w models.Warehouse) pagination.Cursor {
+		return pagination.Cursor{ID: w.ID.String()}
+	})
+	if page.IncludeTotal {
+		var total int64
+		if err := r.db.QueryRowContext(ctx, "SELECT count(*) FROM warehouses").Scan(&total); err != nil {
+			return pagination.Page[models.Warehouse]{}, err
+		}
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// Обновить склад (полная замена названия, адреса и описания)
+func (r warehouseRepository) UpdateWarehouse(ctx context.Context, warehouse models.Warehouse, expectedVersion int64) error {
+	n, err := affected(r.db.ExecContext(ctx, `
+		UPDATE warehouses
+		SET name = ?1, address = ?2, description = NULLIF(?3, ''), version = version + 1
+		WHERE id = ?4 AND (?5 = 0 OR version = ?5)`,
+		warehouse.Name, warehouse.Address, warehouse.Description, warehouse.ID, expectedVersion))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.missingError(ctx, warehouse.ID)
+	}
+	return nil
+}
+
+// Удалить склад (остатки и аналитика удаляются каскадно)
+func (r warehouseRepository) DeleteWarehouse(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	n, err := affected(r.db.ExecContext(ctx, "DELETE FROM warehouses WHERE id = ?1 AND (?2 = 0 OR version = ?2)", id, expectedVersion))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.missingError(ctx, id)
+	}
+	return nil
+}
+
+// missingError различает отсутствие склада и конфликт версий
+func (r warehouseRepository) missingError(ctx context.Context, id uuid.UUID) error {
+	found, err := exists(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = ?)", id)
+	if err != nil {
+		return err
+	}
+	if found {
+		return repository.ErrVersionMismatch
+	}
+	return repository.ErrWarehouseNotFound
+}
+
+func (r warehouseRepository) GetWarehouseByID(ctx context.Context, id uuid.UUID) (*models.Warehouse, error) {
+	var w models.Warehouse
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, address, COALESCE(description, ''), version FROM warehouses WHERE id = ?
+	`, id).Scan(&w.ID, &w.Name, &w.Address, &w.Description, &w.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrWarehouseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yourusername/warehouse-service/internal/app"
+	"github.com/yourusername/warehouse-service/internal/health"
+	"github.com/yourusername/warehouse-service/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// Storage — хранилище, на котором собирается приложение. Все поля —
+// интерфейсы, поэтому сборка приложения не зависит от драйвера.
+type Storage struct {
+	Repos       Repositories
+	Tx          Transactor
+	Idempotency IdempotencyRepository
+	// Журнал аудита, пользователи, API-ключи и общие квоты есть не во всех
+	// хранилищах; без них аудит и администрирование не публикуются
+	Audit   AuditRepository
+	Users   UserRepository
+	APIKeys APIKeyRepository
+	// RateLimit — общие корзины квот нескольких реплик (rate_limit.store
+	// postgres). Если он реализует ratelimit.Expirer, истекшие корзины
+	// удаляет фоновая задача
+	RateLimit ratelimit.Store
+	// Collectors — метрики хранилища: пулы соединений, отставание реплики
+	Collectors []prometheus.Collector
+	// Writes — учет записей клиентов, чтобы они читали свои изменения не с
+	// отстающей реплики; nil без реплики
+	Writes WriteTracker
+	// Ready — пробы готовности хранилища для /readyz
+	Ready map[string]health.Check
+	// Jobs — фоновые задачи хранилища
+	Jobs []app.Job
+	// Close освобождает соединения и файлы; nil, если освобождать нечего
+	Close func() error
+}
+
+// WriteTracker запоминает записи клиентов (ReplicaRouter): в течение Window
+// после записи чтения клиента идут на основную базу.
+type WriteTracker interface {
+	MarkWrite(client string)
+	Window() time.Duration
+}
+
+// StorageSettings — разделы конфигурации, которые получают драйверы хранилищ.
+type StorageSettings struct {
+	SQLite   SQLiteConfig
+	Database DatabaseConfig
+}
+
+type SQLiteConfig struct {
+	// Path — файл базы; создается при первом запуске
+	Path string `yaml:"path"`
+}
+
+// StorageDriver открывает хранилище по настройкам.
+type StorageDriver struct {
+	// Open открывает хранилище, готовое к работе сервера
+	Open func(ctx context.Context, settings StorageSettings, logger *zap.Logger) (Storage, error)
+	// Validate проверяет настройки драйвера; nil — проверять нечего
+	Validate func(settings StorageSettings) error
+	// Users — хранилище поддерживает пользователей и API-ключи (features.auth)
+	Users bool
+	// Demo — хранилище для разработки и демонстрации: без пользователей
+	// features.auth отключается с предупреждением, а не останавливает запуск
+	Demo bool
+}
+
+var storageDrivers = map[string]StorageDriver{}
+
+// RegisterStorage добавляет драйвер хранилища, выбираемый через
+// storage.driver. Пакет хранилища вызывает его из init, поэтому драйвер
+// доступен, если пакет импортирован (в сервере — пустым импортом).
+// Повторное имя — ошибка программы.
+func RegisterStorage(name string, driver StorageDriver) {
+	if driver.Open == nil {
+		panic("repository: storage driver " + name + " has no Open")
+	}
+	if _, dup := storageDrivers[name]; dup {
+		panic("repository: storage driver " + name + " registered twice")
+	}
+	storageDrivers[name] = driver
+}
+
+// LookupStorage возвращает зарегистрированный драйвер.
+func LookupStorage(name string) (StorageDriver, bool) {
+	driver, ok := storageDrivers[name]
+	return driver, ok
+}
+
+// StorageDrivers возвращает имена зарегистрированных драйверов по алфавиту.
+func StorageDrivers() []string {
+	names := make([]string, 0, len(storageDrivers))
+	for name := range storageDrivers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// OpenStorage открывает хранилище драйвером name. Close результата можно
+// вызывать несколько раз.
+func OpenStorage(ctx context.Context, name string, settings StorageSettings, logger *zap.Logger) (Storage, error) {
+	driver, ok := storageDrivers[name]
+	if !ok {
+		return Storage{}, fmt.Errorf("unknown storage driver %q", name)
+	}
+	storage, err := driver.Open(ctx, settings, logger)
+	if err != nil {
+		return Storage{}, err
+	}
+	if storage.Close != nil {
+		storage.Close = sync.OnceValue(storage.Close)
+	}
+	return storage, nil
+}
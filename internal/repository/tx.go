@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/warehouse-service/internal/logging"
+	"go.uber.org/zap"
+)
+
+// DBTX — общий интерфейс pgxpool.Pool и pgx.Tx. Репозитории принимают его,
+// поэтому одни и те же методы работают и в транзакции, и без нее. Begin на
+// pgx.Tx открывает точку сохранения, так что pgx.BeginFunc внутри
+// репозитория остается атомарным и внутри общей транзакции.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var (
+	_ DBTX = (*pgxpool.Pool)(nil)
+	_ DBTX = (pgx.Tx)(nil)
+)
+
+// Repositories — репозитории, работающие через один DBTX.
+type Repositories struct {
+	Warehouses WarehouseRepository
+	Products   ProductRepository
+	Inventory  InventoryRepository
+	Analytics  AnalyticsRepository
+	Reports    ReportRepository
+}
+
+// NewRepositories создает репозитории поверх пула или транзакции.
+func NewRepositories(db DBTX, logger *zap.Logger) Repositories {
+	return NewRoutedRepositories(db, db, logger)
+}
+
+// NewRoutedRepositories создает репозитории, которые читают списки и
+// аналитику (и отчеты) через reader (обычно ReplicaRouter), а все остальное —
+// через db.
+func NewRoutedRepositories(db, reader DBTX, logger *zap.Logger) Repositories {
+	warehouses := NewWarehouseRepository(db)
+	warehouses.read = reader
+	products := NewProductRepository(db)
+	products.read = reader
+	inventory := NewInventoryRepository(db)
+	inventory.read = reader
+	analytics := NewAnalyticsRepository(db, logger)
+	analytics.read = reader
+	reports := NewReportRepository(db, logger)
+	reports.read = reader
+	return Repositories{
+		Warehouses: warehouses,
+		Products:   products,
+		Inventory:  inventory,
+		Analytics:  analytics,
+		Reports:    reports,
+	}
+}
+
+// Transactor выполняет бизнес-операцию в одной транзакции.
+type Transactor interface {
+	// WithTx вызывает fn с репозиториями, привязанными к транзакции, и
+	// фиксирует ее, если fn вернула nil. При взаимной блокировке fn
+	// вызывается повторно, поэтому она не должна иметь побочных эффектов
+	// вне базы.
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// Повторы после взаимных блокировок (и конфликтов сериализации, если уровень
+// изоляции когда-нибудь повысят)
+const (
+	txMaxAttempts = 5
+	txBaseBackoff = 10 * time.Millisecond
+)
+
+// TxManager открывает транзакции на пуле.
+type TxManager struct {
+	db     *pgxpool.Pool
+	opts   pgx.TxOptions
+	logger *zap.Logger
+}
+
+var _ Transactor = (*TxManager)(nil)
+
+// NewTxManager создает менеджер с уровнем изоляции READ COMMITTED. Более
+// строгий уровень не нужен: записи в транзакциях сервисов — условные
+// UPDATE (quantity >= $1, version = $n, CHECK на остаток), которые
+// перепроверяют условие по последней версии строки, а прочитанное до записи
+// используется только для журнала аудита. SERIALIZABLE на популярном товаре
+// отклонял бы почти все параллельные покупки и исчерпывал повторы.
+func NewTxManager(db *pgxpool.Pool, logger *zap.Logger) *TxManager {
+	return &TxManager{db: db, opts: pgx.TxOptions{IsoLevel: pgx.ReadCommitted}, logger: logger}
+}
+
+func (m *TxManager) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = pgx.BeginTxFunc(ctx, m.db, m.opts, func(tx pgx.Tx) error {
+			return fn(NewRepositories(tx, m.logger))
+		})
+		if err == nil || !retryable(err) || attempt == txMaxAttempts {
+			return err
+		}
+
+		// Экспоненциальная пауза со случайной добавкой, чтобы конкурирующие
+		// транзакции не столкнулись снова
+		backoff := txBaseBackoff << (attempt - 1)
+		backoff += rand.N(backoff)
+		logging.FromContext(ctx, m.logger).Warn("Retrying transaction",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// retryable сообщает, можно ли повторить транзакцию: 40001 serialization_failure
+// и 40P01 deadlock_detected
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "errors"
+
+// ErrVersionMismatch возвращается, когда запись изменилась после того,
+// как клиент получил ее версию (оптимистичная блокировка).
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// AnyVersion отключает проверку версии при обновлении или удалении.
+const AnyVersion int64 = 0
+
+// versionError определяет причину, по которой обновление не затронуло строк:
+// запись отсутствует (notFound) или ее версия не совпала с ожидаемой.
+func versionError(exists bool, notFound error) error {
+	if exists {
+		return ErrVersionMismatch
+	}
+	return notFound
+}
@@ -0,0 +1,107 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+var cities = []string{
+	"Москва", "Санкт-Петербург", "Казань", "Екатеринбург", "Новосибирск",
+	"Нижний Новгород", "Самара", "Ростов-на-Дону", "Краснодар", "Воронеж",
+}
+
+var streets = []string{
+	"Промышленная ул.", "Складская ул.", "Индустриальный пр-т", "Логистическая ул.",
+	"Заводское ш.", "Транспортная ул.", "Кольцевая ул.", "Северный пр-д",
+}
+
+// category описывает группу товаров и правила генерации их атрибутов.
+type category struct {
+	name   string
+	brands []string
+	items  []string
+	// weight — диапазон веса в кг
+	weight [2]float64
+	// price — диапазон цены
+	price      [2]float64
+	attributes func(r *rand.Rand) map[string]any
+}
+
+var categories = []category{
+	{
+		name:   "electronics",
+		brands: []string{"Voltix", "Nordtek", "Sonar", "Pixelon"},
+		items:  []string{"Наушники", "Колонка", "Роутер", "Пауэрбанк", "Клавиатура"},
+		weight: [2]float64{0.1, 2.5},
+		price:  [2]float64{900, 25000},
+		attributes: func(r *rand.Rand) map[string]any {
+			return map[string]any{
+				"color":           pick(r, []string{"черный", "белый", "серый", "синий"}),
+				"warranty_months": pick(r, []int{6, 12, 24}),
+				"wireless":        r.IntN(2) == 0,
+			}
+		},
+	},
+	{
+		name:   "household",
+		brands: []string{"Домосфера", "CleanUp", "Уют", "HomeLine"},
+		items:  []string{"Сковорода", "Чайник", "Набор полотенец", "Контейнер", "Швабра"},
+		weight: [2]float64{0.3, 4},
+		price:  [2]float64{250, 6000},
+		attributes: func(r *rand.Rand) map[string]any {
+			return map[string]any{
+				"material": pick(r, []string{"сталь", "пластик", "хлопок", "алюминий"}),
+				"volume_l": float64(1+r.IntN(40)) / 10,
+			}
+		},
+	},
+	{
+		name:   "food",
+		brands: []string{"Полевое", "Северная ферма", "Вкусно", "GreenBox"},
+		items:  []string{"Кофе молотый", "Чай черный", "Мед", "Гречка", "Оливковое масло"},
+		weight: [2]float64{0.1, 2},
+		price:  [2]float64{80, 1500},
+		attributes: func(r *rand.Rand) map[string]any {
+			return map[string]any{
+				"shelf_life_days": pick(r, []int{90, 180, 365, 730}),
+				"organic":         r.IntN(4) == 0,
+			}
+		},
+	},
+	{
+		name:   "apparel",
+		brands: []string{"Urbanika", "Тайга", "Motion", "Basic&Co"},
+		items:  []string{"Футболка", "Куртка", "Кроссовки", "Шапка", "Джинсы"},
+		weight: [2]float64{0.1, 1.5},
+		price:  [2]float64{500, 12000},
+		attributes: func(r *rand.Rand) map[string]any {
+			return map[string]any{
+				"size":  pick(r, []string{"XS", "S", "M", "L", "XL"}),
+				"color": pick(r, []string{"черный", "белый", "хаки", "синий", "красный"}),
+			}
+		},
+	},
+}
+
+func pick[T any](r *rand.Rand, values []T) T {
+	return values[r.IntN(len(values))]
+}
+
+func between(r *rand.Rand, bounds [2]float64) float64 {
+	return bounds[0] + r.Float64()*(bounds[1]-bounds[0])
+}
+
+// ean13 строит штрихкод EAN-13 с контрольной цифрой. Префикс 2 зарезервирован
+// для внутреннего использования и не пересекается с реальными товарами.
+func ean13(profile, index int) string {
+	digits := fmt.Sprintf("2%d%010d", profile%10, index)
+	sum := 0
+	for i, d := range digits {
+		n := int(d - '0')
+		if i%2 == 1 {
+			n *= 3
+		}
+		sum += n
+	}
+	return digits + fmt.Sprint((10-sum%10)%10)
+}
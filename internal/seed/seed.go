@@ -0,0 +1,268 @@
+// Package seed наполняет базу согласованными тестовыми данными: складами,
+// товарами, остатками и историей продаж. Данные генерируются детерминированно
+// из профиля, масштаба и зерна, поэтому повторный запуск ничего не дублирует,
+// а запуск с большим масштабом только добавляет недостающее.
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var ErrUnknownProfile = errors.New("unknown seed profile")
+
+// Profile задает объем данных при масштабе 1.
+type Profile struct {
+	Name string
+	// code различает штрихкоды и идентификаторы разных профилей
+	code       int
+	Warehouses int
+	Products   int
+	// StockRatio — доля товаров, которые есть на каждом складе
+	StockRatio float64
+	// SalesRatio — доля позиций остатков, по которым были продажи
+	SalesRatio float64
+	// MaxSold — верхняя граница проданного количества одной позиции
+	MaxSold int
+}
+
+var profiles = map[string]Profile{
+	"demo":     {Name: "demo", code: 0, Warehouses: 3, Products: 40, StockRatio: 0.8, SalesRatio: 0.6, MaxSold: 50},
+	"loadtest": {Name: "loadtest", code: 1, Warehouses: 25, Products: 5000, StockRatio: 0.5, SalesRatio: 0.5, MaxSold: 500},
+}
+
+// Profiles возвращает имена доступных профилей.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options — параметры запуска.
+type Options struct {
+	Profile string
+	// Scale умножает число складов и товаров профиля
+	Scale float64
+	// Seed — зерно генератора; другое зерно дает другие данные
+	Seed uint64
+}
+
+// Stats — число добавленных строк; уже существующие строки не учитываются.
+type Stats struct {
+	Warehouses int64
+	Products   int64
+	Inventory  int64
+	Sales      int64
+}
+
+// Размер пачки запросов в одном SendBatch
+const batchSize = 500
+
+// Seeder записывает данные в базу.
+type Seeder struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func New(db *pgxpool.Pool, logger *zap.Logger) *Seeder {
+	return &Seeder{db: db, logger: logger}
+}
+
+// Run генерирует данные профиля и добавляет отсутствующие строки.
+func (s *Seeder) Run(ctx context.Context, opts Options) (Stats, error) {
+	profile, ok := profiles[opts.Profile]
+	if !ok {
+		return Stats{}, fmt.Errorf("%w %q (available: %s)", ErrUnknownProfile, opts.Profile, strings.Join(Profiles(), ", "))
+	}
+	if opts.Scale <= 0 {
+		return Stats{}, errors.New("scale must be positive")
+	}
+	warehouses := scaled(profile.Warehouses, opts.Scale)
+	products := scaled(profile.Products, opts.Scale)
+
+	var stats Stats
+	s.logger.Info("Seeding database",
+		zap.String("profile", profile.Name),
+		zap.Float64("scale", opts.Scale),
+		zap.Int("warehouses", warehouses),
+		zap.Int("products", products),
+	)
+
+	// 1. Склады
+	batch := newWriter(s.db, &stats.Warehouses)
+	for i := 0; i < warehouses; i++ {
+		r := s.rand(opts.Seed, profile, "warehouse", i)
+		city := pick(r, cities)
+		if err := batch.queue(ctx, `INSERT INTO warehouses (id, name, address, description) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+			entityID(opts.Seed, profile, "warehouse", i),
+			fmt.Sprintf("Склад %s-%d", city, i+1),
+			fmt.Sprintf("%s, %s %d", city, pick(r, streets), 1+r.IntN(150)),
+			"Сгенерирован профилем "+profile.Name,
+		); err != nil {
+			return stats, fmt.Errorf("seed warehouses: %w", err)
+		}
+	}
+	if err := batch.flush(ctx); err != nil {
+		return stats, fmt.Errorf("seed warehouses: %w", err)
+	}
+
+	// 2. Товары
+	batch = newWriter(s.db, &stats.Products)
+	for i := 0; i < products; i++ {
+		r := s.rand(opts.Seed, profile, "product", i)
+		cat := categories[i%len(categories)]
+		attributes := cat.attributes(r)
+		attributes["brand"] = pick(r, cat.brands)
+		attributes["category"] = cat.name
+		attributesJSON, err := json.Marshal(attributes)
+		if err != nil {
+			return stats, err
+		}
+		name := fmt.Sprintf("%s %s %s-%d", pick(r, cat.items), attributes["brand"], strings.ToUpper(cat.name[:2]), 100+i)
+		if err := batch.queue(ctx, `INSERT INTO products (id, name, description, attributes, weight, barcode) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+			entityID(opts.Seed, profile, "product", i),
+			name,
+			"Тестовый товар категории "+cat.name,
+			attributesJSON,
+			math.Round(between(r, cat.weight)*1000)/1000,
+			ean13(profile.code, i),
+		); err != nil {
+			return stats, fmt.Errorf("seed warehouses: %w", err)
+		}
+	}
+	if err := batch.flush(ctx); err != nil {
+		return stats, fmt.Errorf("seed products: %w", err)
+	}
+
+	// 3. Остатки и история продаж. Строки вставляются только для товаров и
+	// складов этого профиля, которые действительно есть в базе
+	stock := newWriter(s.db, &stats.Inventory)
+	sales := newWriter(s.db, &stats.Sales)
+	for w := 0; w < warehouses; w++ {
+		warehouseID := entityID(opts.Seed, profile, "warehouse", w)
+		for p := 0; p < products; p++ {
+			r := s.rand(opts.Seed, profile, fmt.Sprintf("inventory-%d", w), p)
+			if r.Float64() >= profile.StockRatio {
+				continue
+			}
+			productID := entityID(opts.Seed, profile, "product", p)
+			cat := categories[p%len(categories)]
+			price := decimal.NewFromFloat(between(r, cat.price)).Round(2)
+			discount := decimal.Zero
+			if r.IntN(4) == 0 {
+				discount = decimal.NewFromInt(int64(5 * (1 + r.IntN(6))))
+			}
+			if err := stock.queue(ctx, `
+				INSERT INTO inventory (product_id, warehouse_id, quantity, price, discount)
+				SELECT $1::uuid, $2::uuid, $3::int, $4::numeric, $5::numeric
+				WHERE EXISTS (SELECT 1 FROM products WHERE id = $1) AND EXISTS (SELECT 1 FROM warehouses WHERE id = $2)
+				ON CONFLICT (product_id, warehouse_id) DO NOTHING`,
+				productID, warehouseID, r.IntN(1000), price, discount,
+			); err != nil {
+				return stats, fmt.Errorf("seed inventory: %w", err)
+			}
+
+			if r.Float64() >= profile.SalesRatio {
+				continue
+			}
+			sold := 1 + r.IntN(profile.MaxSold)
+			unit := price.Mul(decimal.NewFromInt(100).Sub(discount)).Div(decimal.NewFromInt(100))
+			// Продажа попадает и в журнал sales (для отчетов) со случайным
+			// временем за последние 30 дней
+			if err := sales.queue(ctx, `
+				WITH sale AS (
+					INSERT INTO analytics (warehouse_id, product_id, sold_quantity, total_sum)
+					SELECT $1::uuid, $2::uuid, $3::int, $4::numeric
+					WHERE EXISTS (SELECT 1 FROM products WHERE id = $2) AND EXISTS (SELECT 1 FROM warehouses WHERE id = $1)
+					ON CONFLICT (warehouse_id, product_id) DO NOTHING
+					RETURNING warehouse_id, product_id, sold_quantity, total_sum
+				)
+				INSERT INTO sales (warehouse_id, product_id, quantity, total_sum, sold_at)
+				SELECT warehouse_id, product_id, sold_quantity, total_sum, now() - make_interval(secs => $5::int)
+				FROM sale`,
+				warehouseID, productID, sold, unit.Mul(decimal.NewFromInt(int64(sold))).Round(2), r.IntN(30*24*60*60),
+			); err != nil {
+				return stats, fmt.Errorf("seed sales: %w", err)
+			}
+		}
+	}
+	if err := stock.flush(ctx); err != nil {
+		return stats, fmt.Errorf("seed inventory: %w", err)
+	}
+	if err := sales.flush(ctx); err != nil {
+		return stats, fmt.Errorf("seed sales: %w", err)
+	}
+
+	s.logger.Info("Seeding finished",
+		zap.Int64("warehouses", stats.Warehouses),
+		zap.Int64("products", stats.Products),
+		zap.Int64("inventory", stats.Inventory),
+		zap.Int64("sales", stats.Sales),
+	)
+	return stats, nil
+}
+
+// Генератор сущности зависит только от зерна, профиля и номера, поэтому
+// данные не меняются при изменении масштаба
+func (s *Seeder) rand(seed uint64, profile Profile, kind string, index int) *rand.Rand {
+	id := entityID(seed, profile, kind, index)
+	return rand.New(rand.NewPCG(seed, uint64(id.ID())<<32|uint64(index)))
+}
+
+// Идентификатор выводится из тех же параметров, что и данные, поэтому
+// повторная вставка попадает в ON CONFLICT
+func entityID(seed uint64, profile Profile, kind string, index int) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("warehouse-service/seed/%s/%d/%s/%d", profile.Name, seed, kind, index)))
+}
+
+func scaled(n int, scale float64) int {
+	return max(1, int(math.Round(float64(n)*scale)))
+}
+
+// writer отправляет запросы пачками и суммирует число вставленных строк.
+type writer struct {
+	db       *pgxpool.Pool
+	batch    *pgx.Batch
+	inserted *int64
+}
+
+func newWriter(db *pgxpool.Pool, inserted *int64) *writer {
+	return &writer{db: db, batch: &pgx.Batch{}, inserted: inserted}
+}
+
+// queue добавляет запрос и отправляет пачку, когда она заполнена
+func (w *writer) queue(ctx context.Context, sql string, args ...any) error {
+	w.batch.Queue(sql, args...).Exec(func(tag pgconn.CommandTag) error {
+		*w.inserted += tag.RowsAffected()
+		return nil
+	})
+	if w.batch.Len() < batchSize {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+func (w *writer) flush(ctx context.Context) error {
+	if w.batch.Len() == 0 {
+		return nil
+	}
+	err := w.db.SendBatch(ctx, w.batch).Close()
+	w.batch = &pgx.Batch{}
+	return err
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+const (
+	defaultTopWarehouses = 10
+	maxTopWarehouses     = 100
+	// Период выручки по дням: по умолчанию последние 30 дней, не больше года
+	defaultRevenueDays = 30
+	maxRevenueDays     = 366
+)
+
+// AnalyticsService — отчеты о продажах. Сводные отчеты читаются из
+// предрассчитанных таблиц, которые обновляет RefreshReports.
+type AnalyticsService struct {
+	repo    repository.AnalyticsRepository
+	reports repository.ReportRepository
+	audit   *audit.Recorder
+}
+
+func NewAnalyticsService(repo repository.AnalyticsRepository, reports repository.ReportRepository, recorder *audit.Recorder) *AnalyticsService {
+	return &AnalyticsService{repo: repo, reports: reports, audit: recorder}
+}
+
+func (s *AnalyticsService) WarehouseSales(ctx context.Context, warehouseID uuid.UUID, page pagination.Params) (pagination.Page[models.Analytics], error) {
+	return s.repo.GetWarehouseAnalytics(ctx, warehouseID, page)
+}
+
+// TopWarehouses возвращает склады с наибольшей выручкой; limit 0 — топ-10
+func (s *AnalyticsService) TopWarehouses(ctx context.Context, limit int) (models.Report[models.WarehouseRevenue], error) {
+	if limit == 0 {
+		limit = defaultTopWarehouses
+	}
+	if limit < 0 || limit > maxTopWarehouses {
+		return models.Report[models.WarehouseRevenue]{}, invalid("limit must be between 1 and %d", maxTopWarehouses)
+	}
+	return s.reports.TopWarehouses(ctx, limit)
+}
+
+// DailyRevenue возвращает выручку склада по дням (UTC) с from по to
+// включительно. Нулевой to — сегодня, нулевой from — 30 дней до to.
+func (s *AnalyticsService) DailyRevenue(ctx context.Context, warehouseID uuid.UUID, from, to time.Time) (models.Report[models.DailyRevenue], error) {
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -(defaultRevenueDays - 1))
+	}
+	if from.After(to) {
+		return models.Report[models.DailyRevenue]{}, invalid("from must not be after to")
+	}
+	if to.Sub(from) >= maxRevenueDays*24*time.Hour {
+		return models.Report[models.DailyRevenue]{}, invalid("period must not exceed %d days", maxRevenueDays)
+	}
+	return s.reports.DailyRevenue(ctx, warehouseID, from, to)
+}
+
+// StockValue возвращает стоимость остатков по складам с учетом скидок
+func (s *AnalyticsService) StockValue(ctx context.Context) (models.Report[models.StockValue], error) {
+	return s.reports.StockValue(ctx)
+}
+
+// RefreshReports обновляет все отчеты; ошибка одного отчета не мешает
+// обновить остальные
+func (s *AnalyticsService) RefreshReports(ctx context.Context) error {
+	return errors.Join(s.reports.RefreshRevenue(ctx), s.reports.RefreshStockValue(ctx))
+}
+
+// DeleteSales удаляет накопленные продажи товара на складе
+func (s *AnalyticsService) DeleteSales(ctx context.Context, warehouseID, productID uuid.UUID) error {
+	if err := s.repo.DeleteAnalytics(ctx, warehouseID, productID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "analytics", warehouseID.String()+"/"+productID.String(), nil, nil)
+	return nil
+}
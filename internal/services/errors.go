@@ -0,0 +1,25 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidationError — некорректные входные данные. Текст предназначен клиенту.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...any) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// IsValidation сообщает, что операция отклонена из-за входных данных.
+func IsValidation(err error) bool {
+	var ve *ValidationError
+	return errors.As(err, &ve)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/metrics"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// InventoryService — остатки, цены, скидки и продажи на складах.
+type InventoryService struct {
+	repo    repository.InventoryRepository
+	tx      repository.Transactor
+	audit   *audit.Recorder
+	metrics *metrics.Metrics
+}
+
+func NewInventoryService(repo repository.InventoryRepository, tx repository.Transactor, recorder *audit.Recorder, m *metrics.Metrics) *InventoryService {
+	return &InventoryService{repo: repo, tx: tx, audit: recorder, metrics: m}
+}
+
+// DiscountChange — скидка для набора товаров склада.
+type DiscountChange struct {
+	ProductIDs []uuid.UUID `json:"product_ids"`
+	Discount   float64     `json:"discount"`
+}
+
+// PurchaseResult — итог покупки.
+type PurchaseResult struct {
+	Units int             `json:"units"`
+	Total decimal.Decimal `json:"total"`
+}
+
+// LineTotal — стоимость quantity единиц с учетом скидки в процентах.
+func LineTotal(price, discount decimal.Decimal, quantity int) decimal.Decimal {
+	return price.Mul(hundred.Sub(discount)).Div(hundred).Mul(decimal.NewFromInt(int64(quantity))).Round(2)
+}
+
+func validateItems(items map[uuid.UUID]int) error {
+	if len(items) == 0 {
+		return invalid("items must not be empty")
+	}
+	for productID, quantity := range items {
+		if quantity <= 0 {
+			return invalid("quantity of product %s must be positive", productID)
+		}
+	}
+	return nil
+}
+
+// Create размещает товар на складе; для существующей позиции количество
+// добавляется, а цена и скидка заменяются
+func (s *InventoryService) Create(ctx context.Context, inventory models.Inventory) (*models.Inventory, error) {
+	switch {
+	case inventory.Quantity < 0:
+		return nil, invalid("quantity must not be negative")
+	case inventory.Price.IsNegative():
+		return nil, invalid("price must not be negative")
+	case inventory.Discount.IsNegative() || inventory.Discount.GreaterThan(hundred):
+		return nil, invalid("discount must be between 0 and 100")
+	}
+	inventory.ID = uuid.New()
+
+	var created *models.Inventory
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Inventory.Create(ctx, inventory); err != nil {
+			return err
+		}
+		var err error
+		created, err = repos.Inventory.GetProductInWarehouse(ctx, inventory.ProductID, inventory.WarehouseID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionCreate, "inventory", created.ID.String(), nil, created)
+	return created, nil
+}
+
+// AddQuantity изменяет остаток позиции на delta (поступление на склад)
+func (s *InventoryService) AddQuantity(ctx context.Context, productID, warehouseID uuid.UUID, delta int, expectedVersion int64) (*models.Inventory, error) {
+	var before, after *models.Inventory
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID); err != nil {
+			return err
+		}
+		if before.Quantity+delta < 0 {
+			return invalid("quantity must not become negative")
+		}
+		if err := repos.Inventory.UpdateQuantity(ctx, productID, warehouseID, delta, expectedVersion); err != nil {
+			return err
+		}
+		after, err = repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionUpdate, "inventory", after.ID.String(), before, after)
+	return after, nil
+}
+
+// SetDiscount устанавливает скидку сразу для нескольких товаров склада
+func (s *InventoryService) SetDiscount(ctx context.Context, warehouseID uuid.UUID, change DiscountChange) error {
+	if len(change.ProductIDs) == 0 {
+		return invalid("product_ids must not be empty")
+	}
+	if change.Discount < 0 || change.Discount > 100 {
+		return invalid("discount must be between 0 and 100")
+	}
+
+	if err := s.repo.SetDiscount(ctx, change.ProductIDs, warehouseID, change.Discount); err != nil {
+		return err
+	}
+	// Скидка меняется пачкой, поэтому в журнал пишется сам запрос, привязанный к складу
+	s.audit.Record(ctx, audit.ActionUpdate, "inventory_discount", warehouseID.String(), nil, change)
+	return nil
+}
+
+func (s *InventoryService) ListByWarehouse(ctx context.Context, warehouseID uuid.UUID, filter repository.InventoryFilter, page pagination.Params) (pagination.Page[models.InventoryWithNames], error) {
+	return s.repo.GetByWarehouse(ctx, warehouseID, filter, page)
+}
+
+func (s *InventoryService) Get(ctx context.Context, productID, warehouseID uuid.UUID) (*models.Inventory, error) {
+	return s.repo.GetProductInWarehouse(ctx, productID, warehouseID)
+}
+
+// CalculateTotal считает стоимость корзины по текущим ценам и скидкам склада
+func (s *InventoryService) CalculateTotal(ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) (decimal.Decimal, error) {
+	if err := validateItems(items); err != nil {
+		return decimal.Zero, err
+	}
+
+	total := decimal.Zero
+	for productID, quantity := range items {
+		price, err := s.repo.GetProductPrice(ctx, productID, warehouseID)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("product %s: %w", productID, err)
+		}
+		total = total.Add(LineTotal(price.Price, price.Discount, quantity))
+	}
+	return total, nil
+}
+
+// Purchase списывает товары и записывает продажи в аналитику в одной
+// транзакции: либо проходит вся покупка, либо ничего
+func (s *InventoryService) Purchase(ctx context.Context, warehouseID uuid.UUID, items map[uuid.UUID]int) (PurchaseResult, error) {
+	if err := validateItems(items); err != nil {
+		return PurchaseResult{}, err
+	}
+
+	var result PurchaseResult
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		// При повторе транзакции подсчет начинается заново
+		result = PurchaseResult{Total: decimal.Zero}
+		if err := repos.Inventory.Purchase(ctx, warehouseID, items); err != nil {
+			return err
+		}
+		for productID, quantity := range items {
+			price, err := repos.Inventory.GetProductPrice(ctx, productID, warehouseID)
+			if err != nil {
+				return fmt.Errorf("product %s: %w", productID, err)
+			}
+			total := LineTotal(price.Price, price.Discount, quantity)
+			if err := repos.Analytics.RecordSale(ctx, warehouseID, productID, quantity, total); err != nil {
+				return fmt.Errorf("record sale of product %s: %w", productID, err)
+			}
+			result.Units += quantity
+			result.Total = result.Total.Add(total)
+		}
+		return nil
+	})
+	if err != nil {
+		reason := metrics.PurchaseFailedError
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			reason = metrics.PurchaseFailedInsufficientStock
+		}
+		s.metrics.PurchaseFailed(warehouseID, reason)
+		return PurchaseResult{}, err
+	}
+
+	s.audit.Record(ctx, audit.ActionPurchase, "warehouse", warehouseID.String(), nil, map[string]any{"items": items})
+	s.metrics.Purchase(warehouseID, result.Units, result.Total)
+	return result, nil
+}
+
+// RemoveFromWarehouse удаляет позицию товара со склада
+func (s *InventoryService) RemoveFromWarehouse(ctx context.Context, warehouseID, productID uuid.UUID, expectedVersion int64) error {
+	var before *models.Inventory
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Inventory.GetProductInWarehouse(ctx, productID, warehouseID); err != nil {
+			return err
+		}
+		return repos.Inventory.DeleteProductFromWarehouse(ctx, warehouseID, productID, expectedVersion)
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "inventory", before.ID.String(), before, nil)
+	return nil
+}
+
+// Delete удаляет позицию по ее ID
+func (s *InventoryService) Delete(ctx context.Context, inventoryID uuid.UUID, expectedVersion int64) error {
+	if err := s.repo.DeleteInventory(ctx, inventoryID, expectedVersion); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "inventory", inventoryID.String(), nil, nil)
+	return nil
+}
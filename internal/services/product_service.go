@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/audit"
+	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
+
+// ProductService — операции с каталогом товаров.
+type ProductService struct {
+	repo  repository.ProductRepository
+	tx    repository.Transactor
+	audit *audit.Recorder
+}
+
+func NewProductService(repo repository.ProductRepository, tx repository.Transactor, recorder *audit.Recorder) *ProductService {
+	return &ProductService{repo: repo, tx: tx, audit: recorder}
+}
+
+func validateProduct(product models.Product) error {
+	if product.Name == "" || product.Barcode == "" {
+		return invalid("Name and Barcode are required")
+	}
+	if product.Weight < 0 {
+		return invalid("weight must not be negative")
+	}
+	return nil
+}
+
+// Create добавляет товар с новым ID и возвращает сохраненную запись
+func (s *ProductService) Create(ctx context.Context, product models.Product) (*models.Product, error) {
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+	product.ID = uuid.New().String()
+
+	var created *models.Product
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Products.Create(ctx, product); err != nil {
+			return err
+		}
+		var err error
+		created, err = repos.Products.GetByID(ctx, product.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionCreate, "product", product.ID, nil, created)
+	return created, nil
+}
+
+func (s *ProductService) Get(ctx context.Context, id string) (*models.Product, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *ProductService) List(ctx context.Context, page pagination.Params) (pagination.Page[models.Product], error) {
+	return s.repo.GetAll(ctx, page)
+}
+
+// Update полностью заменяет товар: отсутствующие поля сбрасываются
+func (s *ProductService) Update(ctx context.Context, product models.Product, expectedVersion int64) (*models.Product, error) {
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	var before, after *models.Product
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Products.GetByID(ctx, product.ID); err != nil {
+			return err
+		}
+		after, err = saveProduct(ctx, repos, product, expectedVersion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionUpdate, "product", product.ID, before, after)
+	return after, nil
+}
+
+// Patch применяет patch к текущему состоянию товара в одной транзакции с сохранением
+func (s *ProductService) Patch(ctx context.Context, id string, expectedVersion int64, patch func(current models.Product) (models.Product, error)) (*models.Product, error) {
+	var before, after *models.Product
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Products.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if expectedVersion != repository.AnyVersion && expectedVersion != before.Version {
+			return repository.ErrVersionMismatch
+		}
+
+		product, err := patch(*before)
+		if err != nil {
+			return err
+		}
+		product.ID = id // ID нельзя изменить патчем
+		if err := validateProduct(product); err != nil {
+			return err
+		}
+		after, err = saveProduct(ctx, repos, product, before.Version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionUpdate, "product", id, before, after)
+	return after, nil
+}
+
+func saveProduct(ctx context.Context, repos repository.Repositories, product models.Product, expectedVersion int64) (*models.Product, error) {
+	if err := repos.Products.Update(ctx, product, expectedVersion); err != nil {
+		return nil, err
+	}
+	return repos.Products.GetByID(ctx, product.ID)
+}
+
+// Delete удаляет товар вместе с его остатками на складах
+func (s *ProductService) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	var before *models.Product
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Products.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return repos.Products.Delete(ctx, id, expectedVersion)
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "product", id, before, nil)
+	return nil
+}
@@ -2,54 +2,138 @@ package services
 
 import (
 	"context"
-	"errors"
 
+	"github.com/google/uuid"
+	"github.com/yourusername/warehouse-service/internal/audit"
 	"github.com/yourusername/warehouse-service/internal/models"
+	"github.com/yourusername/warehouse-service/internal/pagination"
+	"github.com/yourusername/warehouse-service/internal/repository"
+)
 
-	//"your_project/models"
+// WarehouseService — операции со складами.
+type WarehouseService struct {
+	repo  repository.WarehouseRepository
+	tx    repository.Transactor
+	audit *audit.Recorder
+}
 
-	"github.com/google/uuid"
-)
+func NewWarehouseService(repo repository.WarehouseRepository, tx repository.Transactor, recorder *audit.Recorder) *WarehouseService {
+	return &WarehouseService{repo: repo, tx: tx, audit: recorder}
+}
+
+func validateWarehouse(warehouse models.Warehouse) error {
+	if warehouse.Address == "" {
+		return invalid("Address is required")
+	}
+	return nil
+}
+
+// Create добавляет склад и возвращает сохраненную запись
+func (s *WarehouseService) Create(ctx context.Context, warehouse models.Warehouse) (*models.Warehouse, error) {
+	if err := validateWarehouse(warehouse); err != nil {
+		return nil, err
+	}
+
+	var created *models.Warehouse
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		id, err := repos.Warehouses.CreateWarehouse(ctx, warehouse.Name, warehouse.Address)
+		if err != nil {
+			return err
+		}
+		created, err = repos.Warehouses.GetWarehouseByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionCreate, "warehouse", created.ID.String(), nil, created)
+	return created, nil
+}
 
-// Временное хранилище складов
-var warehouses = make(map[uuid.UUID]models.Warehouse)
+func (s *WarehouseService) Get(ctx context.Context, id uuid.UUID) (*models.Warehouse, error) {
+	return s.repo.GetWarehouseByID(ctx, id)
+}
 
-// AddWarehouse добавляет новый склад
-func AddWarehouse(ctx context.Context, warehouse models.Warehouse) (models.Warehouse, error) {
-	warehouse.ID = uuid.New()
-	warehouses[warehouse.ID] = warehouse
-	return warehouse, nil
+func (s *WarehouseService) List(ctx context.Context, page pagination.Params) (pagination.Page[models.Warehouse], error) {
+	return s.repo.GetAllWarehouses(ctx, page)
 }
 
-// GetWarehouses возвращает все склады
-func GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
-	var result []models.Warehouse
-	for _, warehouse := range warehouses {
-		result = append(result, warehouse)
+// Update полностью заменяет склад, если его версия совпадает с expectedVersion
+func (s *WarehouseService) Update(ctx context.Context, warehouse models.Warehouse, expectedVersion int64) (*models.Warehouse, error) {
+	if err := validateWarehouse(warehouse); err != nil {
+		return nil, err
+	}
+
+	var before, after *models.Warehouse
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Warehouses.GetWarehouseByID(ctx, warehouse.ID); err != nil {
+			return err
+		}
+		after, err = saveWarehouse(ctx, repos, warehouse, expectedVersion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionUpdate, "warehouse", warehouse.ID.String(), before, after)
+	return after, nil
+}
+
+// Patch применяет patch к текущему состоянию склада и сохраняет результат.
+// Чтение и запись выполняются в одной транзакции, поэтому патч не может
+// затереть параллельное изменение.
+func (s *WarehouseService) Patch(ctx context.Context, id uuid.UUID, expectedVersion int64, patch func(current models.Warehouse) (models.Warehouse, error)) (*models.Warehouse, error) {
+	var before, after *models.Warehouse
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Warehouses.GetWarehouseByID(ctx, id); err != nil {
+			return err
+		}
+		if expectedVersion != repository.AnyVersion && expectedVersion != before.Version {
+			return repository.ErrVersionMismatch
+		}
+
+		warehouse, err := patch(*before)
+		if err != nil {
+			return err
+		}
+		warehouse.ID = id // ID нельзя изменить патчем
+		if err := validateWarehouse(warehouse); err != nil {
+			return err
+		}
+		// Патч построен по прочитанной версии, поэтому сохраняем только поверх нее
+		after, err = saveWarehouse(ctx, repos, warehouse, before.Version)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	s.audit.Record(ctx, audit.ActionUpdate, "warehouse", id.String(), before, after)
+	return after, nil
 }
 
-// UpdateWarehouse обновляет склад
-func UpdateWarehouse(ctx context.Context, id uuid.UUID, warehouse models.Warehouse) (models.Warehouse, error) {
-	existingWarehouse, exists := warehouses[id]
-	if !exists {
-		return models.Warehouse{}, errors.New("warehouse not found")
+// save обновляет склад и перечитывает его, чтобы вернуть новую версию
+func saveWarehouse(ctx context.Context, repos repository.Repositories, warehouse models.Warehouse, expectedVersion int64) (*models.Warehouse, error) {
+	if err := repos.Warehouses.UpdateWarehouse(ctx, warehouse, expectedVersion); err != nil {
+		return nil, err
 	}
-	// Обновление данных склада
-	existingWarehouse.Name = warehouse.Name
-	existingWarehouse.Address = warehouse.Address
-	//existingWarehouse.Capacity = warehouse.Capacity
-	existingWarehouse.Description = warehouse.Description
-	warehouses[id] = existingWarehouse
-	return existingWarehouse, nil
+	return repos.Warehouses.GetWarehouseByID(ctx, warehouse.ID)
 }
 
-// DeleteWarehouse удаляет склад
-func DeleteWarehouse(ctx context.Context, id uuid.UUID) error {
-	if _, exists := warehouses[id]; !exists {
-		return errors.New("warehouse not found")
+// Delete удаляет склад вместе с его остатками и аналитикой
+func (s *WarehouseService) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	var before *models.Warehouse
+	err := s.tx.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if before, err = repos.Warehouses.GetWarehouseByID(ctx, id); err != nil {
+			return err
+		}
+		return repos.Warehouses.DeleteWarehouse(ctx, id, expectedVersion)
+	})
+	if err != nil {
+		return err
 	}
-	delete(warehouses, id)
+	s.audit.Record(ctx, audit.ActionDelete, "warehouse", id.String(), before, nil)
 	return nil
 }
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxTracer создает спан на каждый SQL-запрос и пакет запросов pgx.
+// Подключается через pgxpool.Config.ConnConfig.Tracer. Параметры запросов
+// в спаны не попадают, только текст SQL.
+type PgxTracer struct{}
+
+var (
+	_ pgx.QueryTracer = PgxTracer{}
+	_ pgx.BatchTracer = PgxTracer{}
+)
+
+func (PgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = Tracer().Start(ctx, "postgres "+operation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(strings.TrimSpace(data.SQL)),
+		))
+	return ctx
+}
+
+func (PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	endSpan(span, data.Err, data.CommandTag.RowsAffected())
+}
+
+func (PgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = Tracer().Start(ctx, "postgres batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.Int("db.batch.size", data.Batch.Len()),
+		))
+	return ctx
+}
+
+func (PgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if data.Err != nil {
+		trace.SpanFromContext(ctx).RecordError(data.Err)
+	}
+}
+
+func (PgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err, -1)
+}
+
+func endSpan(span trace.Span, err error, rows int64) {
+	if rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+	if err != nil && err != pgx.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// operation возвращает первое ключевое слово запроса (SELECT, UPDATE, ...)
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	// CTE: WITH ... SELECT/UPDATE — имя по первому слову достаточно для поиска
+	return strings.ToUpper(fields[0])
+}
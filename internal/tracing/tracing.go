@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Имя инструментирующей библиотеки для всех спанов сервиса
+const instrumentationName = "github.com/yourusername/warehouse-service"
+
+// Экспортеры спанов
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Config — настройки трассировки.
+type Config struct {
+	// Exporter — none (по умолчанию), otlp или stdout
+	Exporter string
+	// Endpoint — адрес OTLP/HTTP коллектора (host:port); пусто — из
+	// OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4318
+	Endpoint string
+	// Insecure отключает TLS при отправке в коллектор
+	Insecure bool
+	// SampleRatio — доля трассируемых запросов без родительского спана (0..1]
+	SampleRatio float64
+	ServiceName string
+	// Output — куда пишет stdout-экспортер; nil означает os.Stdout
+	Output io.Writer
+}
+
+// Setup настраивает глобальные TracerProvider и W3C trace-context
+// пропагатор. Возвращенная функция отправляет накопленные спаны и
+// останавливает экспорт; ее нужно вызвать при завершении сервиса.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	// Заголовки traceparent/tracestate пробрасываются даже без экспорта
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		out := cfg.Output
+		if out == nil {
+			out = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	default:
+		return nil, ErrUnknownExporter
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трассировщик сервиса из глобального провайдера.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -1 +0,0 @@
-ALTER TABLE warehouses DROP COLUMN capacity;
@@ -0,0 +1,2 @@
+-- Колонку не восстанавливаем: текущая схема ее не использует
+SELECT 1;
@@ -0,0 +1,2 @@
+-- Колонка capacity есть только в базах, созданных до 000001_init_schema
+ALTER TABLE warehouses DROP COLUMN IF EXISTS capacity;
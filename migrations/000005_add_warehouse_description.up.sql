@@ -0,0 +1 @@
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS description TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE inventory DROP COLUMN IF EXISTS version;
+ALTER TABLE products DROP COLUMN IF EXISTS version;
+ALTER TABLE warehouses DROP COLUMN IF EXISTS version;
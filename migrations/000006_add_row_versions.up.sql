@@ -0,0 +1,3 @@
+ALTER TABLE warehouses ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE products ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE inventory ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
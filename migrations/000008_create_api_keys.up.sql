@@ -0,0 +1,9 @@
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL, -- SHA-256 от ключа, сам ключ не хранится
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,17 @@
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'warehouse_manager', 'cashier', 'analyst')),
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE TABLE user_warehouses (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, warehouse_id)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    occurred_at TIMESTAMP NOT NULL DEFAULT now(),
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    diff JSONB,
+    request_id TEXT
+);
+
+CREATE INDEX audit_log_occurred_at_idx ON audit_log (occurred_at, id);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id, occurred_at);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor, occurred_at);
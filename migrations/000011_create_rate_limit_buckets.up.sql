@@ -0,0 +1,9 @@
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    -- после этого момента корзина полна и строку можно удалить
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_expires_at_idx ON rate_limit_buckets (expires_at);
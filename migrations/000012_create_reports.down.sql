@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS report_state;
+DROP TABLE IF EXISTS report_stock_value;
+DROP TABLE IF EXISTS report_warehouse_revenue;
+DROP TABLE IF EXISTS report_daily_revenue;
+DROP TABLE IF EXISTS sales;
@@ -0,0 +1,55 @@
+-- Журнал продаж: каждая покупка с датой. analytics хранит только накопленные
+-- суммы, а отчету о выручке по дням нужна дата продажи. Товар не ссылается
+-- на products: выручка удаленного товара остается в истории.
+CREATE TABLE sales (
+    id BIGSERIAL PRIMARY KEY,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    total_sum NUMERIC(10, 2) NOT NULL CHECK (total_sum >= 0),
+    -- NULL — продажа перенесена из analytics, дата неизвестна
+    sold_at TIMESTAMPTZ DEFAULT now(),
+    -- продажа уже учтена в отчетах о выручке
+    aggregated BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX sales_warehouse_product_idx ON sales (warehouse_id, product_id);
+CREATE INDEX sales_pending_idx ON sales (id) WHERE NOT aggregated;
+
+-- Накопленные до журнала продажи переносятся итогами без даты: они входят
+-- в выручку склада за все время, но не в выручку по дням, история которой
+-- начинается с применения миграции
+INSERT INTO sales (warehouse_id, product_id, quantity, total_sum, sold_at)
+SELECT warehouse_id, product_id, sold_quantity, total_sum, NULL FROM analytics WHERE sold_quantity > 0;
+
+-- Выручка склада по дням (UTC) и за все время
+CREATE TABLE report_daily_revenue (
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    sold_quantity BIGINT NOT NULL,
+    revenue NUMERIC(14, 2) NOT NULL,
+    PRIMARY KEY (warehouse_id, day)
+);
+
+CREATE TABLE report_warehouse_revenue (
+    warehouse_id UUID PRIMARY KEY REFERENCES warehouses(id) ON DELETE CASCADE,
+    sold_quantity BIGINT NOT NULL,
+    revenue NUMERIC(14, 2) NOT NULL
+);
+
+CREATE INDEX report_warehouse_revenue_revenue_idx ON report_warehouse_revenue (revenue DESC, warehouse_id);
+
+-- Стоимость остатков склада по текущим ценам со скидкой
+CREATE TABLE report_stock_value (
+    warehouse_id UUID PRIMARY KEY REFERENCES warehouses(id) ON DELETE CASCADE,
+    units BIGINT NOT NULL,
+    stock_value NUMERIC(16, 2) NOT NULL
+);
+
+-- Момент последнего обновления каждого отчета (NULL — еще не строился)
+CREATE TABLE report_state (
+    name TEXT PRIMARY KEY,
+    as_of TIMESTAMPTZ
+);
+
+INSERT INTO report_state (name) VALUES ('revenue'), ('stock_value');
@@ -0,0 +1 @@
+ALTER TABLE audit_log ALTER COLUMN occurred_at TYPE TIMESTAMP;
@@ -0,0 +1,4 @@
+-- occurred_at без часового пояса сравнивался с параметрами from/to после
+-- отбрасывания их смещения. Существующие значения записаны now() в часовом
+-- поясе сессии и так же им интерпретируются при преобразовании.
+ALTER TABLE audit_log ALTER COLUMN occurred_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,24 @@
+// Package migrations встраивает SQL-миграции в бинарник.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS содержит файлы NNNNNN_name.up.sql и NNNNNN_name.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// SQLite содержит миграции схемы SQLite в том же формате, что и FS.
+var SQLite = func() fs.FS {
+	sub, err := fs.Sub(sqliteFiles, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}()
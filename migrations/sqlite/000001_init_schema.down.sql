@@ -0,0 +1,5 @@
+DROP TABLE idempotency_keys;
+DROP TABLE analytics;
+DROP TABLE inventory;
+DROP TABLE products;
+DROP TABLE warehouses;
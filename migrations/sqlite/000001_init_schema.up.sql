@@ -0,0 +1,59 @@
+-- Схема SQLite повторяет схему PostgreSQL (миграции 000001–000007) с
+-- поправками на типы: UUID хранятся текстом, деньги — целым числом копеек,
+-- скидка — сотыми долями процента. Имена ограничений совпадают с PostgreSQL,
+-- чтобы репозитории переводили их в одни и те же ошибки.
+
+CREATE TABLE warehouses (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT 'Warehouse',
+    address TEXT NOT NULL,
+    description TEXT,
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE products (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    attributes TEXT NOT NULL DEFAULT '{}',
+    weight REAL NOT NULL,
+    barcode TEXT NOT NULL CONSTRAINT products_barcode_key UNIQUE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE inventory (
+    id TEXT PRIMARY KEY,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id TEXT NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CONSTRAINT inventory_quantity_check CHECK (quantity >= 0),
+    price_cents INTEGER NOT NULL CONSTRAINT inventory_price_check CHECK (price_cents >= 0),
+    discount_bp INTEGER NOT NULL DEFAULT 0 CONSTRAINT inventory_discount_check CHECK (discount_bp BETWEEN 0 AND 10000),
+    version INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (product_id, warehouse_id)
+);
+
+CREATE INDEX inventory_warehouse_id_idx ON inventory (warehouse_id);
+
+CREATE TABLE analytics (
+    id TEXT PRIMARY KEY,
+    warehouse_id TEXT NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sold_quantity INTEGER NOT NULL CHECK (sold_quantity >= 0),
+    total_cents INTEGER NOT NULL CHECK (total_cents >= 0),
+    UNIQUE (warehouse_id, product_id)
+);
+
+CREATE TABLE idempotency_keys (
+    key TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER,
+    content_type TEXT,
+    response_body BLOB,
+    expires_at INTEGER NOT NULL, -- Unix-время в миллисекундах
+    PRIMARY KEY (key, method, path)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,50 @@
+-- Журнал продаж и отчеты, как в миграции PostgreSQL 000012: деньги в
+-- копейках, время продажи — Unix-миллисекунды, день — строка YYYY-MM-DD (UTC).
+
+CREATE TABLE sales (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    warehouse_id TEXT NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    total_cents INTEGER NOT NULL CHECK (total_cents >= 0),
+    sold_at INTEGER, -- NULL — продажа перенесена из analytics, дата неизвестна
+    aggregated INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX sales_warehouse_product_idx ON sales (warehouse_id, product_id);
+CREATE INDEX sales_pending_idx ON sales (id) WHERE aggregated = 0;
+
+-- Накопленные продажи переносятся итогами без даты: в выручку по дням они
+-- не попадают
+INSERT INTO sales (warehouse_id, product_id, quantity, total_cents, sold_at)
+SELECT warehouse_id, product_id, sold_quantity, total_cents, NULL
+FROM analytics WHERE sold_quantity > 0;
+
+CREATE TABLE report_daily_revenue (
+    warehouse_id TEXT NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    sold_quantity INTEGER NOT NULL,
+    revenue_cents INTEGER NOT NULL,
+    PRIMARY KEY (warehouse_id, day)
+);
+
+CREATE TABLE report_warehouse_revenue (
+    warehouse_id TEXT PRIMARY KEY REFERENCES warehouses(id) ON DELETE CASCADE,
+    sold_quantity INTEGER NOT NULL,
+    revenue_cents INTEGER NOT NULL
+);
+
+CREATE INDEX report_warehouse_revenue_revenue_idx ON report_warehouse_revenue (revenue_cents DESC, warehouse_id);
+
+CREATE TABLE report_stock_value (
+    warehouse_id TEXT PRIMARY KEY REFERENCES warehouses(id) ON DELETE CASCADE,
+    units INTEGER NOT NULL,
+    value_cents INTEGER NOT NULL
+);
+
+CREATE TABLE report_state (
+    name TEXT PRIMARY KEY,
+    as_of INTEGER -- Unix-время в миллисекундах
+);
+
+INSERT INTO report_state (name) VALUES ('revenue'), ('stock_value');
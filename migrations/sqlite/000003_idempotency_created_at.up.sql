@@ -0,0 +1,4 @@
+-- Время резервирования ключа, как created_at в PostgreSQL: резерв без
+-- ответа освобождается через repository.IdempotencyLease. У ключей до
+-- миграции оно неизвестно (0), и их незавершенные резервы освобождаются сразу.
+ALTER TABLE idempotency_keys ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0; -- Unix-время в миллисекундах